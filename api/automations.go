@@ -0,0 +1,118 @@
+package api
+
+import (
+	"Mystery/automation"
+	"Mystery/sizes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Handles /api/automations/...
+func (s *Server) routeAutomations(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJson(w, http.StatusOK, s.Manager.GetAutomations())
+		case http.MethodPost:
+			s.addAutomation(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+
+		return
+	}
+
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w, http.MethodDelete)
+		return
+	}
+
+	if !s.Manager.RemoveAutomation(parts[0]) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("automation %v not found", parts[0]))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) addAutomation(w http.ResponseWriter, r *http.Request) {
+	var auto automation.Automation
+
+	if err := readJson(r, &auto); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.validateAutomation(&auto); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.Manager.AddAutomation(&auto); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	writeJson(w, http.StatusCreated, auto)
+}
+
+// Checks the automation against the profiles, proxy lists and notifiers the manager has
+func (s *Server) validateAutomation(auto *automation.Automation) error {
+	if auto.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(auto.MonitorInputs) == 0 {
+		return errors.New("no monitor inputs")
+	}
+
+	if len(auto.Profiles) == 0 {
+		return errors.New("no profiles")
+	}
+
+	for _, profile := range auto.Profiles {
+		if _, ok := s.Manager.Profiles[profile]; !ok {
+			return errors.New(fmt.Sprintf("unknown profile %q", profile))
+		}
+	}
+
+	if _, ok := s.Manager.ProxyLists[auto.ProxyList]; auto.ProxyList != "" && !ok {
+		return errors.New(fmt.Sprintf("unknown proxy list %q", auto.ProxyList))
+	}
+
+	if auto.TotalTaskCount <= 0 {
+		return errors.New("total task count must be greater than zero")
+	}
+
+	if auto.PaymentRetries < 0 || auto.PaymentRetryDelayMs < 0 {
+		return errors.New("payment retries and delay cannot be negative")
+	}
+
+	if !sizes.IsValidStrategy(auto.VariantStrategy) {
+		return errors.New(fmt.Sprintf("unknown variant strategy %q", auto.VariantStrategy))
+	}
+
+	if err := auto.Notify.Validate(); err != nil {
+		return errors.New(fmt.Sprintf("notify: %v", err))
+	}
+
+	for eventType, names := range auto.Notify {
+		for _, name := range names {
+			if s.Manager.Notifications == nil {
+				return errors.New("notify: no notifiers are set up")
+			}
+
+			if _, ok := s.Manager.Notifications.Sink(name); !ok {
+				return errors.New(fmt.Sprintf("notify: %v: unknown notifier %q", eventType, name))
+			}
+		}
+	}
+
+	return nil
+}
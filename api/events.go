@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// How often a comment is sent on an idle event stream so proxies and clients don't time it out
+var eventKeepAlive = 15 * time.Second
+
+// Streams every task event as a Server-Sent Event named after the event's type, ex.
+//
+//	event: status_changed
+//	data: {"type":"status_changed","time":"...","task_id":"abc123","status":{"value":"Monitoring","level":0}}
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming isn't supported")
+		return
+	}
+
+	subscription := s.Manager.Subscribe()
+	defer subscription.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(e)
+
+			if err != nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"Mystery/logs"
+	"Mystery/tasks"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Server Exposes a task manager over HTTP/JSON. Every request needs the token, either as an
+// "Authorization: Bearer <token>" header or, for clients that can't set headers (ex. EventSource), a token query
+// parameter.
+//
+//	GET    /api/tasks                     List tasks
+//	POST   /api/tasks                     Create a task in an existing group
+//	GET    /api/tasks/<id>                Get a task
+//	PATCH  /api/tasks/<id>                Edit a stopped task's monitor inputs, sizes, profile or proxy list
+//	DELETE /api/tasks/<id>                Stop and delete a task
+//	POST   /api/tasks/<id>/start          Start a task
+//	POST   /api/tasks/<id>/stop           Stop a task
+//	GET    /api/groups                    List task groups
+//	POST   /api/groups                    Create an empty task group
+//	GET    /api/groups/<name>             Get a task group and its tasks
+//	DELETE /api/groups/<name>             Stop and delete a task group and its tasks
+//	POST   /api/groups/<name>/start       Start every task in a group
+//	POST   /api/groups/<name>/stop        Stop every task in a group
+//	GET    /api/automations               List automations
+//	POST   /api/automations               Add an automation
+//	DELETE /api/automations/<name>        Remove an automation
+//	GET    /api/events                    Stream task events as Server-Sent Events
+type Server struct {
+	Manager *tasks.Manager
+	Token   string
+	mutex   *sync.Mutex
+	server  *http.Server
+}
+
+// The error body every failed request responds with
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer Creates a server for the manager that only accepts requests with the token
+func NewServer(m *tasks.Manager, token string) *Server {
+	return &Server{
+		Manager: m,
+		Token:   token,
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// IsLoopbackAddress Returns if the address (ex. "127.0.0.1:8080" / "localhost:8080") only listens on this machine
+func IsLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAndServe Serves the API on a loopback address until Close is called
+func (s *Server) ListenAndServe(addr string) error {
+	if !IsLoopbackAddress(addr) {
+		return errors.New(fmt.Sprintf("api address %v is not a loopback address", addr))
+	}
+
+	if s.Token == "" {
+		return errors.New("api token is required")
+	}
+
+	s.mutex.Lock()
+	s.server = &http.Server{Addr: addr, Handler: s}
+	server := s.server
+	s.mutex.Unlock()
+
+	logs.Info("Serving the API", "address", addr)
+
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Close Stops the server and closes every connection, including event streams
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+
+	return s.server.Close()
+}
+
+// ServeHTTP Checks the token and routes the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid or missing token")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if len(parts) < 2 || parts[0] != "api" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch parts[1] {
+	case "tasks":
+		s.routeTasks(w, r, parts[2:])
+	case "groups":
+		s.routeGroups(w, r, parts[2:])
+	case "automations":
+		s.routeAutomations(w, r, parts[2:])
+	case "events":
+		s.handleEvents(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) isAuthorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	return s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// Responds with the method not allowed error and the methods that are
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logs.Error("Failed to write API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, errorResponse{message})
+}
+
+// Decodes a JSON request body, rejecting unknown fields so typos don't go unnoticed
+func readJson(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return errors.New(fmt.Sprintf("invalid body: %v", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"Mystery/profiles"
+	"Mystery/proxies"
+	"Mystery/tasks"
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "secret"
+
+// Runs a task by marking it as running until it's stopped
+type testRunner struct {
+	task *tasks.Task
+}
+
+func (r *testRunner) Start() {
+	r.task.Begin()
+}
+
+func (r *testRunner) Stop() {
+	r.task.Cancel()
+	r.task.Finish()
+}
+
+// Starts a server for a manager with a test profile, proxy list and group
+func newTestServer(t *testing.T) (*httptest.Server, *tasks.Manager) {
+	m := tasks.NewManager()
+	m.Profiles = map[string]*profiles.Profile{"Test US": {Name: "Test US"}, "Test UK": {Name: "Test UK"}}
+	m.ProxyLists = map[string]*proxies.ProxyList{"Live": {Name: "Live"}}
+
+	m.NewTask = func(site tasks.Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode tasks.TaskMode, inputs []string, sizes []string) *tasks.Task {
+		task := tasks.NewTask(site, profile, proxyList, mode, inputs, sizes)
+		task.Runner = &testRunner{&task}
+		return &task
+	}
+
+	group := tasks.NewTaskGroup("Dunks")
+	m.AddTaskGroup(&group)
+
+	server := httptest.NewServer(NewServer(&m, testToken))
+	t.Cleanup(server.Close)
+	return server, &m
+}
+
+// Sends an authorized request and decodes the JSON response into out, if given
+func request(t *testing.T, server *httptest.Server, method string, path string, body string, out interface{}) int {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%v %v: invalid response: %v", method, path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+// Tests that requests without the token are rejected and only loopback addresses are allowed
+func TestAuthorization(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	for _, url := range []string{"/api/tasks", "/api/tasks?token=wrong"} {
+		resp, err := http.Get(server.URL + url)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("%v: expected 401. got %v", url, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/api/tasks?token=" + testToken)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the query token to be accepted. got %v", resp.StatusCode)
+	}
+
+	for addr, expected := range map[string]bool{"127.0.0.1:7777": true, "localhost:7777": true, "[::1]:7777": true, ":7777": false, "0.0.0.0:7777": false} {
+		if IsLoopbackAddress(addr) != expected {
+			t.Fatalf("%v: expected loopback to be %v", addr, expected)
+		}
+	}
+
+	if err := NewServer(nil, testToken).ListenAndServe("0.0.0.0:7777"); err == nil {
+		t.Fatal("expected a non loopback address to be refused")
+	}
+}
+
+// Tests creating, starting, editing and deleting a task
+func TestTaskLifecycle(t *testing.T) {
+	server, m := newTestServer(t)
+	var task taskView
+
+	status := request(t, server, http.MethodPost, "/api/tasks", `{"group": "Dunks", "site": {"url": "https://kith.com"}, "profile": "Test US", "mode": "fast", "monitor_inputs": ["+dunk"], "sizes": ["10"]}`, &task)
+
+	if status != http.StatusCreated || task.Site.Name != "kith.com" || task.Mode != "Fast" || task.Quantity != 1 || task.Group != "Dunks" {
+		t.Fatalf("unexpected created task %v %+v", status, task)
+	}
+
+	if status := request(t, server, http.MethodPost, "/api/tasks", `{"group": "Dunks", "site": {"url": "https://kith.com"}, "profile": "Nope", "monitor_inputs": ["+dunk"]}`, nil); status != http.StatusBadRequest {
+		t.Fatalf("expected an unknown profile to be rejected. got %v", status)
+	}
+
+	if status := request(t, server, http.MethodPost, "/api/tasks/"+task.Id+"/start", "", &task); status != http.StatusOK || !task.Running {
+		t.Fatalf("expected the task to be running. got %v %+v", status, task)
+	}
+
+	if status := request(t, server, http.MethodPatch, "/api/tasks/"+task.Id, `{"sizes": ["11"]}`, nil); status != http.StatusConflict {
+		t.Fatalf("expected editing a running task to conflict. got %v", status)
+	}
+
+	request(t, server, http.MethodPost, "/api/tasks/"+task.Id+"/stop", "", &task)
+
+	if status := request(t, server, http.MethodPatch, "/api/tasks/"+task.Id, `{"sizes": ["11"], "profile": "Nope"}`, nil); status != http.StatusBadRequest {
+		t.Fatalf("expected an unknown profile to be rejected. got %v", status)
+	}
+
+	if m.GetTask(task.Id).Sizes[0] != "10" {
+		t.Fatalf("expected a rejected edit to change nothing")
+	}
+
+	status = request(t, server, http.MethodPatch, "/api/tasks/"+task.Id, `{"monitor_inputs": ["+jordan"], "sizes": ["11"], "profile": "Test UK", "proxy_list": "Live"}`, &task)
+
+	if status != http.StatusOK || task.MonitorInputs[0] != "+jordan" || task.Sizes[0] != "11" || task.Profile != "Test UK" || task.ProxyList != "Live" {
+		t.Fatalf("unexpected edited task %v %+v", status, task)
+	}
+
+	if status := request(t, server, http.MethodDelete, "/api/tasks/"+task.Id, "", nil); status != http.StatusNoContent {
+		t.Fatalf("expected the task to be deleted. got %v", status)
+	}
+
+	if m.GetTask(task.Id) != nil || len(m.GetTaskGroup("Dunks").Tasks) != 0 {
+		t.Fatalf("expected the task to be removed from the manager and group")
+	}
+}
+
+// Tests creating, starting and deleting a task group
+func TestGroups(t *testing.T) {
+	server, m := newTestServer(t)
+
+	if status := request(t, server, http.MethodPost, "/api/groups", `{"name": "Dunks"}`, nil); status != http.StatusConflict {
+		t.Fatalf("expected a duplicate group to conflict. got %v", status)
+	}
+
+	if status := request(t, server, http.MethodPost, "/api/groups", `{"name": "Jordans"}`, nil); status != http.StatusCreated {
+		t.Fatalf("expected the group to be created. got %v", status)
+	}
+
+	for i := 0; i < 2; i++ {
+		request(t, server, http.MethodPost, "/api/tasks", `{"group": "Jordans", "site": {"url": "https://kith.com"}, "profile": "Test US", "monitor_inputs": ["+jordan"]}`, nil)
+	}
+
+	var group groupView
+
+	if status := request(t, server, http.MethodPost, "/api/groups/Jordans/start", "", &group); status != http.StatusOK || group.Running != 2 || len(group.Tasks) != 2 {
+		t.Fatalf("expected 2 running tasks. got %v %+v", status, group)
+	}
+
+	var groups []groupView
+	request(t, server, http.MethodGet, "/api/groups", "", &groups)
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups. got %v", len(groups))
+	}
+
+	if status := request(t, server, http.MethodDelete, "/api/groups/Jordans", "", nil); status != http.StatusNoContent {
+		t.Fatalf("expected the group to be deleted. got %v", status)
+	}
+
+	if m.GetTaskGroup("Jordans") != nil || len(m.Tasks) != 0 {
+		t.Fatalf("expected the group and its tasks to be removed")
+	}
+}
+
+// Tests adding and removing automations
+func TestAutomations(t *testing.T) {
+	server, m := newTestServer(t)
+	auto := `{"name": "Dunks", "monitor_inputs": ["+dunk"], "profiles": ["Test US"], "total_task_count": 5}`
+
+	if status := request(t, server, http.MethodPost, "/api/automations", auto, nil); status != http.StatusCreated {
+		t.Fatalf("expected the automation to be added. got %v", status)
+	}
+
+	if status := request(t, server, http.MethodPost, "/api/automations", auto, nil); status != http.StatusConflict {
+		t.Fatalf("expected a duplicate automation to conflict. got %v", status)
+	}
+
+	if status := request(t, server, http.MethodPost, "/api/automations", strings.Replace(auto, `"Dunks"`, `"Jordans"`, 1)[:40], nil); status != http.StatusBadRequest {
+		t.Fatalf("expected an invalid body to be rejected. got %v", status)
+	}
+
+	if status := request(t, server, http.MethodPost, "/api/automations", strings.Replace(auto, `"Test US"`, `"Nope"`, 1), nil); status != http.StatusBadRequest {
+		t.Fatalf("expected an unknown profile to be rejected. got %v", status)
+	}
+
+	if len(m.GetAutomations()) != 1 {
+		t.Fatalf("expected 1 automation. got %v", len(m.GetAutomations()))
+	}
+
+	if status := request(t, server, http.MethodDelete, "/api/automations/Dunks", "", nil); status != http.StatusNoContent || len(m.GetAutomations()) != 0 {
+		t.Fatalf("expected the automation to be removed. got %v", status)
+	}
+}
+
+// Tests that task events are streamed as Server-Sent Events
+func TestEvents(t *testing.T) {
+	server, m := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/api/events?token=" + testToken)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %v", resp.Header.Get("Content-Type"))
+	}
+
+	// The subscription is made before the headers are sent, so events sent from here on are streamed
+	task := tasks.NewTask(tasks.Website{}, nil, nil, tasks.ModeShopifySafe, nil, nil)
+	m.AddTask(&task)
+	task.UpdateStatus(&tasks.TaskStatus{Value: "Monitoring"}, false)
+
+	lines := make(chan string)
+
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+
+		close(lines)
+	}()
+
+	var received []string
+
+	for len(received) < 2 {
+		select {
+		case line := <-lines:
+			if line != "" {
+				received = append(received, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the event. got %v", received)
+		}
+	}
+
+	if received[0] != "event: status_changed" || !strings.HasPrefix(received[1], "data: ") {
+		t.Fatalf("unexpected event %v", received)
+	}
+
+	var e tasks.TaskEvent
+
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(received[1], "data: ")), &e); err != nil || e.TaskId != task.Id || e.Status.Value != "Monitoring" {
+		t.Fatalf("unexpected event data %v (%v)", received[1], err)
+	}
+}
@@ -0,0 +1,439 @@
+package api
+
+import (
+	"Mystery/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type taskView struct {
+	Id             string            `json:"id"`
+	Group          string            `json:"group"`
+	Site           tasks.Website     `json:"site"`
+	Profile        string            `json:"profile"`
+	ProxyList      string            `json:"proxy_list"`
+	Mode           string            `json:"mode"`
+	MonitorInputs  []string          `json:"monitor_inputs"`
+	Sizes          []string          `json:"sizes"`
+	Quantity       int               `json:"quantity"`
+	PaymentRetries int               `json:"payment_retries"`
+	Running        bool              `json:"running"`
+	Status         *tasks.TaskStatus `json:"status"`
+}
+
+type groupView struct {
+	Name    string     `json:"name"`
+	Running int        `json:"running"` // The amount of tasks in the group that are running.
+	Tasks   []taskView `json:"tasks"`
+}
+
+type createTaskRequest struct {
+	Group          string        `json:"group"`
+	Site           tasks.Website `json:"site"` // The name defaults to the URL's host.
+	Profile        string        `json:"profile"`
+	ProxyList      string        `json:"proxy_list"`
+	Mode           string        `json:"mode"` // "safe" or "fast". Defaults to safe.
+	MonitorInputs  []string      `json:"monitor_inputs"`
+	Sizes          []string      `json:"sizes"`
+	Quantity       int           `json:"quantity"` // Defaults to 1.
+	PaymentRetries int           `json:"payment_retries"`
+}
+
+// Fields that are left out aren't changed. An empty proxy list removes the task's proxy list.
+type editTaskRequest struct {
+	MonitorInputs *[]string `json:"monitor_inputs"`
+	Sizes         *[]string `json:"sizes"`
+	Profile       *string   `json:"profile"`
+	ProxyList     *string   `json:"proxy_list"`
+}
+
+type createGroupRequest struct {
+	Name string `json:"name"`
+}
+
+func newTaskView(t *tasks.Task) taskView {
+	v := taskView{
+		Id:             t.Id,
+		Site:           t.Site,
+		Mode:           tasks.ModeToString(t.Mode),
+		MonitorInputs:  t.MonitorInputs,
+		Sizes:          t.Sizes,
+		Quantity:       t.Quantity,
+		PaymentRetries: t.PaymentRetries,
+		Running:        t.IsRunning(),
+		Status:         t.GetStatus(),
+	}
+
+	if t.Group != nil {
+		v.Group = t.Group.Name
+	}
+
+	if t.Profile != nil {
+		v.Profile = t.Profile.Name
+	}
+
+	if t.ProxyList != nil {
+		v.ProxyList = t.ProxyList.Name
+	}
+
+	return v
+}
+
+func newGroupView(g *tasks.TaskGroup) groupView {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	v := groupView{Name: g.Name, Tasks: []taskView{}}
+
+	for _, t := range g.Tasks {
+		task := newTaskView(t)
+
+		if task.Running {
+			v.Running++
+		}
+
+		v.Tasks = append(v.Tasks, task)
+	}
+
+	return v
+}
+
+// Handles /api/tasks/...
+func (s *Server) routeTasks(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			s.listTasks(w)
+		case http.MethodPost:
+			s.createTask(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+
+		return
+	}
+
+	t := s.Manager.GetTask(parts[0])
+
+	if t == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("task %v not found", parts[0]))
+		return
+	}
+
+	if len(parts) == 2 && (parts[1] == "start" || parts[1] == "stop") {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		if t.Runner == nil {
+			writeError(w, http.StatusConflict, "task can't be run")
+			return
+		}
+
+		if parts[1] == "start" {
+			s.Manager.StartTask(t.Runner)
+		} else {
+			s.Manager.StopTask(t.Runner)
+		}
+
+		writeJson(w, http.StatusOK, newTaskView(t))
+		return
+	}
+
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, newTaskView(t))
+	case http.MethodPatch:
+		s.editTask(w, r, t)
+	case http.MethodDelete:
+		s.deleteTask(t)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+func (s *Server) listTasks(w http.ResponseWriter) {
+	s.Manager.TaskMutex.Lock()
+	list := make([]*tasks.Task, 0, len(s.Manager.Tasks))
+
+	for t := range s.Manager.Tasks {
+		list = append(list, t)
+	}
+
+	s.Manager.TaskMutex.Unlock()
+
+	views := make([]taskView, len(list))
+
+	for i, t := range list {
+		views[i] = newTaskView(t)
+	}
+
+	writeJson(w, http.StatusOK, views)
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var req createTaskRequest
+
+	if err := readJson(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if s.Manager.NewTask == nil {
+		writeError(w, http.StatusServiceUnavailable, "no task factory set")
+		return
+	}
+
+	group := s.Manager.GetTaskGroup(req.Group)
+
+	if group == nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown task group %q", req.Group))
+		return
+	}
+
+	siteUrl, err := url.Parse(req.Site.Url)
+
+	if err != nil || (siteUrl.Scheme != "http" && siteUrl.Scheme != "https") || siteUrl.Host == "" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid site url %q", req.Site.Url))
+		return
+	}
+
+	if req.Site.Name == "" {
+		req.Site.Name = siteUrl.Host
+	}
+
+	mode := tasks.ModeShopifySafe
+
+	if req.Mode != "" {
+		if mode, err = tasks.ModeFromString(req.Mode); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if len(req.MonitorInputs) == 0 {
+		writeError(w, http.StatusBadRequest, "no monitor inputs")
+		return
+	}
+
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	if req.Quantity < 0 || req.PaymentRetries < 0 {
+		writeError(w, http.StatusBadRequest, "quantity and payment retries cannot be negative")
+		return
+	}
+
+	profile, ok := s.Manager.Profiles[req.Profile]
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown profile %q", req.Profile))
+		return
+	}
+
+	proxyList, ok := s.Manager.ProxyLists[req.ProxyList]
+
+	if req.ProxyList != "" && !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown proxy list %q", req.ProxyList))
+		return
+	}
+
+	t := s.Manager.NewTask(req.Site, profile, proxyList, mode, req.MonitorInputs, req.Sizes)
+	t.Quantity = req.Quantity
+	t.PaymentRetries = req.PaymentRetries
+
+	group.AddTask(t)
+	s.Manager.AddTask(t)
+
+	writeJson(w, http.StatusCreated, newTaskView(t))
+}
+
+// Edits a task's inputs. Running tasks read their inputs while checking out, so only stopped tasks can be edited.
+func (s *Server) editTask(w http.ResponseWriter, r *http.Request, t *tasks.Task) {
+	var req editTaskRequest
+
+	if err := readJson(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.applyTaskEdit(t, req); err == errTaskRunning {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJson(w, http.StatusOK, newTaskView(t))
+}
+
+var errTaskRunning = errors.New("stop the task before editing it")
+
+// Validates every field before changing any, so a bad edit leaves the task as it was. Returns errTaskRunning if the task
+// is running.
+func (s *Server) applyTaskEdit(t *tasks.Task, req editTaskRequest) error {
+	profile, proxyList := t.Profile, t.ProxyList
+
+	if req.MonitorInputs != nil && len(*req.MonitorInputs) == 0 {
+		return errors.New("no monitor inputs")
+	}
+
+	if req.Profile != nil {
+		p, ok := s.Manager.Profiles[*req.Profile]
+
+		if !ok {
+			return errors.New(fmt.Sprintf("unknown profile %q", *req.Profile))
+		}
+
+		profile = p
+	}
+
+	if req.ProxyList != nil {
+		list, ok := s.Manager.ProxyLists[*req.ProxyList]
+
+		if *req.ProxyList != "" && !ok {
+			return errors.New(fmt.Sprintf("unknown proxy list %q", *req.ProxyList))
+		}
+
+		proxyList = list
+	}
+
+	edited := t.Edit(func() {
+		if req.MonitorInputs != nil {
+			t.MonitorInputs = *req.MonitorInputs
+		}
+
+		if req.Sizes != nil {
+			t.Sizes = *req.Sizes
+		}
+
+		t.Profile = profile
+		t.ProxyList = proxyList
+	})
+
+	if !edited {
+		return errTaskRunning
+	}
+
+	return nil
+}
+
+// Stops a task and removes it from its group and the manager
+func (s *Server) deleteTask(t *tasks.Task) {
+	if t.Runner != nil {
+		s.Manager.StopTask(t.Runner)
+	}
+
+	if t.Group != nil {
+		t.Group.RemoveTask(t)
+	}
+
+	s.Manager.RemoveTask(t)
+}
+
+// Handles /api/groups/...
+func (s *Server) routeGroups(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			s.listGroups(w)
+		case http.MethodPost:
+			s.createGroup(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+
+		return
+	}
+
+	g := s.Manager.GetTaskGroup(parts[0])
+
+	if g == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("task group %v not found", parts[0]))
+		return
+	}
+
+	if len(parts) == 2 && (parts[1] == "start" || parts[1] == "stop") {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		if parts[1] == "start" {
+			s.Manager.StartTaskGroup(g)
+		} else {
+			s.Manager.StopTaskGroup(g)
+		}
+
+		writeJson(w, http.StatusOK, newGroupView(g))
+		return
+	}
+
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, newGroupView(g))
+	case http.MethodDelete:
+		s.Manager.RemoveTaskGroup(g)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+func (s *Server) listGroups(w http.ResponseWriter) {
+	s.Manager.TaskGroupMutex.Lock()
+	list := make([]*tasks.TaskGroup, 0, len(s.Manager.TaskGroups))
+
+	for g := range s.Manager.TaskGroups {
+		list = append(list, g)
+	}
+
+	s.Manager.TaskGroupMutex.Unlock()
+
+	views := make([]groupView, len(list))
+
+	for i, g := range list {
+		views[i] = newGroupView(g)
+	}
+
+	writeJson(w, http.StatusOK, views)
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	var req createGroupRequest
+
+	if err := readJson(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	g := tasks.NewTaskGroup(req.Name)
+
+	if !s.Manager.AddTaskGroupIfAbsent(&g) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("task group %v already exists", req.Name))
+		return
+	}
+
+	writeJson(w, http.StatusCreated, newGroupView(&g))
+}
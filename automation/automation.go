@@ -1,68 +1,73 @@
 package automation
 
 import (
+	"Mystery/keywords"
+	"Mystery/logs"
+	"Mystery/notify"
+	"Mystery/sizes"
 	"Mystery/utils"
 	"fmt"
-	"github.com/disgoorg/disgo/discord"
-	"github.com/disgoorg/disgo/webhook"
-	"github.com/disgoorg/log"
-	"github.com/disgoorg/snowflake/v2"
 	"net/url"
-	"regexp"
-	"strings"
-	"time"
 )
 
 type Automation struct {
-	Name             string   // A unique name for the automation group.
-	MonitorInputs    []string // List of monitor inputs whether it be keywords, links, or variants.
-	Sizes            []string // The size range it will check out. Leave blank for random.
-	Profiles         []string // List of profiles it will use to check out. It distributes profiles equally with TotalTaskCount.
-	ProxyList        string   // The proxy list it will use to check out.
-	CheckUrl         bool     // If it checks the url/handle of the item
-	PriceMinimum     int      // The minimum price in USD the product needs to fall within.
-	PriceMaximum     int      // The maximum price in USD the product needs to fall within.
-	Quantity         int      // The amount of the given product it will attempt to check out.
-	TotalTaskCount   int      // The amount of total tasks that will be run.
-	SiteWhitelist    []string // If not empty, only go for these specific sites and ignore SiteBlacklist. Otherwise, it uses all sites.
-	SiteBlacklist    []string // Ignores products from specific sites.
-	PaymentRetries   int      // The amount of times it'll attempt to retry payment submission.
-	StopAfterMinutes int      // The time in minutes the tasks will stop after.
+	Name                string         `json:"name"`                   // A unique name for the automation group.
+	MonitorInputs       []string       `json:"monitor_inputs"`         // List of monitor inputs whether it be keywords, links, or variants.
+	Sizes               []string       `json:"sizes"`                  // The size range it will check out. Leave blank for random.
+	Profiles            []string       `json:"profiles"`               // List of profiles it will use to check out. It distributes profiles equally with TotalTaskCount.
+	ProxyList           string         `json:"proxy_list"`             // The proxy list it will use to check out.
+	CheckUrl            bool           `json:"check_url"`              // If it checks the url/handle of the item
+	PriceMinimum        int            `json:"price_minimum"`          // The minimum price in USD the product needs to fall within.
+	PriceMaximum        int            `json:"price_maximum"`          // The maximum price in USD the product needs to fall within.
+	Quantity            int            `json:"quantity"`               // The amount of the given product it will attempt to check out.
+	TotalTaskCount      int            `json:"total_task_count"`       // The amount of total tasks that will be run.
+	SiteWhitelist       []string       `json:"site_whitelist"`         // If not empty, only go for these specific sites and ignore SiteBlacklist. Otherwise, it uses all sites.
+	SiteBlacklist       []string       `json:"site_blacklist"`         // Ignores products from specific sites.
+	PaymentRetries      int            `json:"payment_retries"`        // The amount of times it'll attempt to retry payment submission.
+	PaymentRetryDelayMs int            `json:"payment_retry_delay_ms"` // The delay before retrying payment after a decline. Leave at 0 for the default.
+	StopAfterMinutes    int            `json:"stop_after_minutes"`     // The time in minutes the tasks will stop after.
+	VariantStrategy     sizes.Strategy `json:"variant_strategy"`       // How each task chooses between the matching variants. Leave blank for every task to go for the first.
+	VariantSeed         int64          `json:"variant_seed"`           // Makes random variant choices repeat in the same order. Leave at 0 to seed from the time.
+	Notify              notify.Routes  `json:"notify"`                 // The notifiers each event is sent to. Events that aren't listed use the config's default routes.
 }
 
-// IsProductMatch Returns if the product is a match for the automation
+// IsProductMatch Returns if the product is a match for the automation. Keywords are checked against the title, the
+// handle if CheckUrl is set, and then the SKU and name of the first variant. Keywords with a field are checked against
+// that field.
 func (a *Automation) IsProductMatch(live *ZephyrMonitorLive) bool {
 	if len(a.MonitorInputs) == 0 {
 		return false
 	}
 
 	product := live.Body.Payload.Product
+	doc := product.Document()
+	texts := []string{product.Title}
 
-	for _, input := range a.MonitorInputs {
-		switch utils.GetMonitorInputTypeFromString(input) {
-		case utils.MonitorInputTypeKeywords:
-			if utils.IsKeywordMatch(product.Title, input) {
-				return true
-			}
+	if a.CheckUrl {
+		texts = append(texts, product.Handle)
+	}
 
-			if a.CheckUrl && utils.IsKeywordMatch(product.Handle, input) {
-				return true
-			}
+	if len(product.Variants) > 0 {
+		// Check the variant name as a last resort
+		texts = append(texts, product.Variants[0].Sku, product.Variants[0].Name)
+	}
+
+	for _, input := range a.MonitorInputs {
+		if utils.GetMonitorInputTypeFromString(input) != utils.MonitorInputTypeKeywords {
+			continue
+		}
 
-			if len(product.Variants) > 0 {
-				variant := product.Variants[0]
+		query, err := keywords.Parse(input)
 
-				// Check SKU
-				if utils.IsKeywordMatch(variant.Sku, input) {
-					return true
-				}
+		if err != nil {
+			logs.Error("Invalid keywords", "automation", a.Name, "keywords", input, "error", err)
+			continue
+		}
 
-				// Check variant name as a last resort
-				if utils.IsKeywordMatch(variant.Name, input) {
-					return true
-				}
+		for _, text := range texts {
+			if query.Match(text, doc) {
+				return true
 			}
-
 		}
 	}
 
@@ -74,7 +79,7 @@ func (a *Automation) IsWebsiteMatch(live *ZephyrMonitorLive) bool {
 	productStoreUrl, err := url.Parse(live.Body.Payload.Store)
 
 	if err != nil {
-		log.Error(err)
+		logs.Error("Invalid product store URL", "automation", a.Name, "error", err)
 		return false
 	}
 
@@ -85,7 +90,7 @@ func (a *Automation) IsWebsiteMatch(live *ZephyrMonitorLive) bool {
 			siteUrl, err := url.Parse(site)
 
 			if err != nil {
-				log.Error(err)
+				logs.Error("Invalid site URL", "automation", a.Name, "site", site, "error", err)
 				return false
 			}
 
@@ -98,7 +103,7 @@ func (a *Automation) IsWebsiteMatch(live *ZephyrMonitorLive) bool {
 			siteUrl, err := url.Parse(site)
 
 			if err != nil {
-				log.Error(err)
+				logs.Error("Invalid site URL", "automation", a.Name, "site", site, "error", err)
 				return false
 			}
 
@@ -117,97 +122,57 @@ func (a *Automation) IsPriceMatch(live *ZephyrMonitorLive) bool {
 	variants := live.Body.Payload.Product.Variants
 
 	if len(variants) == 0 {
-		log.Error("No variants found for product")
+		logs.Error("No variants found for product", "automation", a.Name, "product", live.Body.Payload.Product.Title)
 		return false
 	}
 
 	price, err := variants[0].Price.Float64()
 
 	if err != nil {
-		log.Error(err)
+		logs.Error("Invalid product price", "automation", a.Name, "product", live.Body.Payload.Product.Title, "error", err)
 		return false
 	}
 
 	return price >= float64(a.PriceMinimum) && price <= float64(a.PriceMaximum)
 }
 
-// GetMatchingSizeVariants Gets variants that match the specified sizes
+// GetMatchingSizeVariants Gets the available variants that match the specified sizes
 func (a *Automation) GetMatchingSizeVariants(live *ZephyrMonitorLive) []ZephyrMonitorLiveProductVariant {
-	productVariants := live.Body.Payload.Product.Variants
-	var matched []ZephyrMonitorLiveProductVariant
+	matcher, err := sizes.NewMatcher(a.Sizes)
 
-	for _, variant := range productVariants {
-		if !variant.Available {
-			continue
-		}
+	if err != nil {
+		logs.Error("Invalid sizes", "automation", a.Name, "error", err)
+		return nil
+	}
+
+	product := live.Body.Payload.Product
+	option := product.SizeOption()
+	var matched []ZephyrMonitorLiveProductVariant
 
-		if len(a.Sizes) == 0 {
+	for _, variant := range product.Variants {
+		if variant.Available && matcher.Match(variant.Option(option)) {
 			matched = append(matched, variant)
-		} else {
-			for _, size := range a.Sizes {
-				// Shoe sizes are determined if the variant option contains ANY number
-				if regexp.MustCompile(`\d`).MatchString(variant.Title) {
-					// Isolating the numbers from the size, so we can check against that
-					regex := regexp.MustCompile("[^0-9.]+")
-					rawSize := regex.ReplaceAllString(variant.Title, "")
-
-					if rawSize == size {
-						matched = append(matched, variant)
-					}
-				} else {
-					// Otherwise, we can safely assume it's clothing and match the exact size.
-					// Not using contains because "L" can pick up XL, XXL, etc.
-					if variant.Title == size {
-						matched = append(matched, variant)
-					}
-				}
-			}
 		}
 	}
 
 	return matched
 }
 
-// SendWebhook SendAutomationWebhook Sends a webhook to signal that automation for a product has started/stopped
-func (a *Automation) SendWebhook(started bool, live *ZephyrMonitorLive) {
-	client := webhook.New(snowflake.ID(0), "")
-	embed := discord.NewEmbedBuilder()
-
-	if started {
-		embed.SetAuthor("\U0001F7E2 Automation Started", "", "https://i.imgur.com/RLauXcd.png")
-		embed.SetColor(0x00FF00)
-	} else {
-		embed.SetAuthor("🔴 Automation Stopped", "", "https://i.imgur.com/RLauXcd.png")
-		embed.SetColor(0xFF0000)
-	}
-
+// NewEvent Creates the event that signals automation for a product has started/stopped
+func (a *Automation) NewEvent(started bool, live *ZephyrMonitorLive) notify.Event {
 	store := live.Body.Payload.Store
 	product := live.Body.Payload.Product
 
-	embed.SetThumbnail(product.GetImage())
-	embed.AddField("Automation Name", a.Name, false)
-	embed.AddField("Site", store, false)
-	embed.AddField("Product", fmt.Sprintf("[%v](%vproducts/%v)", product.Title, store, product.Handle), false)
-
-	sizes := "Random"
-
-	if len(a.Sizes) > 0 {
-		sizes = strings.Join(a.Sizes, ", ")
-	}
-
-	embed.AddField("Sizes", sizes, true)
-	embed.AddField("Quanitty", fmt.Sprintf("%v", a.Quantity), true)
-	embed.AddField("Task Count", fmt.Sprintf("%v", a.TotalTaskCount), true)
-	embed.AddField("Payment Retries", fmt.Sprintf("%v", a.PaymentRetries), true)
-	embed.AddField("Stop After Minutes", fmt.Sprintf("%v", a.StopAfterMinutes), true)
-
-	embed.SetFooter("Nothing", "https://i.imgur.com/RLauXcd.png")
-	embed.SetTimestamp(time.Now())
-
-	_, err := client.CreateEmbeds([]discord.Embed{embed.Build()})
-
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+	return notify.NewAutomationEvent(started, notify.AutomationEvent{
+		Name:             a.Name,
+		Site:             store,
+		ProductTitle:     product.Title,
+		ProductUrl:       fmt.Sprintf("%vproducts/%v", store, product.Handle),
+		ProductImage:     product.GetImage(),
+		Sizes:            a.Sizes,
+		Quantity:         a.Quantity,
+		TaskCount:        a.TotalTaskCount,
+		PaymentRetries:   a.PaymentRetries,
+		StopAfterMinutes: a.StopAfterMinutes,
+	})
 }
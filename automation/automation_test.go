@@ -1,6 +1,7 @@
 package automation
 
 import (
+	"Mystery/notify"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -57,6 +58,40 @@ func TestAutomationProductMatchCheckUrl(t *testing.T) {
 	}
 }
 
+// Tests matching phrases and fields, and that keywords that can't be parsed are skipped
+func TestAutomationProductMatchQuery(t *testing.T) {
+	zephyr := ZephyrMonitorLive{
+		Body: ZephyrMonitorLiveBody{
+			Payload: ZephyrMonitorLivePayload{
+				Product: ZephyrMonitorLiveProduct{
+					Handle:   "nkdd1399-300",
+					Title:    "Nike Dunk High Retro BTTYS - Noble Green / White",
+					Vendor:   "Nike",
+					Variants: []ZephyrMonitorLiveProductVariant{{Sku: "DD1399-300-9"}},
+				},
+				Store: "https://kith.com/",
+			},
+		},
+	}
+
+	tests := map[string]bool{
+		`+"dunk high",+vendor:nike`: true,
+		`+"high dunk"`:              false,
+		"+jordan|dunk,-low":         true,
+		"+sku:/dd1399-3\\d\\d/":     true,
+		"+vendor:adidas,+dunk":      false,
+		"+dunk,":                    false,
+	}
+
+	for input, expected := range tests {
+		auto := Automation{Name: "Test", MonitorInputs: []string{input}}
+
+		if auto.IsProductMatch(&zephyr) != expected {
+			t.Fatalf("%v: expected a match to be %v", input, expected)
+		}
+	}
+}
+
 // Tests if an automation website matches with whitelist only
 func TestAutomationWebsiteMatchWhitelist(t *testing.T) {
 	auto := Automation{
@@ -152,7 +187,44 @@ func TestAutomationGetSizeMatch(t *testing.T) {
 	}
 }
 
-func TestSendAutomationWebhook(t *testing.T) {
+// Tests matching sizes held by the second option in another size system
+func TestAutomationGetSizeMatchOption(t *testing.T) {
+	live := ZephyrMonitorLive{Body: ZephyrMonitorLiveBody{Payload: ZephyrMonitorLivePayload{Product: ZephyrMonitorLiveProduct{
+		Options: []interface{}{map[string]interface{}{"name": "Color"}, map[string]interface{}{"name": "Size"}},
+		Variants: []ZephyrMonitorLiveProductVariant{
+			{Id: 1, Available: true, Option1: "Black", Option2: "US 9 / EU 42.5"},
+			{Id: 2, Available: true, Option1: "Black", Option2: "US 10 / EU 44"},
+			{Id: 3, Available: false, Option1: "Black", Option2: "US 10.5 / EU 44.5"},
+			{Id: 4, Available: true, Option1: "Black", Option2: "US 11 / EU 45"},
+		},
+	}}}}
+
+	tests := map[string][]int64{
+		"9":         {1},
+		"EU 44-45":  {2, 4},
+		"UK 8-9.5":  {1, 2},
+		"Black":     nil,
+		"9, 10, 11": {1, 2, 4},
+	}
+
+	for spec, expected := range tests {
+		auto := Automation{Sizes: []string{spec}}
+		matched := auto.GetMatchingSizeVariants(&live)
+
+		if len(matched) != len(expected) {
+			t.Fatalf("%v: expected variants %v. got %+v", spec, expected, matched)
+		}
+
+		for i, variant := range matched {
+			if variant.Id != expected[i] {
+				t.Fatalf("%v: expected variants %v. got %+v", spec, expected, matched)
+			}
+		}
+	}
+}
+
+// Tests the automation started event for a product
+func TestAutomationEvent(t *testing.T) {
 	a := Automation{
 		Name:             "TEST",
 		MonitorInputs:    nil,
@@ -178,6 +250,30 @@ func TestSendAutomationWebhook(t *testing.T) {
 		return
 	}
 
-	fmt.Println(product.Body.Payload.Product.GetImage())
-	a.SendWebhook(true, &product)
+	e := a.NewEvent(true, &product)
+
+	if e.Type != notify.EventAutomationStarted || e.Automation == nil {
+		t.Fatalf("expected an automation started event. got %v", e.Type)
+	}
+
+	expected := notify.AutomationEvent{
+		Name:             "TEST",
+		Site:             "https://www.laces.mx/",
+		ProductTitle:     "adidas adiFOM Q Gresix",
+		ProductUrl:       "https://www.laces.mx/products/adifom-q-cblack-carbon-gresix-laces-mexico",
+		ProductImage:     "https://cdn.shopify.com/s/files/1/0018/4506/7865/products/HP6586_5_FOOTWEAR_Photography_SideMedialCenterView_white.jpg?v=1668528534",
+		Sizes:            []string{"7.5", "12.5"},
+		Quantity:         1,
+		TaskCount:        100,
+		PaymentRetries:   1,
+		StopAfterMinutes: 5,
+	}
+
+	if fmt.Sprintf("%+v", *e.Automation) != fmt.Sprintf("%+v", expected) {
+		t.Fatalf("unexpected automation event.\nexpected %+v\ngot      %+v", expected, *e.Automation)
+	}
+
+	if a.NewEvent(false, &product).Type != notify.EventAutomationStopped {
+		t.Fatal("expected an automation stopped event")
+	}
 }
@@ -1,10 +1,10 @@
 package automation
 
 import (
+	"Mystery/logs"
 	"encoding/json"
 	"fmt"
 	"github.com/sacOO7/gowebsocket"
-	"log"
 )
 
 // ZephyrMonitorMessage Standard monitor message which only parses the type, so the correct struct can be parsed.
@@ -25,22 +25,25 @@ var ZephyrMonitorPreviousAntibot *ZephyrMonitorMessageAntibot
 // ZephyrMonitorChannel Channel for zephyr monitor products
 var ZephyrMonitorChannel = make(chan *ZephyrMonitorLive, 1)
 
+// ZephyrMonitorKey The Zephyr AIO license key used to authenticate with the monitor.
+var ZephyrMonitorKey = ""
+
+// ZephyrMonitorUri The websocket URI of the Zephyr monitor.
+var ZephyrMonitorUri = ""
+
 // ConnectToZephyrMonitor Connects to the Z-AIO monitor.
 func ConnectToZephyrMonitor() {
-	const MonitorKey string = ""
-	const MonitorUri string = ""
-
-	socket := gowebsocket.New(MonitorUri)
+	socket := gowebsocket.New(ZephyrMonitorUri)
 
 	socket.OnConnected = func(socket gowebsocket.Socket) {
-		log.Println("Connected to monitor. Authenticating...")
-		socket.SendText(fmt.Sprintf("{\"server\":null,\"type\":\"auth\",\"version\":\"1.9.76\",\"key\":\"%v\"}", MonitorKey))
-		socket.SendText(fmt.Sprintf("{\"wsServerDisconnections\":0,\"shippingRates\":[],\"tasks247Footsites\":0,\"interval\":3600000,\"type\":\"tasksStats\",\"version\":\"1.9.76\",\"tasks247\":0,\"key\":\"%v\",\"tasks\":0,\"wsClientDisconnections\":0}", MonitorKey))
-		log.Println("Sent authentication details.")
+		logs.Info("Connected to monitor. Authenticating...")
+		socket.SendText(fmt.Sprintf("{\"server\":null,\"type\":\"auth\",\"version\":\"1.9.76\",\"key\":\"%v\"}", ZephyrMonitorKey))
+		socket.SendText(fmt.Sprintf("{\"wsServerDisconnections\":0,\"shippingRates\":[],\"tasks247Footsites\":0,\"interval\":3600000,\"type\":\"tasksStats\",\"version\":\"1.9.76\",\"tasks247\":0,\"key\":\"%v\",\"tasks\":0,\"wsClientDisconnections\":0}", ZephyrMonitorKey))
+		logs.Info("Sent authentication details.")
 	}
 
 	socket.OnConnectError = func(err error, socket gowebsocket.Socket) {
-		log.Println("Monitor Connection Error: ", err)
+		logs.Error("Monitor connection error", "error", err)
 		ConnectToZephyrMonitor()
 		return
 	}
@@ -50,24 +53,24 @@ func ConnectToZephyrMonitor() {
 	}
 
 	socket.OnBinaryMessage = func(data []byte, socket gowebsocket.Socket) {
-		log.Println("Received Binary Message: ", data)
+		logs.Debug("Received binary message", "data", data)
 	}
 
 	socket.OnPingReceived = func(data string, socket gowebsocket.Socket) {
-		socket.SendText(fmt.Sprintf("{\"type\":\"ping\",\"key\":\"%v\"}", MonitorKey))
+		socket.SendText(fmt.Sprintf("{\"type\":\"ping\",\"key\":\"%v\"}", ZephyrMonitorKey))
 	}
 
 	socket.OnPongReceived = func(data string, socket gowebsocket.Socket) {
-		log.Println("Received Pong: " + data)
+		logs.Debug("Received pong", "data", data)
 	}
 
 	socket.OnDisconnected = func(err error, socket gowebsocket.Socket) {
-		log.Println("Disconnected from monitor. Reconnecting.")
+		logs.Warn("Disconnected from monitor. Reconnecting.", "error", err)
 		ConnectToZephyrMonitor()
 		return
 	}
 
-	log.Println("Connecting to monitor...")
+	logs.Info("Connecting to monitor...")
 	socket.Connect()
 }
 
@@ -76,19 +79,18 @@ func handleZephyrMonitorMessage(message string) {
 	var rawMsg ZephyrMonitorMessage
 
 	if err := json.Unmarshal([]byte(message), &rawMsg); err != nil {
-		log.Println("Failed to unmarshal monitor message.")
-		log.Println(err)
+		logs.Error("Failed to unmarshal monitor message", "error", err)
 		return
 	}
 
 	switch rawMsg.Type {
 	case "pinConfig":
 		if err := json.Unmarshal([]byte(message), &ZephyrMonitorSiteList); err != nil {
-			log.Printf("Failed to unmarshal monitor message: %v\n", err)
+			logs.Error("Failed to unmarshal monitor message", "type", rawMsg.Type, "error", err)
 			return
 		}
 
-		log.Printf("Received %v sites from the monitor.\n", len(ZephyrMonitorSiteList.Body))
+		logs.Info("Received sites from the monitor", "count", len(ZephyrMonitorSiteList.Body))
 	case "livemonitorInit":
 		return
 	case "livemonitorInitFootsites":
@@ -103,18 +105,18 @@ func handleZephyrMonitorMessage(message string) {
 		ZephyrMonitorPreviousAntibot = ZephyrMonitorCurrentAntibot
 
 		if err := json.Unmarshal([]byte(message), &ZephyrMonitorCurrentAntibot); err != nil {
-			log.Printf("Failed to unmarshal monitor message: %v\n", err)
+			logs.Error("Failed to unmarshal monitor message", "type", rawMsg.Type, "error", err)
 			return
 		}
 
-		log.Printf("Received anti-bot data for %v sites.\n", len(ZephyrMonitorCurrentAntibot.Sites))
+		logs.Info("Received anti-bot data from the monitor", "count", len(ZephyrMonitorCurrentAntibot.Sites))
 	case "livemonitor":
-		log.Printf(message)
+		logs.Debug("Received live product message", "message", message)
 
 		var liveProduct ZephyrMonitorLive
 
 		if err := json.Unmarshal([]byte(message), &liveProduct); err != nil {
-			log.Printf("Failed to unmarshal monitor message: %v\n", err)
+			logs.Error("Failed to unmarshal monitor message", "type", rawMsg.Type, "error", err)
 			return
 		}
 
@@ -125,7 +127,7 @@ func handleZephyrMonitorMessage(message string) {
 
 		ZephyrMonitorChannel <- &liveProduct
 		p := liveProduct.Body.Payload.Product
-		log.Printf("Received incoming liveProduct - Store: %v | Title: %v | Price: %v\n", liveProduct.Body.Payload.Store, p.Title, p.Variants[0].Price)
+		logs.Info("Received live product", "store", liveProduct.Body.Payload.Store, "title", p.Title, "price", p.Variants[0].Price)
 	case "pong":
 		return
 	}
@@ -1,6 +1,6 @@
 package automation
 
-import "log"
+import "Mystery/logs"
 
 type ZephyrMonitorMessageAntibot struct {
 	Type  string                            `json:"type"`
@@ -15,6 +15,6 @@ type ZephyrMonitorMessageAntibotSite struct {
 
 func (m *ZephyrMonitorMessageAntibot) PrintSites() {
 	for _, s := range m.Sites {
-		log.Printf("%v - %v\n", s.Url, s.Antibot)
+		logs.Info("Anti-bot", "site", s.Url, "antibot", s.Antibot)
 	}
 }
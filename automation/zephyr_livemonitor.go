@@ -1,6 +1,8 @@
 package automation
 
 import (
+	"Mystery/keywords"
+	"Mystery/sizes"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -40,7 +42,7 @@ type ZephyrMonitorLiveProduct struct {
 	Id            int64                             `json:"id,omitempty"`
 	PublishedAt   time.Time                         `json:"published_at,omitempty"`
 	Timestamp     int64                             `json:"timestamp,omitempty"`
-	FeaturedImage string                            `json:"featured_image,omitempty""`
+	FeaturedImage string                            `json:"featured_image,omitempty"`
 }
 
 type ZephyrMonitorLiveProductImage struct {
@@ -72,6 +74,9 @@ type ZephyrMonitorLiveProductVariant struct {
 	Sku              string                                  `json:"sku,omitempty"`
 	Barcode          string                                  `json:"barcode,omitempty"`
 	Name             string                                  `json:"name,omitempty"`
+	Option1          string                                  `json:"option1,omitempty"`
+	Option2          string                                  `json:"option2,omitempty"`
+	Option3          string                                  `json:"option3,omitempty"`
 }
 
 type ZephyrMonitorLiveProductVariantOption struct {
@@ -98,3 +103,66 @@ func (p *ZephyrMonitorLiveProduct) GetImage() string {
 
 	return ""
 }
+
+// Document Returns the fields of the product that keywords can be limited to. The monitor doesn't send tags.
+func (p *ZephyrMonitorLiveProduct) Document() keywords.Document {
+	doc := keywords.Document{
+		keywords.FieldTitle:  {p.Title},
+		keywords.FieldHandle: {p.Handle},
+		keywords.FieldVendor: {p.Vendor},
+	}
+
+	for _, variant := range p.Variants {
+		doc[keywords.FieldSku] = append(doc[keywords.FieldSku], variant.Sku)
+	}
+
+	return doc
+}
+
+// SizeOption Returns which option of the product's variants holds the size. Defaults to the first option when no
+// option looks like a size.
+func (p *ZephyrMonitorLiveProduct) SizeOption() int {
+	names := sizes.OptionNames(p.Options)
+	variantOptions := make([][]string, len(p.Variants))
+
+	for i, variant := range p.Variants {
+		for j := 0; j < 3; j++ {
+			variantOptions[i] = append(variantOptions[i], variant.Option(j))
+		}
+
+		// The option names are only sent with the values by some sites
+		if len(names) == 0 {
+			for _, option := range variant.OptionValues {
+				names = append(names, option.Name)
+			}
+		}
+	}
+
+	if option := sizes.DetectOption(names, variantOptions); option != -1 {
+		return option
+	}
+
+	return 0
+}
+
+// Option Returns the variant's option from 0 for Option1 to 2 for Option3. Variants sent without their options only
+// have their title, which is returned for the first option.
+func (v *ZephyrMonitorLiveProductVariant) Option(i int) string {
+	options := []string{v.Option1, v.Option2, v.Option3}
+
+	if v.Option1 == "" {
+		options = []string{v.Title, "", ""}
+
+		for j, option := range v.OptionValues {
+			if j < len(options) {
+				options[j] = option.Value
+			}
+		}
+	}
+
+	if i < 0 || i >= len(options) {
+		return ""
+	}
+
+	return options[i]
+}
@@ -1,7 +1,7 @@
 package automation
 
 import (
-	"log"
+	"Mystery/logs"
 )
 
 type MonitorMessagePinConfig struct {
@@ -11,6 +11,6 @@ type MonitorMessagePinConfig struct {
 
 func (m *MonitorMessagePinConfig) PrintSites() {
 	for k, _ := range m.Body {
-		log.Println(k)
+		logs.Info("Site", "site", k)
 	}
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"Mystery/config"
+	"Mystery/history"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const historyUsage = `Usage: nothing history <command> [flags]
+
+Commands:
+  rates     Success rate per site and mode
+  spend     Spend on successful checkouts per profile
+  declines  Declines by reason
+  export    Every checkout attempt as CSV
+
+Flags:
+`
+
+// Runs a history command with the arguments after "history"
+func runHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	configPath := flags.String("config", "config.json", "Path to the config file with the history database.")
+	dbPath := flags.String("db", "", "Path to the history database. Takes precedence over the config.")
+	site := flags.String("site", "", "Only include checkouts on this site.")
+	since := flags.Duration("since", 0, "Only include checkouts from this long ago, ex. 24h.")
+	out := flags.String("out", "", "File to export to. Defaults to stdout.")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), historyUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.New("missing history command")
+	}
+
+	command := args[0]
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *dbPath == "" {
+		config.ProfilePassphrase = os.Getenv("NOTHING_PROFILE_PASSPHRASE")
+		cfg, err := config.Load(*configPath)
+
+		if err != nil {
+			return err
+		}
+
+		if cfg.History == "" {
+			return errors.New(fmt.Sprintf("%v has no history database", *configPath))
+		}
+
+		*dbPath = cfg.History
+	}
+
+	store, err := history.OpenReadOnly(*dbPath)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	filter := history.Filter{Site: *site}
+
+	if *since > 0 {
+		filter.Since = time.Now().Add(-*since)
+	}
+
+	records, err := store.Records(filter)
+
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	switch command {
+	case "rates":
+		fmt.Fprintln(w, "SITE\tMODE\tATTEMPTS\tSUCCESSES\tDECLINES\tSUCCESS RATE")
+
+		for _, r := range history.SuccessRates(records) {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%.1f%%\n", r.Site, r.Mode, r.Attempts, r.Successes, r.Declines, r.Rate()*100)
+		}
+	case "spend":
+		fmt.Fprintln(w, "PROFILE\tORDERS\tSPENT")
+
+		for _, s := range history.SpendByProfile(records) {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", s.Profile, s.Orders, history.FormatPrice(s.Total))
+		}
+	case "declines":
+		fmt.Fprintln(w, "DECLINES\tREASON")
+
+		for _, d := range history.DeclinesByReason(records) {
+			fmt.Fprintf(w, "%v\t%v\n", d.Count, d.Reason)
+		}
+	case "export":
+		return exportHistory(records, *out)
+	default:
+		flags.Usage()
+		return errors.New(fmt.Sprintf("unknown history command %v", command))
+	}
+
+	return nil
+}
+
+// Writes the records as CSV to the file, or stdout if no file is given
+func exportHistory(records []history.Record, path string) error {
+	if path == "" {
+		return history.WriteCsv(os.Stdout, records)
+	}
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := history.WriteCsv(file, records); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"Mystery/api"
+	"Mystery/automation"
+	"Mystery/config"
+	"Mystery/logs"
+	"Mystery/metrics"
+	"Mystery/notify"
+	"Mystery/tui"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		if err := runHistory(os.Args[2:]); err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "profiles" {
+		if err := runProfiles(os.Args[2:]); err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		return
+	}
+
+	configPath := flag.String("config", "config.json", "Path to the JSON or YAML config file.")
+	dashboardEnabled := flag.Bool("tui", false, "Show a terminal dashboard of the task groups instead of console logs.")
+	flag.Parse()
+
+	config.ProfilePassphrase = os.Getenv("NOTHING_PROFILE_PASSPHRASE")
+
+	cfg, err := config.Load(*configPath)
+
+	if err != nil {
+		log.Fatalf("Failed to load config: %v\n", err)
+	}
+
+	var logBuffer *tui.LogBuffer
+	var extraHandlers []logs.Handler
+
+	// The dashboard owns the terminal, so console logs go to its log pane instead
+	if *dashboardEnabled {
+		cfg.Log.Format = "none"
+		logBuffer = tui.NewLogBuffer(logs.LevelInfo, 50)
+		extraHandlers = append(extraHandlers, logBuffer)
+	}
+
+	closeLog, err := cfg.ApplyLogging(extraHandlers...)
+
+	if err != nil {
+		log.Fatalf("Failed to open log file: %v\n", err)
+	}
+
+	defer closeLog()
+
+	if err := cfg.ApplyGateways(); err != nil {
+		log.Fatalf("Failed to load gateway file: %v\n", err)
+	}
+
+	m := cfg.Build()
+	store, err := cfg.OpenHistory()
+
+	if err != nil {
+		log.Fatalf("Failed to open history database: %v\n", err)
+	}
+
+	if store != nil {
+		m.History = store
+		defer store.Close()
+	}
+
+	logs.Info("Loaded config", "task_groups", len(m.TaskGroups), "tasks", len(m.Tasks), "automations", len(m.Automations))
+
+	if len(m.Automations) > 0 {
+		cfg.ApplyZephyr()
+		m.HandleNewAutomationProducts()
+		go automation.ConnectToZephyrMonitor()
+	}
+
+	if cfg.Api.Address != "" {
+		token := cfg.Api.Token
+
+		if env := os.Getenv("NOTHING_API_TOKEN"); env != "" {
+			token = env
+		}
+
+		server := api.NewServer(m, token)
+		defer server.Close()
+
+		go func() {
+			if err := server.ListenAndServe(cfg.Api.Address); err != nil {
+				logs.Error("API stopped", "error", err)
+			}
+		}()
+	}
+
+	if cfg.Metrics.Address != "" {
+		m.RegisterMetrics(metrics.Default)
+		notify.RegisterMetrics(metrics.Default)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Default)
+		server := &http.Server{Addr: cfg.Metrics.Address, Handler: mux}
+		defer server.Close()
+
+		go func() {
+			logs.Info("Serving metrics", "address", cfg.Metrics.Address)
+
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				logs.Error("Metrics server stopped", "error", err)
+			}
+		}()
+	}
+
+	stopHealthChecks := cfg.StartHealthChecks(m)
+	defer stopHealthChecks()
+
+	stopMonitorEvents := cfg.StartMonitorEvents(m)
+	defer stopMonitorEvents()
+
+	m.StartAllTaskGroups()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	finished := make(chan struct{})
+
+	// Automations and the API can start new tasks at any time, so only exit on our own when there is nothing left to
+	// wait for.
+	if len(m.Automations) == 0 && cfg.Api.Address == "" {
+		go func() {
+			m.WaitForAllTasks()
+			close(finished)
+		}()
+	}
+
+	// Closing the dashboard stops everything the same way a signal does
+	dashboardClosed := make(chan struct{})
+	var dashboard *tui.Dashboard
+
+	if *dashboardEnabled {
+		dashboard = tui.NewDashboard(m, logBuffer)
+
+		go func() {
+			if err := dashboard.Run(os.Stdin, os.Stdout); err != nil {
+				// Console logs are off, so this is the only way to see why
+				log.Printf("Failed to run the dashboard: %v\n", err)
+			}
+
+			close(dashboardClosed)
+		}()
+	}
+
+	stop := func() {
+		m.StopAllAutomationGroups()
+		m.StopAllTaskGroups()
+		m.WaitForAllTasks()
+	}
+
+	select {
+	case sig := <-signals:
+		if dashboard != nil {
+			dashboard.Stop()
+			<-dashboardClosed
+		}
+
+		logs.Info("Stopping all tasks...", "signal", sig)
+		stop()
+	case <-dashboardClosed:
+		logs.Info("Stopping all tasks...", "reason", "dashboard closed")
+		stop()
+	case <-finished:
+		if dashboard != nil {
+			dashboard.Stop()
+			<-dashboardClosed
+		}
+
+		logs.Info("All tasks have finished.")
+	}
+
+	if err := m.Notifications.Close(10 * time.Second); err != nil {
+		logs.Error("Failed to send every notification", "error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"Mystery/config"
+	"Mystery/profiles"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const profilesUsage = `Usage: nothing profiles <command> [flags]
+
+Commands:
+  import      Add the profiles in a CSV file
+  export      Write every profile as CSV
+  passphrase  Encrypt the profile file with NOTHING_PROFILE_NEW_PASSPHRASE, or change its passphrase if it's
+              already encrypted
+
+The profile file is decrypted with NOTHING_PROFILE_PASSPHRASE.
+
+Flags:
+`
+
+// Runs a profiles command with the arguments after "profiles"
+func runProfiles(args []string) error {
+	flags := flag.NewFlagSet("profiles", flag.ContinueOnError)
+	configPath := flags.String("config", "config.json", "Path to the config file with the profile file.")
+	filePath := flags.String("file", "", "Path to the profile file. Takes precedence over the config.")
+	in := flags.String("in", "", "CSV file to import.")
+	out := flags.String("out", "", "File to export to. Defaults to stdout.")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), profilesUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.New("missing profiles command")
+	}
+
+	command := args[0]
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	passphrase := os.Getenv("NOTHING_PROFILE_PASSPHRASE")
+
+	if *filePath == "" {
+		config.ProfilePassphrase = passphrase
+		cfg, err := config.Load(*configPath)
+
+		if err != nil {
+			return err
+		}
+
+		if cfg.ProfileFile == "" {
+			return errors.New(fmt.Sprintf("%v has no profile file", *configPath))
+		}
+
+		*filePath = cfg.ProfileFile
+	}
+
+	store, err := profiles.LoadEncryptedStore(*filePath, passphrase)
+
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "import":
+		return importProfiles(store, *in)
+	case "export":
+		return exportProfiles(store, *out)
+	case "passphrase":
+		return changeProfilePassphrase(store, passphrase, os.Getenv("NOTHING_PROFILE_NEW_PASSPHRASE"))
+	default:
+		flags.Usage()
+		return errors.New(fmt.Sprintf("unknown profiles command %v", command))
+	}
+}
+
+// Adds the profiles in the CSV file to the store and saves it
+func importProfiles(store *profiles.Store, path string) error {
+	if path == "" {
+		return errors.New("missing CSV file to import (-in)")
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	count, err := store.ImportCsv(file)
+
+	if err != nil {
+		return err
+	}
+
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %v profiles\n", count)
+	return nil
+}
+
+// Writes every profile as CSV to the file, or stdout if no file is given
+func exportProfiles(store *profiles.Store, path string) error {
+	if path == "" {
+		return store.ExportCsv(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := store.ExportCsv(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Encrypts the store's file with the new passphrase, or re-encrypts it if it's already encrypted
+func changeProfilePassphrase(store *profiles.Store, current string, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("NOTHING_PROFILE_NEW_PASSPHRASE is not set")
+	}
+
+	if store.IsEncrypted() {
+		return store.ChangePassphrase(current, passphrase)
+	}
+
+	if err := store.SetPassphrase(passphrase); err != nil {
+		return err
+	}
+
+	return store.Save()
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"Mystery/automation"
+	"Mystery/discord"
+	"Mystery/history"
+	"Mystery/logs"
+	"Mystery/notify"
+	"Mystery/profiles"
+	"Mystery/proxies"
+	"Mystery/sizes"
+	"Mystery/tasks"
+	"Mystery/tasks/shopify"
+	"os"
+	"strings"
+	"time"
+)
+
+// Build Creates a task manager with every task group and automation from the config.
+// The config is expected to have been validated already.
+func (c *Config) Build() *tasks.Manager {
+	m := tasks.NewManager()
+
+	sites := map[string]tasks.Website{}
+
+	for _, site := range c.Sites {
+		sites[site.Name] = site
+	}
+
+	profileNames := map[string]*profiles.Profile{}
+
+	for i := range c.Profiles {
+		profileNames[c.Profiles[i].Name] = &c.Profiles[i]
+	}
+
+	proxyLists := map[string]*proxies.ProxyList{}
+
+	for _, list := range c.ProxyLists {
+		pl := proxies.NewProxyList(list.Name, strings.Join(list.Proxies, "\n"))
+		pl.Strategy = proxies.RotationStrategy(list.Strategy)
+		pl.Cooldown = time.Duration(list.CooldownSeconds) * time.Second
+		proxyLists[list.Name] = &pl
+	}
+
+	monitors := map[string]*shopify.SiteMonitor{}
+
+	for _, monitor := range c.Monitors {
+		interval := time.Duration(monitor.IntervalMs) * time.Millisecond
+		monitors[monitor.Site] = shopify.NewSiteMonitor(sites[monitor.Site], proxyLists[monitor.ProxyList], interval)
+	}
+
+	for _, g := range c.TaskGroups {
+		group := tasks.NewTaskGroup(g.Name)
+		group.RetryPolicies = g.Retry
+		group.Notify = g.Notify
+
+		for _, t := range g.Tasks {
+			mode := tasks.ModeShopifySafe
+
+			if t.Mode != "" {
+				mode, _ = tasks.ModeFromString(t.Mode)
+			}
+
+			count := t.Count
+
+			if count == 0 {
+				count = 1
+			}
+
+			for i := 0; i < count; i++ {
+				task := shopify.NewTaskShopify(sites[t.Site], profileNames[t.Profile], proxyLists[t.ProxyList], mode, t.MonitorInputs, t.Sizes)
+				task.Monitor = monitors[t.Site]
+
+				if t.Quantity > 0 {
+					task.Quantity = t.Quantity
+				}
+
+				task.PaymentRetries = t.PaymentRetries
+
+				switch {
+				case t.RotateProxyAfter > 0:
+					task.RotateProxyAfter = t.RotateProxyAfter
+				case t.RotateProxyAfter < 0:
+					task.RotateProxyAfter = 0
+				}
+
+				task.RetryPolicies = t.Retry
+				task.VariantStrategy = sizes.Strategy(t.VariantStrategy)
+				task.VariantSeed = t.VariantSeed
+
+				group.AddTask(&task.Task)
+			}
+		}
+
+		m.AddTaskGroup(&group)
+	}
+
+	for i := range c.Automations {
+		m.Automations = append(m.Automations, &c.Automations[i])
+	}
+
+	m.Notifications = c.buildNotifications()
+	m.TaskLogDir = c.Log.TaskDir
+	m.Profiles = profileNames
+	m.ProxyLists = proxyLists
+	m.NewTask = newShopifyTaskFactory(monitors)
+	c.monitors = monitors
+
+	return &m
+}
+
+// The amount of undelivered events each notifier holds before new ones are dropped
+const notificationQueueSize = 100
+
+// Creates a router with a sink for every notifier. Sinks deliver in the background so tasks never wait on them.
+func (c *Config) buildNotifications() *notify.Router {
+	router := notify.NewRouter()
+	router.Defaults = c.Notify
+
+	for _, n := range c.Notifiers {
+		var sink notify.Notifier
+
+		switch n.Type {
+		case "discord":
+			sink = discord.NewWebhook(n.Url)
+		case "slack":
+			sink = notify.NewSlack(n.Url)
+		case "http":
+			sink = notify.NewHttp(n.Url, n.Headers)
+		case "file":
+			sink = notify.NewFile(n.Path)
+		}
+
+		queue := notify.NewQueue(sink, notificationQueueSize)
+		queue.Name = n.Name
+		router.AddSink(n.Name, queue)
+	}
+
+	return router
+}
+
+// The interval proxy lists are health checked at when they don't set one
+const defaultHealthCheckInterval = 5 * time.Minute
+
+// The time a proxy has to respond to a health check
+const healthCheckTimeout = 15 * time.Second
+
+// StartHealthChecks Starts checking the proxy lists of the manager that have a health check URL in the background.
+// Call the returned function to stop.
+func (c *Config) StartHealthChecks(m *tasks.Manager) func() {
+	var stops []func()
+
+	for _, list := range c.ProxyLists {
+		pl := m.ProxyLists[list.Name]
+
+		if list.HealthCheckUrl == "" || pl == nil {
+			continue
+		}
+
+		interval := time.Duration(list.HealthCheckIntervalSeconds) * time.Second
+
+		if interval == 0 {
+			interval = defaultHealthCheckInterval
+		}
+
+		stops = append(stops, pl.StartHealthChecks(list.HealthCheckUrl, interval, healthCheckTimeout))
+	}
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// StartMonitorEvents Starts the monitors built by Build that send product events, which go to the notifiers of the
+// default routes. Call the returned function to stop.
+func (c *Config) StartMonitorEvents(m *tasks.Manager) func() {
+	var stops []func()
+
+	for _, monitor := range c.Monitors {
+		sm := c.monitors[monitor.Site]
+
+		if !monitor.Events || sm == nil {
+			continue
+		}
+
+		stops = append(stops, sm.Watch(func(e shopify.ProductEvent) {
+			m.Notifications.SendAndLog(nil, e.Notification())
+		}))
+	}
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// Returns a factory for the Shopify tasks that automations start. Tasks on a site with a monitor wait on it.
+func newShopifyTaskFactory(monitors map[string]*shopify.SiteMonitor) tasks.TaskFactory {
+	return func(site tasks.Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode tasks.TaskMode, inputs []string, sizes []string) *tasks.Task {
+		task := shopify.NewTaskShopify(site, profile, proxyList, mode, inputs, sizes)
+		task.Monitor = monitors[site.Name]
+		return &task.Task
+	}
+}
+
+// ApplyZephyr Sets the credentials used to connect to the Zephyr monitor
+func (c *Config) ApplyZephyr() {
+	automation.ZephyrMonitorKey = c.Zephyr.Key
+	automation.ZephyrMonitorUri = c.Zephyr.Uri
+}
+
+// ApplyLogging Replaces the default logger with one that logs at the configured level and format, to the log file if
+// the config has one and to any extra handlers. Returns a function that closes the log file.
+func (c *Config) ApplyLogging(extra ...logs.Handler) (func() error, error) {
+	level, err := logs.ParseLevel(c.Log.Level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	handlers := extra
+
+	switch strings.ToLower(c.Log.Format) {
+	case "json":
+		handlers = append(handlers, logs.NewJsonHandler(os.Stdout, level))
+	case "none":
+	default:
+		handlers = append(handlers, logs.NewConsoleHandler(os.Stdout, level))
+	}
+
+	closeFile := func() error { return nil }
+
+	if c.Log.File != "" {
+		maxSizeMb, maxBackups := c.Log.MaxSizeMb, c.Log.MaxBackups
+
+		if maxSizeMb == 0 {
+			maxSizeMb = 10
+		}
+
+		if maxBackups == 0 {
+			maxBackups = 5
+		}
+
+		file, err := logs.OpenRotatingFile(c.Log.File, int64(maxSizeMb)*1024*1024, maxBackups)
+
+		if err != nil {
+			return nil, err
+		}
+
+		handlers = append(handlers, logs.NewJsonHandler(file, level))
+		closeFile = file.Close
+	}
+
+	logs.SetDefault(logs.New(handlers...))
+	return closeFile, nil
+}
+
+// ApplyGateways Loads the Shopify payment gateway file into the gateway registry, if the config has one
+func (c *Config) ApplyGateways() error {
+	if c.GatewayFile == "" {
+		return nil
+	}
+
+	return shopify.Gateways.Load(c.GatewayFile)
+}
+
+// OpenHistory Opens the checkout history database, if the config has one. Returns nil if it doesn't.
+func (c *Config) OpenHistory() (*history.Store, error) {
+	if c.History == "" {
+		return nil, nil
+	}
+
+	return history.Open(c.History)
+}
@@ -0,0 +1,589 @@
+package config
+
+import (
+	"Mystery/api"
+	"Mystery/automation"
+	"Mystery/keywords"
+	"Mystery/logs"
+	"Mystery/notify"
+	"Mystery/profiles"
+	"Mystery/proxies"
+	"Mystery/sizes"
+	"Mystery/tasks"
+	"Mystery/tasks/shopify"
+	"Mystery/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+type Config struct {
+	Sites       []tasks.Website         `json:"sites"`
+	Profiles    []profiles.Profile      `json:"profiles"`
+	ProfileFile string                  `json:"profile_file"` // JSON file of profiles managed by a profiles.Store. Used alongside Profiles.
+	ProxyLists  []ProxyList             `json:"proxy_lists"`
+	Monitors    []Monitor               `json:"monitors"` // Sites whose tasks share one monitor instead of each polling the site.
+	TaskGroups  []TaskGroup             `json:"task_groups"`
+	Automations []automation.Automation `json:"automations"`
+	Zephyr      Zephyr                  `json:"zephyr"`
+	GatewayFile string                  `json:"gateway_file"` // JSON file of known Shopify payment gateways. Newly found gateways are saved to it.
+	Notifiers   []Notifier              `json:"notifiers"`
+	Notify      notify.Routes           `json:"notify"` // The notifiers each event is sent to when a task group or automation doesn't say otherwise.
+	Log         Log                     `json:"log"`
+	Api         Api                     `json:"api"`
+	Metrics     Metrics                 `json:"metrics"`
+	History     string                  `json:"history"` // Database file every checkout attempt is saved to. Relative paths start from the config's directory.
+
+	monitors map[string]*shopify.SiteMonitor // The monitors made by the last Build, by site name.
+}
+
+type Api struct {
+	Address string `json:"address"` // The loopback address the control API listens on, ex. "127.0.0.1:7777". Off if empty.
+	Token   string `json:"token"`   // Required by every request. NOTHING_API_TOKEN takes precedence.
+}
+
+type Metrics struct {
+	Address string `json:"address"` // The loopback address Prometheus metrics are served on at /metrics, ex. "127.0.0.1:9090". Off if empty.
+}
+
+type Log struct {
+	Level      string `json:"level"`       // "debug", "info", "warn" or "error". Defaults to info.
+	Format     string `json:"format"`      // The console format, "console", "json" or "none". Defaults to console.
+	File       string `json:"file"`        // Also writes JSON lines to this file, rotating it once it reaches MaxSizeMb.
+	MaxSizeMb  int    `json:"max_size_mb"` // Defaults to 10.
+	MaxBackups int    `json:"max_backups"` // The amount of rotated files kept. Defaults to 5.
+	TaskDir    string `json:"task_dir"`    // Every task also logs to <task_dir>/<task id>.log if this is set.
+}
+
+type Notifier struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`    // "discord", "slack", "http" or "file".
+	Url     string            `json:"url"`     // The webhook URL events are posted to. Used by every type except file.
+	Path    string            `json:"path"`    // The file events are appended to as JSON lines. Relative paths start from the config's directory.
+	Headers map[string]string `json:"headers"` // Headers sent with every event. Only used by the http type.
+}
+
+// ProfilePassphrase Decrypts the profile file if it's encrypted. Kept out of the config file so the passphrase is never
+// stored next to the profiles it protects.
+var ProfilePassphrase string
+
+type ProxyList struct {
+	Name                       string   `json:"name"`
+	File                       string   `json:"file"`                          // Path to a file with one proxy per line. Relative paths start from the config's directory.
+	Proxies                    []string `json:"proxies"`                       // Proxies listed directly in the config. Used alongside File if both are given.
+	Strategy                   string   `json:"strategy"`                      // "round_robin", "random", "lru" or "sticky". Defaults to round robin.
+	CooldownSeconds            int      `json:"cooldown_seconds"`              // How long a proxy isn't used for after a ban status or connection error. Defaults to 60.
+	HealthCheckUrl             string   `json:"health_check_url"`              // Checked through every proxy in the background if set. Failing proxies cool down.
+	HealthCheckIntervalSeconds int      `json:"health_check_interval_seconds"` // Defaults to 300.
+}
+
+type Monitor struct {
+	Site       string `json:"site"`        // The name of the site in Config.Sites. Every task on the site waits on this monitor.
+	IntervalMs int    `json:"interval_ms"` // How often the site is polled. Defaults to 3500.
+	ProxyList  string `json:"proxy_list"`  // The name of the proxy list in Config.ProxyLists the monitor polls through. Leave blank for localhost.
+	Events     bool   `json:"events"`      // Polls even while no task is waiting and sends new products, restocks, sell outs and price changes to the notifiers.
+}
+
+type TaskGroup struct {
+	Name   string              `json:"name"`
+	Tasks  []Task              `json:"tasks"`
+	Retry  tasks.RetryPolicies `json:"retry"`  // Retry policies for every task in the group. Task policies take precedence.
+	Notify notify.Routes       `json:"notify"` // The notifiers each checkout event is sent to. Events that aren't listed use the default routes.
+}
+
+type Task struct {
+	Site             string              `json:"site"`               // The name of the site in Config.Sites.
+	Profile          string              `json:"profile"`            // The name of the profile in Config.Profiles.
+	ProxyList        string              `json:"proxy_list"`         // The name of the proxy list in Config.ProxyLists. Leave blank for localhost.
+	Mode             string              `json:"mode"`               // "Safe" or "Fast". Defaults to safe.
+	MonitorInputs    []string            `json:"monitor_inputs"`     // List of monitor inputs whether it be keywords, links, or variants.
+	Sizes            []string            `json:"sizes"`              // The size range it will check out. Leave blank for random.
+	Quantity         int                 `json:"quantity"`           // The amount of the given product it will attempt to check out. Defaults to 1.
+	Count            int                 `json:"count"`              // The amount of identical tasks to create. Defaults to 1.
+	PaymentRetries   int                 `json:"payment_retries"`    // The amount of times payment is submitted again after a decline. The delay is set by the "decline" retry step.
+	RotateProxyAfter int                 `json:"rotate_proxy_after"` // The amount of proxy errors in a row after which the task switches proxy. Defaults to 3, -1 never switches.
+	VariantStrategy  string              `json:"variant_strategy"`   // "random", "preferred", "spread", "largest" or "smallest". Defaults to random.
+	VariantSeed      int64               `json:"variant_seed"`       // Makes random variant choices repeat in the same order. Leave at 0 to seed from the time.
+	Retry            tasks.RetryPolicies `json:"retry"`
+}
+
+type Zephyr struct {
+	Key string `json:"key"`
+	Uri string `json:"uri"`
+}
+
+// Load Reads, parses and validates a config file. Files ending in .yaml or .yml are parsed as YAML, everything else as JSON.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = parseYaml(data, &c)
+	default:
+		err = json.Unmarshal(data, &c)
+	}
+
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("parsing %v: %v", path, err))
+	}
+
+	if c.GatewayFile != "" && !filepath.IsAbs(c.GatewayFile) {
+		c.GatewayFile = filepath.Join(filepath.Dir(path), c.GatewayFile)
+	}
+
+	if c.ProfileFile != "" && !filepath.IsAbs(c.ProfileFile) {
+		c.ProfileFile = filepath.Join(filepath.Dir(path), c.ProfileFile)
+	}
+
+	if err := c.loadProfileFile(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range []*string{&c.Log.File, &c.Log.TaskDir, &c.History} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(filepath.Dir(path), *p)
+		}
+	}
+
+	for i := range c.Notifiers {
+		if n := &c.Notifiers[i]; n.Path != "" && !filepath.IsAbs(n.Path) {
+			n.Path = filepath.Join(filepath.Dir(path), n.Path)
+		}
+	}
+
+	if err := c.loadProxyFiles(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Validate Checks that every field is filled in and that every name referenced by a task or automation exists
+func (c *Config) Validate() error {
+	sites := map[string]struct{}{}
+
+	for i, site := range c.Sites {
+		if site.Name == "" {
+			return errors.New(fmt.Sprintf("site %v: name is required", i+1))
+		}
+
+		if _, ok := sites[site.Name]; ok {
+			return errors.New(fmt.Sprintf("site %v: duplicate name", site.Name))
+		}
+
+		u, err := url.Parse(site.Url)
+
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New(fmt.Sprintf("site %v: invalid url %q", site.Name, site.Url))
+		}
+
+		sites[site.Name] = struct{}{}
+	}
+
+	profileNames := map[string]struct{}{}
+
+	for i, profile := range c.Profiles {
+		if profile.Name == "" {
+			return errors.New(fmt.Sprintf("profile %v: name is required", i+1))
+		}
+
+		if _, ok := profileNames[profile.Name]; ok {
+			return errors.New(fmt.Sprintf("profile %v: duplicate name", profile.Name))
+		}
+
+		profileNames[profile.Name] = struct{}{}
+	}
+
+	proxyLists := map[string]struct{}{}
+
+	for i, list := range c.ProxyLists {
+		if list.Name == "" {
+			return errors.New(fmt.Sprintf("proxy list %v: name is required", i+1))
+		}
+
+		if _, ok := proxyLists[list.Name]; ok {
+			return errors.New(fmt.Sprintf("proxy list %v: duplicate name", list.Name))
+		}
+
+		if len(list.Proxies) == 0 {
+			return errors.New(fmt.Sprintf("proxy list %v: no proxies", list.Name))
+		}
+
+		for n, raw := range list.Proxies {
+			if _, err := proxies.ParseProxy(raw); err != nil {
+				return errors.New(fmt.Sprintf("proxy list %v: proxy %v: %v", list.Name, n+1, err))
+			}
+		}
+
+		if !proxies.IsValidStrategy(proxies.RotationStrategy(list.Strategy)) {
+			return errors.New(fmt.Sprintf("proxy list %v: unknown strategy %q", list.Name, list.Strategy))
+		}
+
+		if list.CooldownSeconds < 0 || list.HealthCheckIntervalSeconds < 0 {
+			return errors.New(fmt.Sprintf("proxy list %v: cooldown and health check interval cannot be negative", list.Name))
+		}
+
+		if list.HealthCheckUrl != "" {
+			if u, err := url.Parse(list.HealthCheckUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return errors.New(fmt.Sprintf("proxy list %v: invalid health check url %q", list.Name, list.HealthCheckUrl))
+			}
+		}
+
+		proxyLists[list.Name] = struct{}{}
+	}
+
+	monitors := map[string]struct{}{}
+
+	for i, monitor := range c.Monitors {
+		if _, ok := sites[monitor.Site]; !ok {
+			return errors.New(fmt.Sprintf("monitor %v: unknown site %q", i+1, monitor.Site))
+		}
+
+		if _, ok := monitors[monitor.Site]; ok {
+			return errors.New(fmt.Sprintf("monitor %v: site %v already has a monitor", i+1, monitor.Site))
+		}
+
+		if _, ok := proxyLists[monitor.ProxyList]; monitor.ProxyList != "" && !ok {
+			return errors.New(fmt.Sprintf("monitor %v: unknown proxy list %q", i+1, monitor.ProxyList))
+		}
+
+		if monitor.IntervalMs < 0 {
+			return errors.New(fmt.Sprintf("monitor %v: interval cannot be negative", i+1))
+		}
+
+		monitors[monitor.Site] = struct{}{}
+	}
+
+	if _, err := logs.ParseLevel(c.Log.Level); err != nil {
+		return errors.New(fmt.Sprintf("log: %v", err))
+	}
+
+	if f := strings.ToLower(c.Log.Format); f != "" && f != "console" && f != "json" && f != "none" {
+		return errors.New(fmt.Sprintf("log: unknown format %q", c.Log.Format))
+	}
+
+	if c.Log.MaxSizeMb < 0 || c.Log.MaxBackups < 0 {
+		return errors.New("log: max size and backups cannot be negative")
+	}
+
+	if c.Api.Address != "" && !api.IsLoopbackAddress(c.Api.Address) {
+		return errors.New(fmt.Sprintf("api: address %v must be a loopback address, ex. 127.0.0.1:7777", c.Api.Address))
+	}
+
+	if c.Metrics.Address != "" && !api.IsLoopbackAddress(c.Metrics.Address) {
+		return errors.New(fmt.Sprintf("metrics: address %v must be a loopback address, ex. 127.0.0.1:9090", c.Metrics.Address))
+	}
+
+	notifiers := map[string]struct{}{}
+
+	for i, n := range c.Notifiers {
+		if n.Name == "" {
+			return errors.New(fmt.Sprintf("notifier %v: name is required", i+1))
+		}
+
+		if _, ok := notifiers[n.Name]; ok {
+			return errors.New(fmt.Sprintf("notifier %v: duplicate name", n.Name))
+		}
+
+		switch n.Type {
+		case "discord", "slack", "http":
+			if u, err := url.Parse(n.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return errors.New(fmt.Sprintf("notifier %v: invalid url %q", n.Name, n.Url))
+			}
+		case "file":
+			if n.Path == "" {
+				return errors.New(fmt.Sprintf("notifier %v: path is required", n.Name))
+			}
+		default:
+			return errors.New(fmt.Sprintf("notifier %v: unknown type %q", n.Name, n.Type))
+		}
+
+		notifiers[n.Name] = struct{}{}
+	}
+
+	if err := validateRoutes(c.Notify, notifiers); err != nil {
+		return errors.New(fmt.Sprintf("notify: %v", err))
+	}
+
+	groups := map[string]struct{}{}
+
+	for i, group := range c.TaskGroups {
+		if group.Name == "" {
+			return errors.New(fmt.Sprintf("task group %v: name is required", i+1))
+		}
+
+		if _, ok := groups[group.Name]; ok {
+			return errors.New(fmt.Sprintf("task group %v: duplicate name", group.Name))
+		}
+
+		if len(group.Tasks) == 0 {
+			return errors.New(fmt.Sprintf("task group %v: no tasks", group.Name))
+		}
+
+		if err := group.Retry.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("task group %v: %v", group.Name, err))
+		}
+
+		if err := validateRoutes(group.Notify, notifiers); err != nil {
+			return errors.New(fmt.Sprintf("task group %v: notify: %v", group.Name, err))
+		}
+
+		for j, task := range group.Tasks {
+			prefix := fmt.Sprintf("task group %v: task %v", group.Name, j+1)
+
+			if _, ok := sites[task.Site]; !ok {
+				return errors.New(fmt.Sprintf("%v: unknown site %q", prefix, task.Site))
+			}
+
+			if _, ok := profileNames[task.Profile]; !ok {
+				return errors.New(fmt.Sprintf("%v: unknown profile %q", prefix, task.Profile))
+			}
+
+			if _, ok := proxyLists[task.ProxyList]; task.ProxyList != "" && !ok {
+				return errors.New(fmt.Sprintf("%v: unknown proxy list %q", prefix, task.ProxyList))
+			}
+
+			if task.Mode != "" {
+				if _, err := tasks.ModeFromString(task.Mode); err != nil {
+					return errors.New(fmt.Sprintf("%v: %v", prefix, err))
+				}
+			}
+
+			if len(task.MonitorInputs) == 0 {
+				return errors.New(fmt.Sprintf("%v: no monitor inputs", prefix))
+			}
+
+			if err := validateMonitorInputs(task.MonitorInputs); err != nil {
+				return errors.New(fmt.Sprintf("%v: %v", prefix, err))
+			}
+
+			if _, err := sizes.NewMatcher(task.Sizes); err != nil {
+				return errors.New(fmt.Sprintf("%v: %v", prefix, err))
+			}
+
+			if !sizes.IsValidStrategy(sizes.Strategy(task.VariantStrategy)) {
+				return errors.New(fmt.Sprintf("%v: unknown variant strategy %q", prefix, task.VariantStrategy))
+			}
+
+			if task.Quantity < 0 || task.Count < 0 || task.PaymentRetries < 0 {
+				return errors.New(fmt.Sprintf("%v: quantity, count and payment retries cannot be negative", prefix))
+			}
+
+			if task.RotateProxyAfter < -1 {
+				return errors.New(fmt.Sprintf("%v: rotate proxy after must be -1 or more", prefix))
+			}
+
+			if err := task.Retry.Validate(); err != nil {
+				return errors.New(fmt.Sprintf("%v: %v", prefix, err))
+			}
+		}
+
+		groups[group.Name] = struct{}{}
+	}
+
+	automations := map[string]struct{}{}
+
+	for i, auto := range c.Automations {
+		if auto.Name == "" {
+			return errors.New(fmt.Sprintf("automation %v: name is required", i+1))
+		}
+
+		if _, ok := automations[auto.Name]; ok {
+			return errors.New(fmt.Sprintf("automation %v: duplicate name", auto.Name))
+		}
+
+		if len(auto.MonitorInputs) == 0 {
+			return errors.New(fmt.Sprintf("automation %v: no monitor inputs", auto.Name))
+		}
+
+		if err := validateMonitorInputs(auto.MonitorInputs); err != nil {
+			return errors.New(fmt.Sprintf("automation %v: %v", auto.Name, err))
+		}
+
+		if _, err := sizes.NewMatcher(auto.Sizes); err != nil {
+			return errors.New(fmt.Sprintf("automation %v: %v", auto.Name, err))
+		}
+
+		if !sizes.IsValidStrategy(auto.VariantStrategy) {
+			return errors.New(fmt.Sprintf("automation %v: unknown variant strategy %q", auto.Name, auto.VariantStrategy))
+		}
+
+		if len(auto.Profiles) == 0 {
+			return errors.New(fmt.Sprintf("automation %v: no profiles", auto.Name))
+		}
+
+		for _, profile := range auto.Profiles {
+			if _, ok := profileNames[profile]; !ok {
+				return errors.New(fmt.Sprintf("automation %v: unknown profile %q", auto.Name, profile))
+			}
+		}
+
+		if _, ok := proxyLists[auto.ProxyList]; auto.ProxyList != "" && !ok {
+			return errors.New(fmt.Sprintf("automation %v: unknown proxy list %q", auto.Name, auto.ProxyList))
+		}
+
+		if auto.TotalTaskCount <= 0 {
+			return errors.New(fmt.Sprintf("automation %v: total task count must be greater than zero", auto.Name))
+		}
+
+		if auto.PaymentRetries < 0 || auto.PaymentRetryDelayMs < 0 {
+			return errors.New(fmt.Sprintf("automation %v: payment retries and delay cannot be negative", auto.Name))
+		}
+
+		if err := validateRoutes(auto.Notify, notifiers); err != nil {
+			return errors.New(fmt.Sprintf("automation %v: notify: %v", auto.Name, err))
+		}
+
+		automations[auto.Name] = struct{}{}
+	}
+
+	if len(c.Automations) > 0 && (c.Zephyr.Key == "" || c.Zephyr.Uri == "") {
+		return errors.New("zephyr key and uri are required when using automations")
+	}
+
+	return nil
+}
+
+// Makes sure every keyword input can be parsed
+func validateMonitorInputs(inputs []string) error {
+	for _, input := range inputs {
+		if utils.GetMonitorInputTypeFromString(input) != utils.MonitorInputTypeKeywords {
+			continue
+		}
+
+		if _, err := keywords.Parse(input); err != nil {
+			return errors.New(fmt.Sprintf("invalid keywords %q: %v", input, err))
+		}
+	}
+
+	return nil
+}
+
+// Checks that routes only use known event types and notifiers
+func validateRoutes(routes notify.Routes, notifiers map[string]struct{}) error {
+	if err := routes.Validate(); err != nil {
+		return err
+	}
+
+	for eventType, names := range routes {
+		for _, name := range names {
+			if _, ok := notifiers[name]; !ok {
+				return errors.New(fmt.Sprintf("%v: unknown notifier %q", eventType, name))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Loads and validates the profile file and appends its profiles to the config's
+func (c *Config) loadProfileFile() error {
+	if c.ProfileFile == "" {
+		return nil
+	}
+
+	store, err := profiles.LoadEncryptedStore(c.ProfileFile, ProfilePassphrase)
+
+	if err != nil {
+		return err
+	}
+
+	for _, p := range store.All() {
+		c.Profiles = append(c.Profiles, *p)
+	}
+
+	return nil
+}
+
+// Reads every proxy list file and appends its proxies to the list
+func (c *Config) loadProxyFiles(dir string) error {
+	for i := range c.ProxyLists {
+		list := &c.ProxyLists[i]
+
+		if list.File == "" {
+			continue
+		}
+
+		path := list.File
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		data, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return errors.New(fmt.Sprintf("proxy list %v: %v", list.Name, err))
+		}
+
+		for n, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+			if line = strings.TrimSpace(line); line == "" {
+				continue
+			}
+
+			if _, err := proxies.ParseProxy(line); err != nil {
+				return errors.New(fmt.Sprintf("proxy list %v: %v line %v: %v", list.Name, list.File, n+1, err))
+			}
+
+			list.Proxies = append(list.Proxies, line)
+		}
+	}
+
+	return nil
+}
+
+// Parses YAML by converting it to JSON first, so the json tags on the config structs are used for both formats.
+func parseYaml(data []byte, c *Config) error {
+	var raw interface{}
+
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(stringifyYamlKeys(raw))
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, c)
+}
+
+// YAML maps with non-string keys (ex. HTTP statuses) can't be converted to JSON, so every key is turned into a string
+func stringifyYamlKeys(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = stringifyYamlKeys(item)
+		}
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+
+		for k, item := range value {
+			m[fmt.Sprintf("%v", k)] = stringifyYamlKeys(item)
+		}
+
+		return m
+	case []interface{}:
+		for i, item := range value {
+			value[i] = stringifyYamlKeys(item)
+		}
+	}
+
+	return v
+}
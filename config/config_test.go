@@ -0,0 +1,407 @@
+package config
+
+import (
+	"Mystery/profiles"
+	"Mystery/proxies"
+	"Mystery/sizes"
+	"Mystery/tasks"
+	"Mystery/tasks/shopify"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfigJson = `{
+	"sites": [{"name": "Kith", "url": "https://kith.com"}],
+	"profiles": [{
+		"name": "Test US",
+		"shipping_address": {"name": "Johnny Appleseeds", "email": "jappleseed124@gmail.com", "line1": "542 6th Ave", "city": "New York", "state": "New York", "post_code": "10011", "country": "United States"},
+		"credit_card": {"number": "5555555555555555", "cvv": "123", "expiry_month": 4, "expiry_year": 2028},
+		"same_billing_address_as_shipping": true
+	}],
+	"proxy_lists": [{"name": "Live", "file": "proxies.txt", "proxies": ["10.0.0.1:8080"], "strategy": "sticky", "cooldown_seconds": 30}],
+	"monitors": [{"site": "Kith", "interval_ms": 1000, "proxy_list": "Live"}],
+	"task_groups": [{
+		"name": "Dunks",
+		"tasks": [
+			{"site": "Kith", "profile": "Test US", "proxy_list": "Live", "mode": "Fast", "monitor_inputs": ["+dunk"], "sizes": ["9"], "count": 3, "rotate_proxy_after": 5, "variant_strategy": "spread", "variant_seed": 7},
+			{"site": "Kith", "profile": "Test US", "monitor_inputs": ["+dunk"], "quantity": 2}
+		]
+	}]
+}`
+
+const testConfigYaml = `
+sites:
+  - name: Kith
+    url: https://kith.com
+profiles:
+  - name: Test US
+    credit_card:
+      number: "5555555555555555"
+      expiry_month: 4
+      expiry_year: 2028
+task_groups:
+  - name: Dunks
+    tasks:
+      - site: Kith
+        profile: Test US
+        mode: safe
+        monitor_inputs: ["+dunk"]
+`
+
+// Writes files into a temporary directory and returns the path of the first one
+func writeTestFiles(t *testing.T, files map[string]string, main string) string {
+	dir := t.TempDir()
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return filepath.Join(dir, main)
+}
+
+// Tests loading and building a JSON config with a proxy list file
+func TestLoadJson(t *testing.T) {
+	path := writeTestFiles(t, map[string]string{
+		"config.json": testConfigJson,
+		"proxies.txt": "127.0.0.1:8080:username:password\r\n\r\n192.68.1.1:1234\r\nsocks5h://user:pa:ss@[::1]:1080\r\n",
+	}, "config.json")
+
+	c, err := Load(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.ProxyLists[0].Proxies) != 4 {
+		t.Fatalf("expected 4 proxies. got %v", len(c.ProxyLists[0].Proxies))
+	}
+
+	m := c.Build()
+
+	if len(m.TaskGroups) != 1 {
+		t.Fatalf("expected 1 task group. got %v", len(m.TaskGroups))
+	}
+
+	if len(m.Tasks) != 4 {
+		t.Fatalf("expected 4 tasks. got %v", len(m.Tasks))
+	}
+
+	for task := range m.Tasks {
+		if task.Runner == nil {
+			t.Fatalf("task %v has no runner", task.Id)
+		}
+
+		if task.Profile == nil || task.Profile.Name != "Test US" {
+			t.Fatalf("task %v has the wrong profile", task.Id)
+		}
+
+		if task.ProxyList != nil && task.ProxyList.Name != "Live" {
+			t.Fatalf("task %v has the wrong proxy list", task.Id)
+		}
+
+		if task.ProxyList != nil && task.RotateProxyAfter != 5 {
+			t.Fatalf("expected tasks to switch proxy after 5 errors. got %v", task.RotateProxyAfter)
+		}
+
+		if task.ProxyList != nil && (task.VariantStrategy != sizes.StrategySpread || task.VariantSeed != 7) {
+			t.Fatalf("expected tasks to spread across sizes with seed 7. got %v and %v", task.VariantStrategy, task.VariantSeed)
+		}
+
+		if runner, ok := task.Runner.(*shopify.Task); !ok || runner.Monitor == nil || runner.Monitor.Interval != time.Second {
+			t.Fatalf("expected task %v to wait on the site's monitor", task.Id)
+		}
+
+		if task.ProxyList == nil && task.Quantity != 2 {
+			t.Fatalf("expected a quantity of 2. got %v", task.Quantity)
+		}
+	}
+
+	if pl := m.ProxyLists["Live"]; pl == nil || pl.Strategy != proxies.StrategySticky || pl.Cooldown != 30*time.Second {
+		t.Fatalf("expected the proxy list's strategy and cooldown to be set. got %+v", pl)
+	}
+
+	if m.NewTask == nil || m.Profiles["Test US"] == nil || m.ProxyLists["Live"] == nil {
+		t.Fatal("expected automations to be able to create tasks with the config's profiles and proxy lists")
+	}
+
+	if task := m.NewTask(tasks.Website{}, nil, nil, tasks.ModeShopifySafe, []string{"1"}, []string{}); task.Runner == nil {
+		t.Fatal("expected automation tasks to have a runner")
+	}
+}
+
+// Tests that an invalid line in a proxy list file is reported with its line number
+func TestLoadInvalidProxyFile(t *testing.T) {
+	path := writeTestFiles(t, map[string]string{
+		"config.json": testConfigJson,
+		"proxies.txt": "127.0.0.1:8080\n\n127.0.0.1:8080:user\n",
+	}, "config.json")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "proxies.txt line 3") {
+		t.Fatalf("expected an error on line 3. got %v", err)
+	}
+}
+
+// Tests loading and building a YAML config
+func TestLoadYaml(t *testing.T) {
+	path := writeTestFiles(t, map[string]string{"config.yml": testConfigYaml}, "config.yml")
+	c, err := Load(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Profiles[0].CreditCard.ExpiryYear != 2028 {
+		t.Fatalf("incorrect expiry year - %v", c.Profiles[0].CreditCard.ExpiryYear)
+	}
+
+	if m := c.Build(); len(m.Tasks) != 1 {
+		t.Fatalf("expected 1 task. got %v", len(m.Tasks))
+	}
+}
+
+// Tests that profiles from the profile file can be used by tasks
+func TestLoadProfileFile(t *testing.T) {
+	profileJson := `[{
+		"name": "Stored",
+		"shipping_address": {"name": "Johnny Appleseeds", "email": "jappleseed124@gmail.com", "phone": "1234567891", "line1": "542 6th Ave", "city": "New York", "post_code": "10011", "country": "United States"},
+		"credit_card": {"number": "4111111111111111", "cvv": "123", "expiry_month": 4, "expiry_year": 2099},
+		"same_billing_address_as_shipping": true
+	}]`
+
+	content := "profile_file: profiles.json\n" + strings.Replace(testConfigYaml, "profile: Test US", "profile: Stored", 1)
+	path := writeTestFiles(t, map[string]string{"config.yaml": content, "profiles.json": profileJson}, "config.yaml")
+	c, err := Load(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Profiles) != 2 || c.Profiles[1].Name != "Stored" {
+		t.Fatalf("expected the stored profile to be added. got %v profiles", len(c.Profiles))
+	}
+
+	for task := range c.Build().Tasks {
+		if task.Profile == nil || task.Profile.Name != "Stored" {
+			t.Fatalf("expected the task to use the stored profile")
+		}
+	}
+
+	path = writeTestFiles(t, map[string]string{"config.yaml": content, "profiles.json": strings.Replace(profileJson, "4111111111111111", "4111111111111112", 1)}, "config.yaml")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid card number") {
+		t.Fatalf("expected the invalid stored profile to be rejected. got %v", err)
+	}
+}
+
+// Tests loading an encrypted profile file with the profile passphrase
+func TestLoadEncryptedProfileFile(t *testing.T) {
+	path := writeTestFiles(t, map[string]string{"config.yaml": "profile_file: profiles.json\n" + testConfigYaml}, "config.yaml")
+	store := profiles.NewStore()
+
+	if err := store.SetPassphrase("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SaveAs(filepath.Join(filepath.Dir(path), "profiles.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	passphrase := ProfilePassphrase
+
+	t.Cleanup(func() {
+		ProfilePassphrase = passphrase
+	})
+
+	ProfilePassphrase = ""
+
+	if _, err := Load(path); !errors.Is(err, profiles.ErrPassphraseRequired) {
+		t.Fatalf("expected ErrPassphraseRequired. got %v", err)
+	}
+
+	ProfilePassphrase = "hunter2"
+
+	if _, err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that references to things that don't exist are rejected
+func TestValidateUnknownReferences(t *testing.T) {
+	tests := map[string]string{
+		"unknown site":           strings.Replace(testConfigYaml, "site: Kith", "site: Nope", 1),
+		"unknown profile":        strings.Replace(testConfigYaml, "profile: Test US", "profile: Nope", 1),
+		"unknown proxy list":     strings.Replace(testConfigYaml, "mode: safe", "proxy_list: Nope", 1),
+		"unknown task mode":      strings.Replace(testConfigYaml, "mode: safe", "mode: turbo", 1),
+		"no monitor inputs":      strings.Replace(testConfigYaml, `monitor_inputs: ["+dunk"]`, "monitor_inputs: []", 1),
+		"invalid keywords":       strings.Replace(testConfigYaml, `monitor_inputs: ["+dunk"]`, `monitor_inputs: ["+dunk,"]`, 1),
+		"invalid size range":     testConfigYaml + "        sizes: [\"10-8\"]\n",
+		"unknown strategy":       testConfigYaml + "        variant_strategy: middle\n",
+		"invalid url":            strings.Replace(testConfigYaml, "url: https://kith.com", "url: kith.com", 1),
+		"unknown log level":      testConfigYaml + "log:\n  level: loud\n",
+		"unknown log format":     testConfigYaml + "log:\n  format: xml\n",
+		"public api address":     testConfigYaml + "api:\n  address: 0.0.0.0:7777\n",
+		"public metrics address": testConfigYaml + "metrics:\n  address: :9090\n",
+		"unknown proxy strategy": testConfigYaml + "proxy_lists:\n  - name: Live\n    proxies: [\"10.0.0.1:8080\"]\n    strategy: fastest\n",
+		"negative cooldown":      testConfigYaml + "proxy_lists:\n  - name: Live\n    proxies: [\"10.0.0.1:8080\"]\n    cooldown_seconds: -1\n",
+		"unknown monitor site":   testConfigYaml + "monitors:\n  - site: Nope\n",
+		"invalid proxy":          testConfigYaml + "proxy_lists:\n  - name: Live\n    proxies: [\"ftp://10.0.0.1:8080\"]\n",
+		"invalid health check":   testConfigYaml + "proxy_lists:\n  - name: Live\n    proxies: [\"10.0.0.1:8080\"]\n    health_check_url: example.com\n",
+	}
+
+	for name, content := range tests {
+		path := writeTestFiles(t, map[string]string{"config.yaml": content}, "config.yaml")
+
+		if _, err := Load(path); err == nil {
+			t.Fatalf("%v: expected an error", name)
+		}
+	}
+}
+
+// Tests that automations require zephyr credentials and known profiles
+func TestValidateAutomations(t *testing.T) {
+	auto := testConfigYaml + `
+automations:
+  - name: Auto
+    monitor_inputs: ["+dunk"]
+    profiles: ["Test US"]
+    total_task_count: 5
+`
+
+	path := writeTestFiles(t, map[string]string{"config.yaml": auto}, "config.yaml")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "zephyr") {
+		t.Fatalf("expected a zephyr error. got %v", err)
+	}
+
+	path = writeTestFiles(t, map[string]string{"config.yaml": auto + "zephyr:\n  key: abc\n  uri: wss://localhost\n"}, "config.yaml")
+
+	if _, err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	path = writeTestFiles(t, map[string]string{"config.yaml": strings.Replace(auto, `profiles: ["Test US"]`, `profiles: ["Nope"]`, 1) + "zephyr:\n  key: abc\n  uri: wss://localhost\n"}, "config.yaml")
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an unknown profile error")
+	}
+}
+
+// Tests that retry policies from YAML are applied to groups and tasks
+func TestLoadRetryPolicies(t *testing.T) {
+	content := strings.Replace(testConfigYaml, "  - name: Dunks\n", `  - name: Dunks
+    retry:
+      default:
+        delay_ms: 1000
+        max_attempts: 5
+      steps:
+        cart:
+          delay_ms: 500
+          backoff: 2
+          status_overrides:
+            429:
+              delay_ms: 10000
+`, 1)
+
+	content = strings.Replace(content, "        mode: safe\n", `        mode: safe
+        retry:
+          steps:
+            payment:
+              max_attempts: 2
+`, 1)
+
+	path := writeTestFiles(t, map[string]string{"config.yaml": content}, "config.yaml")
+	c, err := Load(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := c.Build()
+
+	for task := range m.Tasks {
+		if p := task.GetRetryPolicy(tasks.RetryStepCart).ForStatus(429); p.DelayMs != 10000 {
+			t.Fatalf("expected the 429 override. got %+v", p)
+		}
+
+		if p := task.GetRetryPolicy(tasks.RetryStepContact); p.DelayMs != 1000 || p.MaxAttempts != 5 {
+			t.Fatalf("expected the group's default policy. got %+v", p)
+		}
+
+		if p := task.GetRetryPolicy(tasks.RetryStepPayment); p.MaxAttempts != 2 {
+			t.Fatalf("expected the task's payment policy. got %+v", p)
+		}
+	}
+
+	path = writeTestFiles(t, map[string]string{"config.yaml": strings.Replace(content, "payment:", "paying:", 1)}, "config.yaml")
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an unknown retry step error")
+	}
+}
+
+// Tests that notifiers are built into a router with the default and group routes
+func TestLoadNotifiers(t *testing.T) {
+	notifiers := `
+notifiers:
+  - name: discord
+    type: discord
+    url: https://discord.com/api/webhooks/1/abc
+  - name: log
+    type: file
+    path: events.jsonl
+notify:
+  checkout_success: [discord, log]
+`
+
+	content := strings.Replace(testConfigYaml, "  - name: Dunks\n", "  - name: Dunks\n    notify:\n      checkout_decline: [log]\n", 1) + notifiers
+	path := writeTestFiles(t, map[string]string{"config.yaml": content}, "config.yaml")
+	c, err := Load(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Notifiers[1].Path != filepath.Join(filepath.Dir(path), "events.jsonl") {
+		t.Fatalf("expected the file path to be relative to the config. got %v", c.Notifiers[1].Path)
+	}
+
+	m := c.Build()
+
+	if m.Notifications == nil || len(m.Notifications.Defaults["checkout_success"]) != 2 {
+		t.Fatalf("expected the default routes to be set")
+	}
+
+	for _, name := range []string{"discord", "log"} {
+		if _, ok := m.Notifications.Sink(name); !ok {
+			t.Fatalf("expected a %v sink", name)
+		}
+	}
+
+	for group := range m.TaskGroups {
+		if len(group.Notify["checkout_decline"]) != 1 {
+			t.Fatalf("expected the group routes to be set. got %v", group.Notify)
+		}
+	}
+
+	tests := map[string]string{
+		"unknown notifier":   strings.Replace(content, "[discord, log]", "[discord, slack]", 1),
+		"unknown event type": strings.Replace(content, "checkout_success:", "checkout_maybe:", 1),
+		"unknown type":       strings.Replace(content, "type: discord", "type: telegram", 1),
+		"missing url":        strings.Replace(content, "url: https://discord.com/api/webhooks/1/abc", "", 1),
+		"duplicate name":     strings.Replace(content, "name: log", "name: discord", 1),
+	}
+
+	for name, content := range tests {
+		path := writeTestFiles(t, map[string]string{"config.yaml": content}, "config.yaml")
+
+		if _, err := Load(path); err == nil {
+			t.Fatalf("%v: expected an error", name)
+		}
+	}
+}
@@ -1,32 +1,116 @@
 package discord
 
 import (
+	"Mystery/notify"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/disgoorg/disgo/discord"
-	"github.com/disgoorg/disgo/webhook"
-	"github.com/disgoorg/snowflake/v2"
+	"github.com/go-resty/resty/v2"
+	"net/http"
 	"time"
 )
 
-type CheckoutWebhookData struct {
-	Success       bool
-	FailureReason string
-	Site          string
-	Mode          string
-	ProductTitle  string
-	ProductSize   string
-	ProductImage  string
-	Profile       string
-	ProxyList     string
-	Email         string
-	OrderNumber   string
-	OrderLink     string
-	Quantity      string
+// Webhook Sends events to a Discord channel as embeds through a webhook URL,
+// ex. https://discord.com/api/webhooks/<id>/<token>
+type Webhook struct {
+	Url    string
+	Client *resty.Client
 }
 
-// SendCheckoutWebhook Sends a success/failure checkout webhook to Discord
-func SendCheckoutWebhook(data CheckoutWebhookData) {
-	client := webhook.New(snowflake.ID(0), "")
+// NewWebhook Creates a sink that sends events to a Discord webhook URL
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		Url:    url,
+		Client: resty.New().SetTimeout(15 * time.Second),
+	}
+}
+
+// MaxEmbeds The most embeds Discord allows in one message
+const MaxEmbeds = 10
+
+// Notify Sends the event as an embed
+func (w *Webhook) Notify(e notify.Event) error {
+	return w.Send([]discord.Embed{Embed(e)})
+}
+
+// NotifyBatch Sends the events as one message with an embed per event
+func (w *Webhook) NotifyBatch(events []notify.Event) error {
+	embeds := make([]discord.Embed, len(events))
+
+	for i, e := range events {
+		embeds[i] = Embed(e)
+	}
+
+	return w.Send(embeds)
+}
+
+// MaxBatchSize Returns how many events fit in one message
+func (w *Webhook) MaxBatchSize() int {
+	return MaxEmbeds
+}
+
+// Send Sends a message with the given embeds. Returns a notify.RateLimitError if Discord rate limited the webhook.
+func (w *Webhook) Send(embeds []discord.Embed) error {
+	resp, err := w.Client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(discord.WebhookMessageCreate{Embeds: embeds}).
+		Post(w.Url)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return &notify.RateLimitError{RetryAfter: retryAfter(resp)}
+	}
+
+	if !resp.IsSuccess() {
+		return errors.New(fmt.Sprintf("unexpected status %v: %v", resp.StatusCode(), resp.String()))
+	}
+
+	return nil
+}
+
+// Reads how long to wait from a rate limited response. Discord puts it in the body as seconds, ex.
+// {"message": "You are being rate limited.", "retry_after": 0.5, "global": false}
+func retryAfter(resp *resty.Response) time.Duration {
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+
+	if err := json.Unmarshal(resp.Body(), &body); err == nil && body.RetryAfter > 0 {
+		return time.Duration(body.RetryAfter * float64(time.Second))
+	}
+
+	return notify.ParseRetryAfter(resp.Header().Get("Retry-After"))
+}
+
+// Embed Creates the embed an event is shown as
+func Embed(e notify.Event) discord.Embed {
+	if e.Checkout != nil {
+		return checkoutEmbed(e)
+	}
+
+	if e.Automation != nil {
+		return automationEmbed(e)
+	}
+
+	embed := discord.NewEmbedBuilder()
+	embed.SetAuthor(e.Title(), "", "https://i.imgur.com/RLauXcd.png")
+
+	for _, field := range e.Fields() {
+		embed.AddField(field.Name, field.Value, field.Inline)
+	}
+
+	embed.SetFooter("Nothing", "https://i.imgur.com/RLauXcd.png")
+	embed.SetTimestamp(e.Time)
+	return embed.Build()
+}
+
+// Creates a success/failure checkout embed
+func checkoutEmbed(e notify.Event) discord.Embed {
+	data := e.Checkout
 	embed := discord.NewEmbedBuilder()
 
 	if data.Success {
@@ -46,7 +130,7 @@ func SendCheckoutWebhook(data CheckoutWebhookData) {
 	embed.AddField("Mode", data.Mode, true)
 	embed.AddField("Product", data.ProductTitle, true)
 	embed.AddField("Size", data.ProductSize, true)
-	embed.AddField("Quantity", data.Quantity, true)
+	embed.AddField("Quantity", fmt.Sprintf("%v", data.Quantity), true)
 	embed.AddField("Profile", fmt.Sprintf("||%v||", data.Profile), true)
 	embed.AddField("Email", fmt.Sprintf("||%v||", data.Email), true)
 	embed.AddField("Proxy List", fmt.Sprintf("||%v||", data.ProxyList), true)
@@ -59,13 +143,42 @@ func SendCheckoutWebhook(data CheckoutWebhookData) {
 		embed.AddField("Order Number", "None", true)
 	}
 
+	if e.Group != "" {
+		embed.AddField("Group", e.Group, true)
+	}
+
 	embed.SetFooter("Nothing", "https://i.imgur.com/RLauXcd.png")
-	embed.SetTimestamp(time.Now())
+	embed.SetTimestamp(e.Time)
+	return embed.Build()
+}
 
-	_, err := client.CreateEmbeds([]discord.Embed{embed.Build()})
+// Creates an embed to signal that automation for a product has started/stopped
+func automationEmbed(e notify.Event) discord.Embed {
+	data := e.Automation
+	embed := discord.NewEmbedBuilder()
 
-	if err != nil {
-		fmt.Println(err)
-		return
+	if e.Type == notify.EventAutomationStarted {
+		embed.SetAuthor("\U0001F7E2 Automation Started", "", "https://i.imgur.com/RLauXcd.png")
+		embed.SetColor(0x00FF00)
+	} else {
+		embed.SetAuthor("🔴 Automation Stopped", "", "https://i.imgur.com/RLauXcd.png")
+		embed.SetColor(0xFF0000)
+	}
+
+	if data.ProductImage != "" {
+		embed.SetThumbnail(data.ProductImage)
 	}
+
+	embed.AddField("Automation Name", data.Name, false)
+	embed.AddField("Site", data.Site, false)
+	embed.AddField("Product", fmt.Sprintf("[%v](%v)", data.ProductTitle, data.ProductUrl), false)
+
+	// The remaining fields are the same ones every sink shows
+	for _, field := range e.Fields()[3:] {
+		embed.AddField(field.Name, field.Value, field.Inline)
+	}
+
+	embed.SetFooter("Nothing", "https://i.imgur.com/RLauXcd.png")
+	embed.SetTimestamp(e.Time)
+	return embed.Build()
 }
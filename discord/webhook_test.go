@@ -1,45 +1,183 @@
 package discord
 
 import (
+	"Mystery/notify"
+	"encoding/json"
+	"github.com/disgoorg/disgo/discord"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
-func TestSuccessWebhook(t *testing.T) {
-	for i := 0; i < 1; i++ {
-		data := CheckoutWebhookData{
-			Success:       true,
-			FailureReason: "",
-			Site:          "Sneaker Politics",
-			Mode:          "Fast (Auto)",
-			ProductTitle:  "Nike Dunk Low - Test",
-			ProductSize:   "12",
-			ProductImage:  "https://cdn.shopify.com/s/files/1/0214/7974/products/DD8338-001-PHSLH000-2000.jpg",
-			Profile:       "Test Profile",
-			ProxyList:     "Test Proxies",
-			Email:         "test@gmail.com",
-			OrderNumber:   "#123456",
-			OrderLink:     "https://sneakerpolitics.com",
+// Starts a server that records every webhook message it receives
+func newTestWebhookServer(t *testing.T, status int) (*httptest.Server, *[]discord.WebhookMessageCreate) {
+	var messages []discord.WebhookMessageCreate
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discord.WebhookMessageCreate
+
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
 		}
 
-		SendCheckoutWebhook(data)
+		messages = append(messages, message)
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(server.Close)
+	return server, &messages
+}
+
+// Returns the value of an embed field
+func getField(embed discord.Embed, name string) string {
+	for _, field := range embed.Fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+
+	return ""
+}
+
+func TestSuccessWebhook(t *testing.T) {
+	server, messages := newTestWebhookServer(t, http.StatusNoContent)
+
+	e := notify.NewCheckoutEvent(notify.EventCheckoutSuccess, notify.CheckoutEvent{
+		Success:      true,
+		Site:         "Sneaker Politics",
+		Mode:         "Fast (Auto)",
+		ProductTitle: "Nike Dunk Low - Test",
+		ProductSize:  "12",
+		ProductImage: "https://cdn.shopify.com/s/files/1/0214/7974/products/DD8338-001-PHSLH000-2000.jpg",
+		Profile:      "Test Profile",
+		ProxyList:    "Test Proxies",
+		Email:        "test@gmail.com",
+		OrderNumber:  "#123456",
+		OrderLink:    "https://sneakerpolitics.com",
+		Quantity:     1,
+	})
+
+	if err := NewWebhook(server.URL).Notify(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*messages) != 1 || len((*messages)[0].Embeds) != 1 {
+		t.Fatalf("expected 1 message with 1 embed. got %+v", *messages)
+	}
+
+	embed := (*messages)[0].Embeds[0]
+
+	if embed.Author == nil || embed.Author.Name != "Successful Checkout! ✅" {
+		t.Fatalf("unexpected author %+v", embed.Author)
+	}
+
+	if order := getField(embed, "Order Number"); order != "||[#123456](https://sneakerpolitics.com)||" {
+		t.Fatalf("unexpected order number %q", order)
+	}
+
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != e.Checkout.ProductImage {
+		t.Fatalf("expected the product image as the thumbnail. got %+v", embed.Thumbnail)
 	}
 }
 
 func TestFailureWebhook(t *testing.T) {
-	data := CheckoutWebhookData{
-		Success:       false,
+	server, messages := newTestWebhookServer(t, http.StatusNoContent)
+
+	e := notify.NewCheckoutEvent(notify.EventCheckoutDecline, notify.CheckoutEvent{
 		FailureReason: "Card was declined",
 		Site:          "Kith",
 		Mode:          "Fast",
 		ProductTitle:  "Nike Dunk High Retro Bttys Noble Green / White",
 		ProductSize:   "5.5",
-		ProductImage:  "https://cdn.shopify.com/s/files/1/0094/2252/products/DD1399-300-PHSRH000-2000_1080x.jpg?v=1659621681",
 		Profile:       "Test US",
 		ProxyList:     "Live",
 		Email:         "test@gmail.com",
-		OrderNumber:   "12345",
-		OrderLink:     "https://kith.com",
+	})
+
+	e.Group = "Dunks"
+
+	if err := NewWebhook(server.URL).Notify(e); err != nil {
+		t.Fatal(err)
+	}
+
+	embed := (*messages)[0].Embeds[0]
+
+	if embed.Description != "**Reason:** Card was declined" || embed.Color != 0xFF0000 {
+		t.Fatalf("unexpected failure embed %q (%x)", embed.Description, embed.Color)
 	}
 
-	SendCheckoutWebhook(data)
+	if getField(embed, "Order Number") != "None" || getField(embed, "Group") != "Dunks" {
+		t.Fatalf("unexpected fields %+v", embed.Fields)
+	}
+}
+
+// Tests the automation started embed
+func TestAutomationWebhook(t *testing.T) {
+	server, messages := newTestWebhookServer(t, http.StatusNoContent)
+
+	e := notify.NewAutomationEvent(true, notify.AutomationEvent{
+		Name:         "Dunks",
+		Site:         "https://kith.com/",
+		ProductTitle: "Nike Dunk Low",
+		ProductUrl:   "https://kith.com/products/nike-dunk-low",
+		Sizes:        []string{"9", "10"},
+		TaskCount:    10,
+	})
+
+	if err := NewWebhook(server.URL).Notify(e); err != nil {
+		t.Fatal(err)
+	}
+
+	embed := (*messages)[0].Embeds[0]
+
+	if embed.Author == nil || embed.Author.Name != "\U0001F7E2 Automation Started" {
+		t.Fatalf("unexpected author %+v", embed.Author)
+	}
+
+	if product := getField(embed, "Product"); product != "[Nike Dunk Low](https://kith.com/products/nike-dunk-low)" {
+		t.Fatalf("unexpected product %q", product)
+	}
+
+	if getField(embed, "Sizes") != "9, 10" || getField(embed, "Task Count") != "10" {
+		t.Fatalf("unexpected fields %+v", embed.Fields)
+	}
+}
+
+// Tests that a webhook that isn't accepted returns an error
+func TestWebhookError(t *testing.T) {
+	server, _ := newTestWebhookServer(t, http.StatusNotFound)
+
+	if err := NewWebhook(server.URL).Notify(notify.NewAutomationEvent(false, notify.AutomationEvent{})); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// Tests that events are batched into one message and that rate limits return how long to wait
+func TestWebhookBatchAndRateLimit(t *testing.T) {
+	server, messages := newTestWebhookServer(t, http.StatusNoContent)
+	events := []notify.Event{notify.NewAutomationEvent(true, notify.AutomationEvent{}), notify.NewAutomationEvent(false, notify.AutomationEvent{})}
+
+	if err := NewWebhook(server.URL).NotifyBatch(events); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*messages) != 1 || len((*messages)[0].Embeds) != 2 {
+		t.Fatalf("expected 1 message with 2 embeds. got %+v", *messages)
+	}
+
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 1.5, "global": false}`))
+	}))
+
+	defer limited.Close()
+
+	err := NewWebhook(limited.URL).NotifyBatch(events)
+	rateLimit, ok := err.(*notify.RateLimitError)
+
+	if !ok || rateLimit.RetryAfter != 1500*time.Millisecond {
+		t.Fatalf("expected a 1.5s rate limit. got %v", err)
+	}
 }
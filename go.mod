@@ -5,16 +5,19 @@ go 1.17
 require (
 	github.com/PuerkitoBio/goquery v1.8.0
 	github.com/disgoorg/disgo v0.13.20
-	github.com/disgoorg/log v1.2.0
-	github.com/disgoorg/snowflake/v2 v2.0.0
 	github.com/go-resty/resty/v2 v2.7.0
 	github.com/sacOO7/gowebsocket v0.0.0-20221109081133-70ac927be105
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
+	golang.org/x/net v0.0.0-20211112202133-69e39bad7dc2
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/andybalholm/cascadia v1.3.1 // indirect
+	github.com/disgoorg/snowflake/v2 v2.0.0 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/sacOO7/go-logger v0.0.0-20180719173527-9ac9add5a50d // indirect
-	github.com/sasha-s/go-csync v0.0.0-20210812194225-61421b77c44b // indirect
-	golang.org/x/net v0.0.0-20211029224645-99673261e6eb // indirect
+	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
 )
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CsvHeader The columns records are exported with
+var CsvHeader = []string{
+	"id", "time", "task_id", "group", "site", "mode", "product", "variant_id", "size", "price", "profile", "proxy_list",
+	"result", "order_number", "failure_reason", "step_timings",
+}
+
+// WriteCsv Writes the records as CSV with a header row. Prices are in dollars and step timings are written as
+// "step=seconds" pairs separated by semicolons, ex. "Monitoring=12.40;Adding To Cart=0.81".
+func WriteCsv(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(CsvHeader); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		timings := make([]string, len(r.StepTimings))
+
+		for i, timing := range r.StepTimings {
+			timings[i] = fmt.Sprintf("%v=%.2f", timing.Step, timing.Duration.Seconds())
+		}
+
+		err := writer.Write([]string{
+			strconv.FormatUint(r.Id, 10),
+			r.Time.UTC().Format(time.RFC3339),
+			r.TaskId,
+			r.Group,
+			r.Site,
+			r.Mode,
+			r.Product,
+			strconv.FormatInt(r.VariantId, 10),
+			r.Size,
+			FormatPrice(r.Price),
+			r.Profile,
+			r.ProxyList,
+			string(r.Result),
+			r.OrderNumber,
+			r.FailureReason,
+			strings.Join(timings, ";"),
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// FormatPrice Formats a price in cents as dollars, ex. 11000 as "110.00"
+func FormatPrice(cents int64) string {
+	sign := ""
+
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%v%d.%02d", sign, cents/100, cents%100)
+}
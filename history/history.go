@@ -0,0 +1,179 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"os"
+	"time"
+)
+
+type Result string
+
+const (
+	ResultSuccess Result = "success"
+	ResultDecline Result = "decline" // Payment was declined by the card's bank.
+	ResultFailure Result = "failure" // Payment failed for any other reason, ex. the product went out of stock.
+)
+
+// StepTiming The total time a task spent on a checkout step
+type StepTiming struct {
+	Step     string        `json:"step"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Record A single checkout attempt. Every payment submission that succeeds or fails is one attempt, so a task that is
+// declined twice and then checks out has three records.
+type Record struct {
+	Id            uint64       `json:"id"`
+	Time          time.Time    `json:"time"`
+	TaskId        string       `json:"task_id"`
+	Group         string       `json:"group,omitempty"`
+	Site          string       `json:"site"`
+	Mode          string       `json:"mode"`
+	Product       string       `json:"product"`
+	VariantId     int64        `json:"variant_id"`
+	Size          string       `json:"size"`
+	Price         int64        `json:"price"` // The total charged in cents, including shipping and taxes when they're known.
+	Profile       string       `json:"profile"`
+	ProxyList     string       `json:"proxy_list"`
+	Result        Result       `json:"result"`
+	OrderNumber   string       `json:"order_number,omitempty"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+	StepTimings   []StepTiming `json:"step_timings"` // The time spent on each step since the task started, in the order they were first reached.
+}
+
+// Filter Limits the records a query returns. Zero values match everything.
+type Filter struct {
+	Since time.Time
+	Site  string
+}
+
+// Matches Returns if the record passes the filter
+func (f Filter) Matches(r Record) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+
+	return f.Site == "" || r.Site == f.Site
+}
+
+// Store Keeps checkout records in a bbolt database file. Only one process can open the file at a time.
+type Store struct {
+	db *bbolt.DB
+}
+
+var recordBucket = []byte("checkouts")
+
+// The time Open waits for another process to close the file
+const openTimeout = time.Second
+
+// Open Opens the database file, creating it if it doesn't exist
+func Open(path string) (*Store, error) {
+	return open(path, false)
+}
+
+// OpenReadOnly Opens an existing database file without the ability to add records
+func OpenReadOnly(path string) (*Store, error) {
+	// bbolt would try to create a missing file and fail with an unhelpful error
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	return open(path, true)
+}
+
+func open(path string, readOnly bool) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: openTimeout, ReadOnly: readOnly})
+
+	if err == bbolt.ErrTimeout {
+		return nil, errors.New(fmt.Sprintf("%v is in use by another process", path))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !readOnly {
+		err = db.Update(func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(recordBucket)
+			return err
+		})
+
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &Store{db}, nil
+}
+
+// Close Closes the database file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add Saves a record, setting its ID. Records without a time are given the current time.
+func (s *Store) Add(r *Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(recordBucket)
+		id, err := bucket.NextSequence()
+
+		if err != nil {
+			return err
+		}
+
+		r.Id = id
+		data, err := json.Marshal(r)
+
+		if err != nil {
+			return err
+		}
+
+		// Big endian keys keep the records in the order they were added
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, id)
+		return bucket.Put(key, data)
+	})
+}
+
+// Records Returns every record that passes the filter, oldest first
+func (s *Store) Records(f Filter) ([]Record, error) {
+	records := []Record{}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(recordBucket)
+
+		// A read only store of a database that has never had a record added
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var r Record
+
+			if err := json.Unmarshal(v, &r); err != nil {
+				return errors.New(fmt.Sprintf("record %v: %v", binary.BigEndian.Uint64(k), err))
+			}
+
+			if f.Matches(r) {
+				records = append(records, r)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,139 @@
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testRecords = []Record{
+	{Site: "Kith", Mode: "Safe", Profile: "Main", Result: ResultSuccess, Price: 11000},
+	{Site: "Kith", Mode: "Safe", Profile: "Main", Result: ResultDecline, FailureReason: "Card declined"},
+	{Site: "Kith", Mode: "Fast", Profile: "Backup", Result: ResultDecline, FailureReason: "Insufficient funds"},
+	{Site: "Kith", Mode: "Fast", Profile: "Backup", Result: ResultDecline, FailureReason: "Card declined"},
+	{Site: "Bodega", Mode: "Safe", Profile: "Backup", Result: ResultSuccess, Price: 22050},
+	{Site: "Bodega", Mode: "Safe", Profile: "Main", Result: ResultFailure, FailureReason: "Out of stock"},
+}
+
+// Tests that records are saved with IDs and read back in order, and that the filter and read only stores work
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path)
+
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+
+	old := Record{Time: time.Now().Add(-48 * time.Hour), Site: "Kith", Result: ResultSuccess}
+
+	for _, r := range append([]Record{old}, testRecords...) {
+		r.StepTimings = []StepTiming{{"Monitoring", 2 * time.Second}, {"Payment Method", 300 * time.Millisecond}}
+
+		if err := store.Add(&r); err != nil {
+			t.Fatalf("failed to add record: %v", err)
+		}
+	}
+
+	if _, err := OpenReadOnly(path); err == nil {
+		t.Fatalf("expected the file to be in use")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+
+	store, err = OpenReadOnly(path)
+
+	if err != nil {
+		t.Fatalf("failed to open store read only: %v", err)
+	}
+
+	defer store.Close()
+	records, err := store.Records(Filter{})
+
+	if err != nil {
+		t.Fatalf("failed to read records: %v", err)
+	}
+
+	if len(records) != len(testRecords)+1 {
+		t.Fatalf("expected %v records. got %v", len(testRecords)+1, len(records))
+	}
+
+	for i, r := range records {
+		if r.Id != uint64(i+1) || r.Time.IsZero() || len(r.StepTimings) != 2 || r.StepTimings[1].Duration != 300*time.Millisecond {
+			t.Fatalf("unexpected record %v: %+v", i, r)
+		}
+	}
+
+	records, _ = store.Records(Filter{Since: time.Now().Add(-24 * time.Hour), Site: "Kith"})
+
+	if len(records) != 4 {
+		t.Fatalf("expected 4 recent Kith records. got %v", len(records))
+	}
+}
+
+// Tests the success rate, spend and decline queries
+func TestStats(t *testing.T) {
+	rates := SuccessRates(testRecords)
+	expectedRates := []SuccessRate{
+		{Site: "Bodega", Mode: "Safe", Attempts: 2, Successes: 1},
+		{Site: "Kith", Mode: "Fast", Attempts: 2, Declines: 2},
+		{Site: "Kith", Mode: "Safe", Attempts: 2, Successes: 1, Declines: 1},
+	}
+
+	if !reflect.DeepEqual(rates, expectedRates) {
+		t.Fatalf("expected rates %+v. got %+v", expectedRates, rates)
+	}
+
+	if rates[0].Rate() != 0.5 || rates[1].Rate() != 0 || (SuccessRate{}).Rate() != 0 {
+		t.Fatalf("unexpected success rates")
+	}
+
+	spend := SpendByProfile(testRecords)
+	expectedSpend := []Spend{{"Backup", 1, 22050}, {"Main", 1, 11000}}
+
+	if !reflect.DeepEqual(spend, expectedSpend) {
+		t.Fatalf("expected spend %+v. got %+v", expectedSpend, spend)
+	}
+
+	declines := DeclinesByReason(testRecords)
+	expectedDeclines := []DeclineCount{{"Card declined", 2}, {"Insufficient funds", 1}}
+
+	if !reflect.DeepEqual(declines, expectedDeclines) {
+		t.Fatalf("expected declines %+v. got %+v", expectedDeclines, declines)
+	}
+}
+
+// Tests that records are exported with a header, prices in dollars and step timings in seconds
+func TestWriteCsv(t *testing.T) {
+	var b bytes.Buffer
+	record := testRecords[4]
+	record.Id = 5
+	record.Time = time.Date(2022, 3, 1, 15, 0, 0, 0, time.UTC)
+	record.VariantId = 39000000001
+	record.StepTimings = []StepTiming{{"Monitoring", 12400 * time.Millisecond}, {"Adding To Cart", 810 * time.Millisecond}}
+
+	if err := WriteCsv(&b, []Record{record}); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	rows, err := csv.NewReader(&b).ReadAll()
+
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+
+	expected := []string{"5", "2022-03-01T15:00:00Z", "", "", "Bodega", "Safe", "", "39000000001", "", "220.50", "Backup", "",
+		"success", "", "", "Monitoring=12.40;Adding To Cart=0.81"}
+
+	if len(rows) != 2 || !reflect.DeepEqual(rows[0], CsvHeader) || !reflect.DeepEqual(rows[1], expected) {
+		t.Fatalf("unexpected CSV %q", rows)
+	}
+
+	if FormatPrice(5) != "0.05" || FormatPrice(-1999) != "-19.99" {
+		t.Fatalf("unexpected prices %v and %v", FormatPrice(5), FormatPrice(-1999))
+	}
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"sort"
+)
+
+// SuccessRate The checkout attempts and successes of a site and mode
+type SuccessRate struct {
+	Site      string
+	Mode      string
+	Attempts  int
+	Successes int
+	Declines  int
+}
+
+// Rate Returns the fraction of attempts that succeeded, from 0 to 1
+func (r SuccessRate) Rate() float64 {
+	if r.Attempts == 0 {
+		return 0
+	}
+
+	return float64(r.Successes) / float64(r.Attempts)
+}
+
+// Spend The orders a profile has placed and what they cost
+type Spend struct {
+	Profile string
+	Orders  int
+	Total   int64 // In cents.
+}
+
+// DeclineCount The amount of declines with the same reason
+type DeclineCount struct {
+	Reason string
+	Count  int
+}
+
+// SuccessRates Returns the success rate of every site and mode, sorted by site then mode
+func SuccessRates(records []Record) []SuccessRate {
+	type key struct{ site, mode string }
+	rates := map[key]*SuccessRate{}
+
+	for _, r := range records {
+		k := key{r.Site, r.Mode}
+
+		if rates[k] == nil {
+			rates[k] = &SuccessRate{Site: r.Site, Mode: r.Mode}
+		}
+
+		rates[k].Attempts++
+
+		switch r.Result {
+		case ResultSuccess:
+			rates[k].Successes++
+		case ResultDecline:
+			rates[k].Declines++
+		}
+	}
+
+	sorted := make([]SuccessRate, 0, len(rates))
+
+	for _, rate := range rates {
+		sorted = append(sorted, *rate)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Site != sorted[j].Site {
+			return sorted[i].Site < sorted[j].Site
+		}
+
+		return sorted[i].Mode < sorted[j].Mode
+	})
+
+	return sorted
+}
+
+// SpendByProfile Returns what every profile has spent on successful checkouts, most spent first
+func SpendByProfile(records []Record) []Spend {
+	spends := map[string]*Spend{}
+
+	for _, r := range records {
+		if r.Result != ResultSuccess {
+			continue
+		}
+
+		if spends[r.Profile] == nil {
+			spends[r.Profile] = &Spend{Profile: r.Profile}
+		}
+
+		spends[r.Profile].Orders++
+		spends[r.Profile].Total += r.Price
+	}
+
+	sorted := make([]Spend, 0, len(spends))
+
+	for _, s := range spends {
+		sorted = append(sorted, *s)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Total != sorted[j].Total {
+			return sorted[i].Total > sorted[j].Total
+		}
+
+		return sorted[i].Profile < sorted[j].Profile
+	})
+
+	return sorted
+}
+
+// DeclinesByReason Returns how often each decline reason was given, most common first
+func DeclinesByReason(records []Record) []DeclineCount {
+	counts := map[string]int{}
+
+	for _, r := range records {
+		if r.Result == ResultDecline {
+			counts[r.FailureReason]++
+		}
+	}
+
+	sorted := make([]DeclineCount, 0, len(counts))
+
+	for reason, count := range counts {
+		sorted = append(sorted, DeclineCount{reason, count})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+
+		return sorted[i].Reason < sorted[j].Reason
+	})
+
+	return sorted
+}
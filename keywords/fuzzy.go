@@ -0,0 +1,118 @@
+package keywords
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Matches text containing the words in order with a few typos in each
+type fuzzyMatcher struct {
+	words []string // Lowercase.
+}
+
+func newFuzzyMatcher(text string) *fuzzyMatcher {
+	return &fuzzyMatcher{words: splitWords(text)}
+}
+
+func (m *fuzzyMatcher) match(value string) bool {
+	words := splitWords(value)
+
+	for start := 0; start+len(m.words) <= len(words); start++ {
+		matched := true
+
+		for i, word := range m.words {
+			if !isTypo(word, words[start+i]) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Splits lowercase text into words of letters and numbers
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Returns if the word is the keyword with at most one typo, or two for keywords of 8 or more characters.
+// Keywords shorter than 4 characters have to match exactly since one typo would already change them too much.
+func isTypo(keyword string, word string) bool {
+	allowed := 0
+	length := len([]rune(keyword))
+
+	if length >= 8 {
+		allowed = 2
+	} else if length >= 4 {
+		allowed = 1
+	}
+
+	return editDistance(keyword, word, allowed) <= allowed
+}
+
+// Returns the amount of inserted, deleted, replaced or swapped characters between two strings, or a number above max
+// once it's known to be above max
+func editDistance(a string, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	d := make([][]int, len(ra)+1)
+
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		rowMin := d[i][0]
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			// Two neighbouring characters that were swapped, ex. "dnuk" for "dunk"
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+
+			rowMin = minInt(rowMin, d[i][j])
+		}
+
+		if rowMin > max {
+			return max + 1
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
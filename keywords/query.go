@@ -0,0 +1,317 @@
+package keywords
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Field string
+
+const (
+	FieldTitle  Field = "title"
+	FieldHandle Field = "handle"
+	FieldSku    Field = "sku"
+	FieldVendor Field = "vendor"
+	FieldTag    Field = "tag"
+)
+
+// Fields Every field a term can be limited to with a prefix, ex. vendor:nike
+var Fields = []Field{FieldTitle, FieldHandle, FieldSku, FieldVendor, FieldTag}
+
+// Document The values of a product's fields. Fields like SKUs and tags can have many values.
+type Document map[Field][]string
+
+// Query A parsed set of keywords. Terms are separated by commas and every term has to match for the query to match.
+//
+//	+dunk             the text has to contain "dunk". The + is optional.
+//	-womens           the text can't contain "womens"
+//	+"dunk low"       quoted phrases can hold spaces, commas and pipes
+//	+dunk|jordan      either one of the alternatives has to match
+//	+/dd1391-1\d\d/   a case-insensitive regular expression
+//	+~bttys           a word within a typo or two, ex. "bttys" matches "BTTYs" and "btys"
+//	+vendor:nike      the term is matched against a field of the product instead of the text
+type Query struct {
+	Raw   string
+	terms []term
+}
+
+type term struct {
+	excluded     bool
+	field        Field // Empty to match against the text.
+	alternatives []matcher
+}
+
+type matcher interface {
+	match(value string) bool
+}
+
+// Matches text containing the keyword or phrase
+type containsMatcher struct {
+	text string // Lowercase.
+}
+
+type regexMatcher struct {
+	pattern *regexp.Regexp
+}
+
+// Parse Parses a set of keywords. Returns an error describing the first term that can't be parsed.
+func Parse(s string) (*Query, error) {
+	p := &parser{input: s}
+	q := &Query{Raw: s}
+
+	for n := 1; ; n++ {
+		t, err := p.parseTerm()
+
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("term %v: %v", n, err))
+		}
+
+		q.terms = append(q.terms, t)
+
+		if p.done() {
+			return q, nil
+		}
+
+		// The term stopped at a comma
+		p.pos++
+	}
+}
+
+// Match Returns if the text and document match every term. Terms without a field are matched against the text and an
+// empty text matches nothing, while terms with a field are matched against that field of the document.
+func (q *Query) Match(text string, doc Document) bool {
+	for _, t := range q.terms {
+		values := doc[t.field]
+
+		if t.field == "" {
+			if text == "" {
+				return false
+			}
+
+			values = []string{text}
+		}
+
+		if t.matches(values) == t.excluded {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns if any of the term's alternatives match any of the values
+func (t *term) matches(values []string) bool {
+	for _, value := range values {
+		for _, m := range t.alternatives {
+			if m.match(value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (m *containsMatcher) match(value string) bool {
+	return strings.Contains(strings.ToLower(value), m.text)
+}
+
+func (m *regexMatcher) match(value string) bool {
+	return m.pattern.MatchString(value)
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+
+	return p.input[p.pos]
+}
+
+func (p *parser) skipSpaces() {
+	for !p.done() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// Parses a term up to the next comma or the end of the input
+func (p *parser) parseTerm() (term, error) {
+	var t term
+	p.skipSpaces()
+
+	if p.done() || p.peek() == ',' {
+		return term{}, errors.New("empty term")
+	}
+
+	switch p.peek() {
+	case '+':
+		p.pos++
+	case '-':
+		t.excluded = true
+		p.pos++
+	}
+
+	p.skipSpaces()
+	t.field = p.parseField()
+
+	for {
+		m, err := p.parseAlternative()
+
+		if err != nil {
+			return term{}, err
+		}
+
+		t.alternatives = append(t.alternatives, m)
+
+		if p.peek() != '|' {
+			return t, nil
+		}
+
+		p.pos++
+	}
+}
+
+// Reads a field prefix if the term starts with one. Text that only looks like a prefix, ex. "size:10", is left alone.
+func (p *parser) parseField() Field {
+	rest := strings.ToLower(p.input[p.pos:])
+
+	for _, field := range Fields {
+		if strings.HasPrefix(rest, string(field)+":") {
+			p.pos += len(field) + 1
+			p.skipSpaces()
+			return field
+		}
+	}
+
+	return ""
+}
+
+// Parses one alternative up to the next pipe, comma or the end of the input
+func (p *parser) parseAlternative() (matcher, error) {
+	p.skipSpaces()
+	fuzzy := false
+
+	if p.peek() == '~' {
+		fuzzy = true
+		p.pos++
+	}
+
+	var text string
+	var err error
+
+	switch p.peek() {
+	case '"':
+		text, err = p.parseQuoted()
+	case '/':
+		if fuzzy {
+			return nil, errors.New("a regular expression can't be fuzzy")
+		}
+
+		return p.parseRegex()
+	default:
+		text = strings.TrimSpace(p.readUntil(",|"))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return nil, errors.New("empty keyword")
+	}
+
+	if fuzzy {
+		// Fuzzy keywords match word by word, so a keyword without words would match everything
+		if len(splitWords(text)) == 0 {
+			return nil, errors.New("empty keyword")
+		}
+
+		return newFuzzyMatcher(text), nil
+	}
+
+	return &containsMatcher{text: strings.ToLower(text)}, nil
+}
+
+// Reads until one of the characters or the end of the input
+func (p *parser) readUntil(chars string) string {
+	start := p.pos
+
+	for !p.done() && !strings.ContainsRune(chars, rune(p.peek())) {
+		p.pos++
+	}
+
+	return p.input[start:p.pos]
+}
+
+// Reads a phrase in double quotes
+func (p *parser) parseQuoted() (string, error) {
+	p.pos++
+	text := p.readUntil(`"`)
+
+	if p.done() {
+		return "", errors.New("missing closing quote")
+	}
+
+	p.pos++
+	return text, p.expectEnd()
+}
+
+// Reads a regular expression between slashes. A slash inside the expression is escaped as \/.
+func (p *parser) parseRegex() (matcher, error) {
+	p.pos++
+	var b strings.Builder
+
+	for {
+		if p.done() {
+			return nil, errors.New("missing closing slash")
+		}
+
+		c := p.peek()
+		p.pos++
+
+		if c == '/' {
+			break
+		}
+
+		if c == '\\' && p.peek() == '/' {
+			c = '/'
+			p.pos++
+		}
+
+		b.WriteByte(c)
+	}
+
+	if b.Len() == 0 {
+		return nil, errors.New("empty regular expression")
+	}
+
+	pattern, err := regexp.Compile("(?i)" + b.String())
+
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid regular expression: %v", err))
+	}
+
+	return &regexMatcher{pattern: pattern}, p.expectEnd()
+}
+
+// Makes sure a quoted phrase or regular expression is followed by a pipe, comma or the end of the input
+func (p *parser) expectEnd() error {
+	p.skipSpaces()
+
+	if !p.done() && p.peek() != ',' && p.peek() != '|' {
+		return errors.New(fmt.Sprintf("unexpected %q after the closing delimiter", p.readUntil(",|")))
+	}
+
+	return nil
+}
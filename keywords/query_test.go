@@ -0,0 +1,120 @@
+package keywords
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests matching keyword sets against a title and the fields of a product
+func TestQueryMatch(t *testing.T) {
+	title := "Nike Dunk Low Retro BTTYS - Black, White"
+	doc := Document{
+		FieldTitle:  {title},
+		FieldHandle: {"nike-dunk-low-retro-dd1391-100"},
+		FieldVendor: {"Nike"},
+		FieldSku:    {"DD1391-100-9", "DD1391-100-10"},
+		FieldTag:    {"sneakers", "mens"},
+	}
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{"+dunk,+low", true},
+		{"+DUNK , + low", true},
+		{"dunk,low", true},
+		{"+dunk,-womens", true},
+		{"+dunk,-bttys", false},
+		{"+high", false},
+		{`+"dunk low"`, true},
+		{`+"low dunk"`, false},
+		{`+"black, white"`, true},
+		{"+jordan|dunk", true},
+		{"+jordan|high", false},
+		{`+"air max"|dunk,+retro`, true},
+		{"-high|bttys", false},
+		{`+/dunk\s+(low|high)/`, true},
+		{`+/^dunk/`, false},
+		{`+/black\/white|black, white/`, true},
+		{"+~bttys", true},
+		{"+~btys", true},
+		{"+~dnuk", true},
+		{"+~bottys", true},
+		{"+~retro low", false},
+		{`+~"dnuk low"`, true},
+		{`+~"dunk lwo"`, false},
+		{"+~xyz", false},
+		{"+vendor:nike", true},
+		{"+vendor:adidas", false},
+		{"-vendor:adidas,+dunk", true},
+		{"+sku:dd1391-100-10", true},
+		{"+tag:sneakers|boots", true},
+		{"-tag:mens", false},
+		{"+handle:/dd1391-\\d{3}/", true},
+		{"+title:~bttys", true},
+		{"+size:10", false},
+	}
+
+	for _, test := range tests {
+		q, err := Parse(test.query)
+
+		if err != nil {
+			t.Fatalf("%v: %v", test.query, err)
+		}
+
+		if q.Match(title, doc) != test.expected {
+			t.Fatalf("%v: expected a match to be %v", test.query, test.expected)
+		}
+	}
+}
+
+// Tests that an empty text only matches keyword sets that are limited to fields
+func TestQueryMatchEmptyText(t *testing.T) {
+	doc := Document{FieldVendor: {"Nike"}}
+
+	for query, expected := range map[string]bool{"-dunk": false, "+vendor:nike": true} {
+		q, err := Parse(query)
+
+		if err != nil {
+			t.Fatalf("%v: %v", query, err)
+		}
+
+		if q.Match("", doc) != expected {
+			t.Fatalf("%v: expected a match to be %v", query, expected)
+		}
+	}
+}
+
+// Tests that invalid keyword sets return an error naming the term
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                 "term 1: empty term",
+		"+dunk,":           "term 2: empty term",
+		"+dunk,,+low":      "term 2: empty term",
+		"+":                "term 1: empty keyword",
+		"+dunk|":           "term 1: empty keyword",
+		`+dunk,+"low`:      "term 2: missing closing quote",
+		`+"dunk" low`:      `term 1: unexpected "low" after the closing delimiter`,
+		"+/dunk":           "term 1: missing closing slash",
+		"+//":              "term 1: empty regular expression",
+		"+/dunk(/":         "term 1: invalid regular expression",
+		"+~/dunk/":         "term 1: a regular expression can't be fuzzy",
+		"+dunk,-vendor:":   "term 2: empty keyword",
+		`+title:"dunk low`: "term 1: missing closing quote",
+		"+jordan|/[a-/,+1": "term 1: invalid regular expression",
+		"+~!!":             "term 1: empty keyword",
+		`-~"--"`:           "term 1: empty keyword",
+	}
+
+	for query, expected := range tests {
+		q, err := Parse(query)
+
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Fatalf("%q: expected an error starting with %q. got %v", query, expected, err)
+		}
+
+		if q != nil {
+			t.Fatalf("%q: expected no query with the error", query)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RotatingFile A log file that is moved aside once it reaches a maximum size. Old files are kept as
+// <path>.1 (the newest) to <path>.<MaxBackups> (the oldest).
+type RotatingFile struct {
+	Path       string
+	MaxBytes   int64 // The size a file is rotated at. Files are never rotated if this is 0.
+	MaxBackups int   // The amount of rotated files kept. Older ones are deleted.
+	mutex      *sync.Mutex
+	file       *os.File
+	size       int64
+}
+
+// OpenRotatingFile Opens a log file for appending, creating it and its directory if needed
+func OpenRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		Path:       path,
+		MaxBytes:   maxBytes,
+		MaxBackups: maxBackups,
+		mutex:      &sync.Mutex{},
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write Appends to the file, rotating it first if the write would take it past its maximum size
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.MaxBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxBytes {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close Closes the file. Writes after closing fail.
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Shifts every backup up by one, moves the current file to <path>.1 and starts a new one
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	f.file = nil
+
+	if f.MaxBackups <= 0 {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return f.open()
+	}
+
+	os.Remove(backupPath(f.Path, f.MaxBackups))
+
+	for i := f.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(f.Path, i), backupPath(f.Path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(f.Path, backupPath(f.Path, 1)); err != nil {
+		f.open()
+		return err
+	}
+
+	return f.open()
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%v.%v", path, i)
+}
@@ -0,0 +1,124 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsoleHandler Writes human readable lines, ex.
+// 15:04:05.000 INFO  Item added to cart task=abc123 group=Dunks site=Kith step=Cart
+type ConsoleHandler struct {
+	Level Level
+	w     io.Writer
+	mutex *sync.Mutex
+}
+
+// JsonHandler Writes every line as a JSON object with "time", "level" and "msg" keys followed by its fields
+type JsonHandler struct {
+	Level Level
+	w     io.Writer
+	mutex *sync.Mutex
+}
+
+// NewConsoleHandler Creates a handler that writes lines at or above the level in a human readable format
+func NewConsoleHandler(w io.Writer, level Level) *ConsoleHandler {
+	return &ConsoleHandler{Level: level, w: w, mutex: &sync.Mutex{}}
+}
+
+// NewJsonHandler Creates a handler that writes lines at or above the level as JSON
+func NewJsonHandler(w io.Writer, level Level) *JsonHandler {
+	return &JsonHandler{Level: level, w: w, mutex: &sync.Mutex{}}
+}
+
+// Enabled Returns if lines at the level are written
+func (h *ConsoleHandler) Enabled(level Level) bool {
+	return level >= h.Level
+}
+
+// Handle Writes the record as a line
+func (h *ConsoleHandler) Handle(r Record) error {
+	var b strings.Builder
+
+	b.WriteString(r.Time.Format("15:04:05.000"))
+	b.WriteString(fmt.Sprintf(" %-5v ", strings.ToUpper(r.Level.String())))
+	b.WriteString(r.Message)
+
+	for _, f := range r.Fields {
+		value := fmt.Sprint(fieldValue(f.Value))
+
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+
+		b.WriteString(fmt.Sprintf(" %v=%v", f.Key, value))
+	}
+
+	b.WriteByte('\n')
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// Enabled Returns if lines at the level are written
+func (h *JsonHandler) Enabled(level Level) bool {
+	return level >= h.Level
+}
+
+// Handle Writes the record as a line of JSON
+func (h *JsonHandler) Handle(r Record) error {
+	var b bytes.Buffer
+
+	b.WriteString(`{"time":`)
+	writeJson(&b, r.Time.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJson(&b, r.Level.String())
+	b.WriteString(`,"msg":`)
+	writeJson(&b, r.Message)
+
+	// Written field by field instead of through a map to keep the order the fields were added in
+	for _, f := range r.Fields {
+		b.WriteByte(',')
+		writeJson(&b, f.Key)
+		b.WriteByte(':')
+		writeJson(&b, fieldValue(f.Value))
+	}
+
+	b.WriteString("}\n")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	_, err := h.w.Write(b.Bytes())
+	return err
+}
+
+// Errors marshal to {} and stringers are usually meant to be shown as strings, so both are logged as their text
+func fieldValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case error:
+		return value.Error()
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return v
+	}
+}
+
+// Writes a value as JSON, falling back to its formatted string if it can't be marshalled
+func writeJson(b *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	b.Write(data)
+}
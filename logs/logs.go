@@ -0,0 +1,214 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Field A key and value attached to a log line, ex. task=abc123
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Record A single log line
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+// Handler Writes log records somewhere, ex. the console or a file
+type Handler interface {
+	Enabled(level Level) bool
+	Handle(r Record) error
+}
+
+// Logger Sends log lines to its handlers with the fields it was created with. Loggers are safe to use from multiple
+// goroutines and cheap to derive from each other with With.
+type Logger struct {
+	handlers []Handler
+	fields   []Field
+}
+
+var (
+	defaultMutex  = &sync.RWMutex{}
+	defaultLogger = New(NewConsoleHandler(os.Stdout, LevelInfo))
+)
+
+// New Creates a logger that writes to every handler
+func New(handlers ...Handler) *Logger {
+	return &Logger{handlers: handlers}
+}
+
+// Default Returns the logger used by the package level functions
+func Default() *Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefault Replaces the logger used by the package level functions
+func SetDefault(l *Logger) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	defaultLogger = l
+}
+
+// String Returns the lower case name of the level, ex. "info"
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel Returns the level from its name (ex. "debug" / "warn"). An empty name is info.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, errors.New(fmt.Sprintf("unknown log level: %v", s))
+	}
+}
+
+// With Returns a logger that adds the given key/value pairs to every line, ex. With("task", id, "site", name)
+func (l *Logger) With(args ...interface{}) *Logger {
+	fields := make([]Field, len(l.fields), len(l.fields)+len(args)/2)
+	copy(fields, l.fields)
+
+	return &Logger{
+		handlers: l.handlers,
+		fields:   append(fields, toFields(args)...),
+	}
+}
+
+// WithHandler Returns a logger that also writes to the given handler
+func (l *Logger) WithHandler(h Handler) *Logger {
+	handlers := make([]Handler, len(l.handlers), len(l.handlers)+1)
+	copy(handlers, l.handlers)
+
+	return &Logger{
+		handlers: append(handlers, h),
+		fields:   l.fields,
+	}
+}
+
+// Log Writes a line at the given level. args are key/value pairs added to the line after the logger's own fields.
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	var fields []Field
+
+	for _, h := range l.handlers {
+		if !h.Enabled(level) {
+			continue
+		}
+
+		if fields == nil {
+			fields = append(append([]Field{}, l.fields...), toFields(args)...)
+		}
+
+		if err := h.Handle(Record{Time: time.Now(), Level: level, Message: msg, Fields: fields}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write log: %v\n", err)
+		}
+	}
+}
+
+// Debug Writes a debug line
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.Log(LevelDebug, msg, args...)
+}
+
+// Info Writes an info line
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.Log(LevelInfo, msg, args...)
+}
+
+// Warn Writes a warning line
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Log(LevelWarn, msg, args...)
+}
+
+// Error Writes an error line
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.Log(LevelError, msg, args...)
+}
+
+// With Returns the default logger with the given key/value pairs
+func With(args ...interface{}) *Logger {
+	return Default().With(args...)
+}
+
+// Debug Writes a debug line to the default logger
+func Debug(msg string, args ...interface{}) {
+	Default().Log(LevelDebug, msg, args...)
+}
+
+// Info Writes an info line to the default logger
+func Info(msg string, args ...interface{}) {
+	Default().Log(LevelInfo, msg, args...)
+}
+
+// Warn Writes a warning line to the default logger
+func Warn(msg string, args ...interface{}) {
+	Default().Log(LevelWarn, msg, args...)
+}
+
+// Error Writes an error line to the default logger
+func Error(msg string, args ...interface{}) {
+	Default().Log(LevelError, msg, args...)
+}
+
+// Pairs up key/value arguments. A key without a value is kept with a nil value, and keys that aren't strings are
+// formatted, so a mistake never loses what was logged.
+func toFields(args []interface{}) []Field {
+	fields := make([]Field, 0, (len(args)+1)/2)
+
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+
+		if !ok {
+			key = fmt.Sprint(args[i])
+		}
+
+		var value interface{}
+
+		if i+1 < len(args) {
+			value = args[i+1]
+		}
+
+		fields = append(fields, Field{key, value})
+	}
+
+	return fields
+}
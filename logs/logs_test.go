@@ -0,0 +1,112 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests that fields from With and the call are written in order by both handlers
+func TestHandlers(t *testing.T) {
+	var console, js bytes.Buffer
+	logger := New(NewConsoleHandler(&console, LevelInfo), NewJsonHandler(&js, LevelDebug)).With("task", "abc123", "group", "Dunks")
+
+	logger.Debug("Hidden from the console")
+	logger.Error("Checkout failed", "step", "Payment", "error", errors.New("card declined"))
+
+	lines := strings.Split(strings.TrimSpace(console.String()), "\n")
+
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], `ERROR Checkout failed task=abc123 group=Dunks step=Payment error="card declined"`) {
+		t.Fatalf("unexpected console output %q", console.String())
+	}
+
+	lines = strings.Split(strings.TrimSpace(js.String()), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines. got %v", len(lines))
+	}
+
+	var line map[string]interface{}
+
+	if err := json.Unmarshal([]byte(lines[1]), &line); err != nil {
+		t.Fatal(err)
+	}
+
+	if line["level"] != "error" || line["msg"] != "Checkout failed" || line["task"] != "abc123" || line["error"] != "card declined" {
+		t.Fatalf("unexpected JSON line %v", line)
+	}
+
+	if !strings.HasPrefix(lines[1], `{"time":`) || strings.Index(lines[1], `"task"`) > strings.Index(lines[1], `"step"`) {
+		t.Fatalf("expected the fields in the order they were added. got %v", lines[1])
+	}
+}
+
+// Tests that deriving a logger doesn't change the one it was derived from
+func TestWith(t *testing.T) {
+	var a, b bytes.Buffer
+	base := New(NewConsoleHandler(&a, LevelInfo)).With("task", "1")
+	child := base.With("step", "Cart").WithHandler(NewConsoleHandler(&b, LevelInfo))
+	base.With("step", "Payment")
+
+	child.Info("Carted")
+	base.Info("Stopped")
+
+	if !strings.Contains(a.String(), "Carted task=1 step=Cart\n") || !strings.Contains(a.String(), "Stopped task=1\n") {
+		t.Fatalf("unexpected output %q", a.String())
+	}
+
+	if strings.Contains(b.String(), "Stopped") || !strings.Contains(b.String(), "Carted") {
+		t.Fatalf("expected only the child to use the added handler. got %q", b.String())
+	}
+
+	if level, err := ParseLevel("WARNING"); err != nil || level != LevelWarn {
+		t.Fatalf("expected warn. got %v (%v)", level, err)
+	}
+
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Fatal("expected an unknown level error")
+	}
+}
+
+// Tests that files are rotated at their maximum size and only the newest backups are kept
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "nothing.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"}
+
+	for file, content := range expected {
+		data, err := ioutil.ReadFile(file)
+
+		if err != nil || string(data) != content {
+			t.Fatalf("expected %v to contain %q. got %q (%v)", file, content, data, err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected only 2 backups to be kept")
+	}
+
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Fatal("expected writing to a closed file to fail")
+	}
+}
@@ -0,0 +1,319 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric A metric a registry can write in the Prometheus text format
+type Metric interface {
+	Name() string
+	write(w io.Writer)
+}
+
+// Registry Holds metrics and exposes them in the Prometheus text format
+type Registry struct {
+	mutex   *sync.Mutex
+	metrics map[string]Metric
+}
+
+// Default The registry the bot's metrics are registered on
+var Default = NewRegistry()
+
+// NewRegistry Creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		mutex:   &sync.Mutex{},
+		metrics: map[string]Metric{},
+	}
+}
+
+// Register Adds metrics to the registry. A metric with the same name is replaced.
+func (r *Registry) Register(metrics ...Metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, m := range metrics {
+		r.metrics[m.Name()] = m
+	}
+}
+
+// WriteText Writes every metric in the Prometheus text format, sorted by name
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.Lock()
+	metrics := make([]Metric, 0, len(r.metrics))
+
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+
+	r.mutex.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name() < metrics[j].Name()
+	})
+
+	var b bytes.Buffer
+
+	for _, m := range metrics {
+		m.write(&b)
+	}
+
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// ServeHTTP Responds with every metric in the Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// The labels and values shared by every metric type
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	mutex  *sync.Mutex
+	series map[string][]string // The label values of every series, by key.
+}
+
+func newVec(name string, help string, kind string, labels []string) vec {
+	return vec{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		mutex:  &sync.Mutex{},
+		series: map[string][]string{},
+	}
+}
+
+func (v *vec) Name() string {
+	return v.name
+}
+
+// Returns the key of the series with the label values. Missing values are empty and extra ones are ignored, so a
+// mistake in the amount of values never stops the bot.
+func (v *vec) key(values []string) (string, []string) {
+	fixed := make([]string, len(v.labels))
+	copy(fixed, values)
+	return strings.Join(fixed, "\xff"), fixed
+}
+
+// Returns the keys of every series, sorted so the output is stable
+func (v *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.series))
+
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec) writeHeader(w io.Writer) {
+	if v.help != "" {
+		fmt.Fprintf(w, "# HELP %v %v\n", v.name, escapeHelp(v.help))
+	}
+
+	fmt.Fprintf(w, "# TYPE %v %v\n", v.name, v.kind)
+}
+
+// Formats label names and values as {a="1",b="2"}, with extra pairs added at the end
+func (v *vec) labelText(values []string, extra ...string) string {
+	var pairs []string
+
+	for i, label := range v.labels {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", label, escapeLabel(values[i])))
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", extra[i], escapeLabel(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec Counters that only go up, one per set of label values
+type CounterVec struct {
+	vec
+	values map[string]float64
+}
+
+// NewCounterVec Creates counters with the given label names
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(name, help, "counter", labels), map[string]float64{}}
+}
+
+// Add Adds to the counter with the label values. Negative amounts are ignored.
+func (c *CounterVec) Add(amount float64, values ...string) {
+	if amount < 0 {
+		return
+	}
+
+	key, fixed := c.key(values)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.series[key] = fixed
+	c.values[key] += amount
+}
+
+// Inc Adds one to the counter with the label values
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Value Returns the counter with the label values
+func (c *CounterVec) Value(values ...string) float64 {
+	key, _ := c.key(values)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.writeHeader(w)
+
+	for _, key := range c.sortedKeys() {
+		fmt.Fprintf(w, "%v%v %v\n", c.name, c.labelText(c.series[key]), formatValue(c.values[key]))
+	}
+}
+
+// GaugeFunc A gauge without labels whose value is read when metrics are written, ex. the amount of running tasks
+type GaugeFunc struct {
+	vec
+	value func() float64
+}
+
+// NewGaugeFunc Creates a gauge that calls value every time metrics are written
+func NewGaugeFunc(name string, help string, value func() float64) *GaugeFunc {
+	return &GaugeFunc{newVec(name, help, "gauge", nil), value}
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.writeHeader(w)
+	fmt.Fprintf(w, "%v %v\n", g.name, formatValue(g.value()))
+}
+
+// DefaultBuckets The upper bounds, in seconds, histograms of request and step durations use
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// HistogramVec Histograms that count observations in buckets, one per set of label values
+type HistogramVec struct {
+	vec
+	buckets []float64
+	counts  map[string][]uint64 // The observations in each bucket, not including the buckets below it.
+	sums    map[string]float64
+	totals  map[string]uint64
+}
+
+// NewHistogramVec Creates histograms with the bucket upper bounds, which must be sorted, and label names
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		vec:     newVec(name, help, "histogram", labels),
+		buckets: buckets,
+		counts:  map[string][]uint64{},
+		sums:    map[string]float64{},
+		totals:  map[string]uint64{},
+	}
+}
+
+// Observe Adds a value to the histogram with the label values
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	key, fixed := h.key(values)
+	bucket := sort.SearchFloat64s(h.buckets, value)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.counts[key] == nil {
+		h.series[key] = fixed
+		h.counts[key] = make([]uint64, len(h.buckets))
+	}
+
+	if bucket < len(h.buckets) {
+		h.counts[key][bucket]++
+	}
+
+	h.sums[key] += value
+	h.totals[key]++
+}
+
+// Count Returns the amount of values observed by the histogram with the label values
+func (h *HistogramVec) Count(values ...string) uint64 {
+	key, _ := h.key(values)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.totals[key]
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.writeHeader(w)
+
+	for _, key := range h.sortedKeys() {
+		values := h.series[key]
+		cumulative := uint64(0)
+
+		for i, bound := range h.buckets {
+			cumulative += h.counts[key][i]
+			fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, h.labelText(values, "le", formatValue(bound)), cumulative)
+		}
+
+		fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, h.labelText(values, "le", "+Inf"), h.totals[key])
+		fmt.Fprintf(w, "%v_sum%v %v\n", h.name, h.labelText(values), formatValue(h.sums[key]))
+		fmt.Fprintf(w, "%v_count%v %v\n", h.name, h.labelText(values), h.totals[key])
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"").Replace(s)
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Tests that every metric type is written in the Prometheus text format, sorted by name and label values
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	counter := NewCounterVec("test_errors_total", "Errors by status.", "site", "status")
+	histogram := NewHistogramVec("test_duration_seconds", "Durations.", []float64{0.1, 1}, "step")
+	gauge := NewGaugeFunc("test_running", "Running\ntasks.", func() float64 { return 3 })
+	r.Register(counter, histogram, gauge)
+
+	counter.Inc("Kith", "503")
+	counter.Add(2, "Kith", "429")
+	counter.Add(-1, "Kith", "429")
+	counter.Inc("Say \"hi\"\\", "500", "extra")
+	histogram.Observe(0.05, "Payment")
+	histogram.Observe(0.1, "Payment")
+	histogram.Observe(0.5, "Payment")
+	histogram.Observe(2, "Payment")
+
+	var b bytes.Buffer
+
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+
+	expected := `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{step="Payment",le="0.1"} 2
+test_duration_seconds_bucket{step="Payment",le="1"} 3
+test_duration_seconds_bucket{step="Payment",le="+Inf"} 4
+test_duration_seconds_sum{step="Payment"} 2.65
+test_duration_seconds_count{step="Payment"} 4
+# HELP test_errors_total Errors by status.
+# TYPE test_errors_total counter
+test_errors_total{site="Kith",status="429"} 2
+test_errors_total{site="Kith",status="503"} 1
+test_errors_total{site="Say \"hi\"\\",status="500"} 1
+# HELP test_running Running\ntasks.
+# TYPE test_running gauge
+test_running 3
+`
+
+	if b.String() != expected {
+		t.Fatalf("expected\n%v\ngot\n%v", expected, b.String())
+	}
+
+	if counter.Value("Kith", "429") != 2 || histogram.Count("Payment") != 4 || histogram.Count("Cart") != 0 {
+		t.Fatalf("unexpected values")
+	}
+}
+
+// Tests that registering a metric with a name that's taken replaces the old one
+func TestRegisterReplaces(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewGaugeFunc("test_running", "", func() float64 { return 1 }))
+	r.Register(NewGaugeFunc("test_running", "", func() float64 { return 2 }))
+
+	var b bytes.Buffer
+	r.WriteText(&b)
+
+	if strings.Count(b.String(), "\ntest_running ") != 1 || !strings.Contains(b.String(), "test_running 2\n") {
+		t.Fatalf("expected only the second gauge. got\n%v", b.String())
+	}
+}
+
+// Tests that the registry serves metrics over HTTP
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewGaugeFunc("test_running", "", func() float64 { return 1 }))
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if resp.Code != http.StatusOK || !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/plain; version=0.0.4") ||
+		!strings.Contains(resp.Body.String(), "test_running 1") {
+		t.Fatalf("unexpected response %v %v: %v", resp.Code, resp.Header(), resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected POST to be rejected. got %v", resp.Code)
+	}
+}
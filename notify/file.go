@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// File Appends every event to a file as a line of JSON
+type File struct {
+	Path  string
+	mutex *sync.Mutex
+}
+
+// NewFile Creates a sink that appends events to a JSONL file. The file is created on the first event.
+func NewFile(path string) *File {
+	return &File{
+		Path:  path,
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Notify Appends the event to the file
+func (f *File) Notify(e Event) error {
+	data, err := json.Marshal(e)
+
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
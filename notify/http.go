@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Http Posts every event as JSON to a URL
+type Http struct {
+	Url     string
+	Headers map[string]string // Sent with every request, ex. an Authorization header.
+	Client  *resty.Client
+}
+
+// NewHttp Creates a sink that posts events to a URL
+func NewHttp(url string, headers map[string]string) *Http {
+	return &Http{
+		Url:     url,
+		Headers: headers,
+		Client:  newClient(),
+	}
+}
+
+// Notify Posts the event
+func (h *Http) Notify(e Event) error {
+	return postJson(h.Client.R().SetHeaders(h.Headers), h.Url, e)
+}
+
+// Creates the client sinks send requests with
+func newClient() *resty.Client {
+	return resty.New().SetTimeout(15 * time.Second)
+}
+
+// Posts a JSON body and returns an error if the response isn't a 2xx
+func postJson(r *resty.Request, url string, body interface{}) error {
+	resp, err := r.
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post(url)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return &RateLimitError{RetryAfter: ParseRetryAfter(resp.Header().Get("Retry-After"))}
+	}
+
+	if !resp.IsSuccess() {
+		return errors.New(fmt.Sprintf("unexpected status %v: %v", resp.StatusCode(), resp.String()))
+	}
+
+	return nil
+}
+
+// ParseRetryAfter Parses a Retry-After header in seconds, ex. "2" or "0.5". Returns a second if it's missing or invalid.
+func ParseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.ParseFloat(header, 64)
+
+	if err != nil || seconds < 0 {
+		return time.Second
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
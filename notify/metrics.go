@@ -0,0 +1,13 @@
+package notify
+
+import (
+	"Mystery/metrics"
+)
+
+var sendFailures = metrics.NewCounterVec("nothing_notification_failures_total",
+	"Notifications that failed to send, by sink. Every failed attempt is counted, including ones that are retried.", "sink")
+
+// RegisterMetrics Adds the notification metrics to the registry
+func RegisterMetrics(r *metrics.Registry) {
+	r.Register(sendFailures)
+}
@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventCheckoutSuccess   EventType = "checkout_success"
+	EventCheckoutDecline   EventType = "checkout_decline" // Payment was declined and the task ran out of payment retries.
+	EventCheckoutFailure   EventType = "checkout_failure" // The task stopped for any other reason, ex. running out of attempts.
+	EventAutomationStarted EventType = "automation_started"
+	EventAutomationStopped EventType = "automation_stopped"
+	EventProductNew        EventType = "product_new"
+	EventVariantNew        EventType = "variant_new" // A product that was already known got a new variant.
+	EventVariantRestock    EventType = "variant_restock"
+	EventVariantSoldOut    EventType = "variant_sold_out"
+	EventPriceChange       EventType = "price_change"
+)
+
+// EventTypes Every type of event that can be routed to sinks
+var EventTypes = []EventType{EventCheckoutSuccess, EventCheckoutDecline, EventCheckoutFailure, EventAutomationStarted, EventAutomationStopped,
+	EventProductNew, EventVariantNew, EventVariantRestock, EventVariantSoldOut, EventPriceChange}
+
+type Event struct {
+	Type       EventType        `json:"type"`
+	Time       time.Time        `json:"time"`
+	Group      string           `json:"group,omitempty"` // The task group the event came from, if any.
+	Checkout   *CheckoutEvent   `json:"checkout,omitempty"`
+	Automation *AutomationEvent `json:"automation,omitempty"`
+	Product    *ProductEvent    `json:"product,omitempty"`
+}
+
+type CheckoutEvent struct {
+	Success       bool   `json:"success"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	Site          string `json:"site"`
+	Mode          string `json:"mode"`
+	ProductTitle  string `json:"product_title"`
+	ProductSize   string `json:"product_size"`
+	ProductImage  string `json:"product_image,omitempty"`
+	Profile       string `json:"profile"`
+	ProxyList     string `json:"proxy_list"`
+	Email         string `json:"email"`
+	OrderNumber   string `json:"order_number,omitempty"`
+	OrderLink     string `json:"order_link,omitempty"`
+	Quantity      int    `json:"quantity"`
+	TaskId        string `json:"task_id"`
+	LogFile       string `json:"log_file,omitempty"` // The task's own log file, if it has one, to look at when a checkout goes wrong.
+}
+
+type AutomationEvent struct {
+	Name             string   `json:"name"`
+	Site             string   `json:"site"`
+	ProductTitle     string   `json:"product_title"`
+	ProductUrl       string   `json:"product_url"`
+	ProductImage     string   `json:"product_image,omitempty"`
+	Sizes            []string `json:"sizes"`
+	Quantity         int      `json:"quantity"`
+	TaskCount        int      `json:"task_count"`
+	PaymentRetries   int      `json:"payment_retries"`
+	StopAfterMinutes int      `json:"stop_after_minutes"`
+}
+
+// ProductEvent A change a site monitor saw between two polls of a site's products
+type ProductEvent struct {
+	Site         string `json:"site"`
+	ProductTitle string `json:"product_title"`
+	ProductUrl   string `json:"product_url"`
+	Size         string `json:"size,omitempty"`       // Empty for new products.
+	VariantId    int64  `json:"variant_id,omitempty"` // Empty for new products.
+	Before       string `json:"before,omitempty"`     // The price or stock before the change, ex. "110.00" or "Sold Out".
+	After        string `json:"after,omitempty"`
+}
+
+// Notifier Delivers events to somewhere outside the bot, ex. a Discord channel or a file
+type Notifier interface {
+	Notify(e Event) error
+}
+
+// Field A labelled value that text based sinks show for an event
+type Field struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// NewCheckoutEvent Creates a checkout success, decline or failure event
+func NewCheckoutEvent(eventType EventType, checkout CheckoutEvent) Event {
+	return Event{
+		Type:     eventType,
+		Time:     time.Now(),
+		Checkout: &checkout,
+	}
+}
+
+// NewAutomationEvent Creates an automation started or stopped event
+func NewAutomationEvent(started bool, automation AutomationEvent) Event {
+	e := Event{
+		Type:       EventAutomationStopped,
+		Time:       time.Now(),
+		Automation: &automation,
+	}
+
+	if started {
+		e.Type = EventAutomationStarted
+	}
+
+	return e
+}
+
+// NewProductEvent Creates a new product, new variant, restock, sold out or price change event
+func NewProductEvent(eventType EventType, product ProductEvent) Event {
+	return Event{
+		Type:    eventType,
+		Time:    time.Now(),
+		Product: &product,
+	}
+}
+
+// IsValidEventType Returns if events of the given type exist
+func IsValidEventType(t EventType) bool {
+	for _, eventType := range EventTypes {
+		if eventType == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Title Returns a short description of the event, ex. "Successful Checkout!"
+func (e *Event) Title() string {
+	switch e.Type {
+	case EventCheckoutSuccess:
+		return "Successful Checkout!"
+	case EventCheckoutDecline:
+		return "Checkout Declined!"
+	case EventCheckoutFailure:
+		return "Checkout Failed!"
+	case EventAutomationStarted:
+		return "Automation Started"
+	case EventAutomationStopped:
+		return "Automation Stopped"
+	case EventProductNew:
+		return "New Product"
+	case EventVariantNew:
+		return "New Variant"
+	case EventVariantRestock:
+		return "Restocked"
+	case EventVariantSoldOut:
+		return "Sold Out"
+	case EventPriceChange:
+		return "Price Changed"
+	default:
+		return string(e.Type)
+	}
+}
+
+// IsSuccess Returns if the event is good news, which sinks can use to pick a color
+func (e *Event) IsSuccess() bool {
+	switch e.Type {
+	case EventCheckoutSuccess, EventAutomationStarted, EventProductNew, EventVariantNew, EventVariantRestock:
+		return true
+	default:
+		return false
+	}
+}
+
+// Fields Returns the details of the event in the order they should be shown
+func (e *Event) Fields() []Field {
+	var fields []Field
+
+	if c := e.Checkout; c != nil {
+		if c.FailureReason != "" {
+			fields = append(fields, Field{"Reason", c.FailureReason, false})
+		}
+
+		orderNumber := c.OrderNumber
+
+		if orderNumber == "" {
+			orderNumber = "None"
+		}
+
+		fields = append(fields,
+			Field{"Site", c.Site, true},
+			Field{"Mode", c.Mode, true},
+			Field{"Product", c.ProductTitle, true},
+			Field{"Size", c.ProductSize, true},
+			Field{"Quantity", fmt.Sprintf("%v", c.Quantity), true},
+			Field{"Profile", c.Profile, true},
+			Field{"Email", c.Email, true},
+			Field{"Proxy List", c.ProxyList, true},
+			Field{"Order Number", orderNumber, true},
+		)
+	}
+
+	if a := e.Automation; a != nil {
+		sizes := "Random"
+
+		if len(a.Sizes) > 0 {
+			sizes = strings.Join(a.Sizes, ", ")
+		}
+
+		fields = append(fields,
+			Field{"Automation Name", a.Name, false},
+			Field{"Site", a.Site, false},
+			Field{"Product", a.ProductTitle, false},
+			Field{"Sizes", sizes, true},
+			Field{"Quantity", fmt.Sprintf("%v", a.Quantity), true},
+			Field{"Task Count", fmt.Sprintf("%v", a.TaskCount), true},
+			Field{"Payment Retries", fmt.Sprintf("%v", a.PaymentRetries), true},
+			Field{"Stop After Minutes", fmt.Sprintf("%v", a.StopAfterMinutes), true},
+		)
+	}
+
+	if p := e.Product; p != nil {
+		fields = append(fields,
+			Field{"Site", p.Site, true},
+			Field{"Product", p.ProductTitle, true},
+			Field{"Link", p.ProductUrl, false},
+		)
+
+		if p.Size != "" {
+			fields = append(fields, Field{"Size", p.Size, true}, Field{"Variant", fmt.Sprintf("%v", p.VariantId), true})
+		}
+
+		if p.Before != "" || p.After != "" {
+			fields = append(fields, Field{"Before", p.Before, true}, Field{"After", p.After, true})
+		}
+	}
+
+	if e.Group != "" {
+		fields = append(fields, Field{"Group", e.Group, true})
+	}
+
+	return fields
+}
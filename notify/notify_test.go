@@ -0,0 +1,190 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A sink that records the events it receives
+type testSink struct {
+	events []Event
+	err    error
+}
+
+func (s *testSink) Notify(e Event) error {
+	s.events = append(s.events, e)
+	return s.err
+}
+
+// Starts a server that records the body and headers of every request
+func newTestServer(t *testing.T, status int) (*httptest.Server, *[]map[string]interface{}, *[]http.Header) {
+	var bodies []map[string]interface{}
+	var headers []http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+
+		bodies = append(bodies, body)
+		headers = append(headers, r.Header)
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(server.Close)
+	return server, &bodies, &headers
+}
+
+func newTestCheckoutEvent() Event {
+	return NewCheckoutEvent(EventCheckoutSuccess, CheckoutEvent{
+		Success:      true,
+		Site:         "Kith",
+		Mode:         "Safe",
+		ProductTitle: "Nike Dunk Low",
+		ProductSize:  "10",
+		Profile:      "Test US",
+		Email:        "test@gmail.com",
+		OrderNumber:  "#1001",
+		OrderLink:    "https://kith.com/orders/1001",
+		Quantity:     1,
+	})
+}
+
+// Tests that events go to their routes and fall back to the default routes
+func TestRouterRoutes(t *testing.T) {
+	router := NewRouter()
+	discord, log := &testSink{}, &testSink{}
+	router.AddSink("discord", discord)
+	router.AddSink("log", log)
+	router.Defaults = Routes{EventCheckoutSuccess: {"discord", "log"}, EventCheckoutFailure: {"log"}}
+
+	routes := Routes{EventCheckoutSuccess: {"discord"}}
+
+	if err := router.Send(routes, newTestCheckoutEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(discord.events) != 1 || len(log.events) != 0 {
+		t.Fatalf("expected the group route to override the default. got %v, %v", len(discord.events), len(log.events))
+	}
+
+	if err := router.Send(routes, NewCheckoutEvent(EventCheckoutFailure, CheckoutEvent{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(discord.events) != 1 || len(log.events) != 1 {
+		t.Fatalf("expected the default route to be used. got %v, %v", len(discord.events), len(log.events))
+	}
+
+	if err := router.Send(Routes{EventCheckoutSuccess: {}}, newTestCheckoutEvent()); err != nil || len(discord.events) != 1 {
+		t.Fatalf("expected an empty route to send nothing. got %v", err)
+	}
+}
+
+// Tests that every sink is tried when one fails or doesn't exist
+func TestRouterErrors(t *testing.T) {
+	router := NewRouter()
+	failing, working := &testSink{err: errors.New("down")}, &testSink{}
+	router.AddSink("failing", failing)
+	router.AddSink("working", working)
+
+	err := router.Send(Routes{EventCheckoutSuccess: {"failing", "missing", "working"}}, newTestCheckoutEvent())
+
+	if err == nil || !strings.Contains(err.Error(), "failing: down") || !strings.Contains(err.Error(), "missing: unknown sink") {
+		t.Fatalf("expected both sinks in the error. got %v", err)
+	}
+
+	if len(working.events) != 1 {
+		t.Fatalf("expected the working sink to be sent the event")
+	}
+
+	var nilRouter *Router
+	nilRouter.SendAndLog(nil, newTestCheckoutEvent())
+
+	if err := (Routes{"checkout_maybe": {"log"}}).Validate(); err == nil {
+		t.Fatal("expected an unknown event type error")
+	}
+}
+
+// Tests that the http sink posts the event as JSON with its headers
+func TestHttp(t *testing.T) {
+	server, bodies, headers := newTestServer(t, http.StatusOK)
+
+	if err := NewHttp(server.URL, map[string]string{"Authorization": "Bearer abc"}).Notify(newTestCheckoutEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	if (*headers)[0].Get("Authorization") != "Bearer abc" {
+		t.Fatalf("expected the authorization header. got %v", (*headers)[0])
+	}
+
+	body := (*bodies)[0]
+
+	if body["type"] != "checkout_success" || body["checkout"].(map[string]interface{})["order_number"] != "#1001" {
+		t.Fatalf("unexpected body %v", body)
+	}
+
+	server, _, _ = newTestServer(t, http.StatusInternalServerError)
+
+	if err := NewHttp(server.URL, nil).Notify(newTestCheckoutEvent()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// Tests the Slack message an event is posted as
+func TestSlack(t *testing.T) {
+	server, bodies, _ := newTestServer(t, http.StatusOK)
+
+	if err := NewSlack(server.URL).Notify(newTestCheckoutEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	body := (*bodies)[0]
+	attachment := body["attachments"].([]interface{})[0].(map[string]interface{})
+
+	if body["text"] != "Successful Checkout!" || attachment["color"] != "good" || attachment["title_link"] != "https://kith.com/orders/1001" {
+		t.Fatalf("unexpected message %v", body)
+	}
+
+	if fields := attachment["fields"].([]interface{}); len(fields) != 9 {
+		t.Fatalf("expected 9 fields. got %v", len(fields))
+	}
+}
+
+// Tests that the file sink appends a line per event
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFile(path)
+
+	for i := 0; i < 2; i++ {
+		if err := sink.Notify(newTestCheckoutEvent()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines. got %v", len(lines))
+	}
+
+	var e Event
+
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil || e.Checkout == nil || e.Checkout.Site != "Kith" {
+		t.Fatalf("unexpected line %v (%v)", lines[1], err)
+	}
+}
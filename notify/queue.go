@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"Mystery/logs"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull The queue has too many undelivered events to take another one
+var ErrQueueFull = errors.New("notification queue is full")
+
+// ErrQueueClosed The queue has been closed and no longer takes events
+var ErrQueueClosed = errors.New("notification queue is closed")
+
+// RateLimitError A sink was rate limited and shouldn't be sent anything else until RetryAfter has passed
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %v", e.RetryAfter)
+}
+
+// Batcher A sink that can deliver several events at once, ex. Discord allows 10 embeds per message
+type Batcher interface {
+	NotifyBatch(events []Event) error
+	MaxBatchSize() int
+}
+
+// Queue Delivers events to a sink on a background goroutine, so a slow or failing sink never blocks the task that
+// sent the event. Failed deliveries are retried with exponential backoff, rate limits are waited out and, if the sink
+// is a Batcher, events that are waiting together are delivered together.
+type Queue struct {
+	Notifier    Notifier
+	Name        string        // The sink's name in metrics.
+	MaxAttempts int           // The amount of times a delivery is tried before its events are dropped.
+	Backoff     time.Duration // The delay before the first retry. Doubles with every retry after it.
+	MaxBackoff  time.Duration
+
+	mutex  *sync.RWMutex
+	closed bool
+	events chan Event
+	done   chan struct{}
+}
+
+// NewQueue Creates a queue that holds up to size undelivered events and starts delivering them to the sink
+func NewQueue(n Notifier, size int) *Queue {
+	q := &Queue{
+		Notifier:    n,
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		mutex:       &sync.RWMutex{},
+		events:      make(chan Event, size),
+		done:        make(chan struct{}),
+	}
+
+	go q.run()
+	return q
+}
+
+// Notify Adds the event to the queue without waiting for it to be delivered. Returns ErrQueueFull instead of
+// waiting when the queue is full.
+func (q *Queue) Notify(e Event) error {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.events <- e:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close Stops taking events and waits up to the timeout for the ones already queued to be delivered
+func (q *Queue) Close(timeout time.Duration) error {
+	q.mutex.Lock()
+
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+
+	q.mutex.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-time.After(timeout):
+		return errors.New(fmt.Sprintf("timed out with %v undelivered events", len(q.events)))
+	}
+}
+
+// Delivers events until the queue is closed and empty
+func (q *Queue) run() {
+	defer close(q.done)
+
+	batcher, _ := q.Notifier.(Batcher)
+
+	for e := range q.events {
+		batch := []Event{e}
+
+		if batcher != nil {
+			batch = q.fillBatch(batch, batcher.MaxBatchSize())
+		}
+
+		if err := q.deliver(batch); err != nil {
+			logs.Error("Dropped notifications", "count", len(batch), "attempts", q.MaxAttempts, "error", err)
+		}
+	}
+}
+
+// Adds events that are already waiting to the batch until it's full
+func (q *Queue) fillBatch(batch []Event, size int) []Event {
+	for len(batch) < size {
+		select {
+		case e, ok := <-q.events:
+			if !ok {
+				return batch
+			}
+
+			batch = append(batch, e)
+		default:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+// Sends a batch of events, retrying until it's delivered or out of attempts
+func (q *Queue) deliver(batch []Event) error {
+	backoff := q.Backoff
+	var err error
+
+	for attempt := 1; attempt <= q.MaxAttempts; attempt++ {
+		if batcher, ok := q.Notifier.(Batcher); ok {
+			err = batcher.NotifyBatch(batch)
+		} else {
+			err = q.Notifier.Notify(batch[0])
+		}
+
+		if err == nil {
+			break
+		}
+
+		sendFailures.Inc(q.Name)
+
+		if attempt == q.MaxAttempts {
+			break
+		}
+
+		var rateLimit *RateLimitError
+
+		// Rate limits say exactly how long to wait, so they don't touch the backoff
+		if errors.As(err, &rateLimit) {
+			time.Sleep(rateLimit.RetryAfter)
+			continue
+		}
+
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > q.MaxBackoff {
+			backoff = q.MaxBackoff
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A batching sink that blocks on its first delivery until released and fails a set amount of times
+type testBatcher struct {
+	mutex   sync.Mutex
+	batches [][]Event
+	entered chan struct{}
+	release chan struct{}
+	errs    []error
+}
+
+func newTestBatcher(errs ...error) *testBatcher {
+	return &testBatcher{entered: make(chan struct{}, 1), release: make(chan struct{}), errs: errs}
+}
+
+func (b *testBatcher) Notify(e Event) error {
+	return b.NotifyBatch([]Event{e})
+}
+
+func (b *testBatcher) NotifyBatch(events []Event) error {
+	select {
+	case b.entered <- struct{}{}:
+		<-b.release
+	default:
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.batches = append(b.batches, events)
+
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		return err
+	}
+
+	return nil
+}
+
+func (b *testBatcher) MaxBatchSize() int {
+	return 10
+}
+
+func (b *testBatcher) sizes() []int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var sizes []int
+
+	for _, batch := range b.batches {
+		sizes = append(sizes, len(batch))
+	}
+
+	return sizes
+}
+
+// Tests that events waiting together are delivered in batches and flushed on close
+func TestQueueBatches(t *testing.T) {
+	sink := newTestBatcher()
+	q := NewQueue(sink, 20)
+
+	if err := q.Notify(newTestCheckoutEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	<-sink.entered
+
+	for i := 0; i < 12; i++ {
+		if err := q.Notify(newTestCheckoutEvent()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(sink.release)
+
+	if err := q.Close(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if sizes := sink.sizes(); len(sizes) != 3 || sizes[0] != 1 || sizes[1] != 10 || sizes[2] != 2 {
+		t.Fatalf("expected batches of 1, 10 and 2. got %v", sizes)
+	}
+
+	if err := q.Notify(newTestCheckoutEvent()); err != ErrQueueClosed {
+		t.Fatalf("expected the closed queue to refuse events. got %v", err)
+	}
+}
+
+// Tests that a full queue refuses events instead of blocking
+func TestQueueFull(t *testing.T) {
+	sink := newTestBatcher()
+	q := NewQueue(sink, 1)
+
+	q.Notify(newTestCheckoutEvent())
+	<-sink.entered
+	q.Notify(newTestCheckoutEvent())
+
+	if err := q.Notify(newTestCheckoutEvent()); err != ErrQueueFull {
+		t.Fatalf("expected a full queue. got %v", err)
+	}
+
+	if err := q.Close(10 * time.Millisecond); err == nil {
+		t.Fatal("expected the close to time out while the sink is blocked")
+	}
+
+	close(sink.release)
+}
+
+// Tests that rate limits are waited out and other errors are retried until out of attempts
+func TestQueueRetries(t *testing.T) {
+	sink := newTestBatcher(&RateLimitError{RetryAfter: 30 * time.Millisecond}, errors.New("down"))
+	close(sink.release)
+
+	q := NewQueue(sink, 10)
+	q.Name = "retries"
+	q.Backoff = time.Millisecond
+	start := time.Now()
+
+	q.Notify(newTestCheckoutEvent())
+
+	if err := q.Close(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected the rate limit to be waited out. took %v", elapsed)
+	}
+
+	if sizes := sink.sizes(); len(sizes) != 3 {
+		t.Fatalf("expected 3 attempts. got %v", len(sizes))
+	}
+
+	if n := sendFailures.Value("retries"); n != 2 {
+		t.Fatalf("expected 2 failed attempts to be counted. got %v", n)
+	}
+
+	sink = newTestBatcher(errors.New("down"), errors.New("down"), errors.New("down"))
+	close(sink.release)
+
+	q = NewQueue(sink, 10)
+	q.Backoff = time.Millisecond
+	q.MaxAttempts = 2
+
+	q.Notify(newTestCheckoutEvent())
+	q.Close(time.Second)
+
+	if sizes := sink.sizes(); len(sizes) != 2 {
+		t.Fatalf("expected the events to be dropped after 2 attempts. got %v", len(sizes))
+	}
+}
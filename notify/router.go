@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"Mystery/logs"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Routes The names of the sinks each type of event is sent to, ex. {"checkout_success": ["discord", "log"]}
+type Routes map[EventType][]string
+
+// Router Sends events to sinks by name. Task groups and automations pick their sinks with Routes, and fall back to the
+// router's default routes for event types they don't list.
+type Router struct {
+	mutex    *sync.RWMutex
+	sinks    map[string]Notifier
+	Defaults Routes
+}
+
+// NewRouter Creates a router with no sinks
+func NewRouter() *Router {
+	return &Router{
+		mutex: &sync.RWMutex{},
+		sinks: map[string]Notifier{},
+	}
+}
+
+// AddSink Adds a sink that routes can send events to by name. A sink with the same name is replaced.
+func (r *Router) AddSink(name string, n Notifier) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.sinks[name] = n
+}
+
+// Sink Returns the sink with the given name
+func (r *Router) Sink(name string) (Notifier, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	n, ok := r.sinks[name]
+	return n, ok
+}
+
+// Send Sends an event to every sink its type is routed to. Every sink is tried even if one fails.
+func (r *Router) Send(routes Routes, e Event) error {
+	names, ok := routes[e.Type]
+
+	if !ok {
+		names = r.Defaults[e.Type]
+	}
+
+	var failed []string
+
+	for _, name := range names {
+		n, ok := r.Sink(name)
+
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%v: unknown sink", name))
+			sendFailures.Inc(name)
+			continue
+		}
+
+		if err := n.Notify(e); err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+			sendFailures.Inc(name)
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.New(fmt.Sprintf("sending %v: %v", e.Type, failed))
+	}
+
+	return nil
+}
+
+// SendAndLog Sends an event and logs any sinks that failed instead of returning the error
+func (r *Router) SendAndLog(routes Routes, e Event) {
+	if r == nil {
+		return
+	}
+
+	if err := r.Send(routes, e); err != nil {
+		logs.Error("Failed to send notification", "error", err)
+	}
+}
+
+// Close Closes every sink that queues events, waiting up to the timeout for them to deliver what's left
+func (r *Router) Close(timeout time.Duration) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	mutex := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	var failed []string
+
+	for name, n := range r.sinks {
+		q, ok := n.(*Queue)
+
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(name string, q *Queue) {
+			defer wg.Done()
+
+			if err := q.Close(timeout); err != nil {
+				mutex.Lock()
+				failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+				mutex.Unlock()
+			}
+		}(name, q)
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.New(fmt.Sprintf("closing sinks: %v", failed))
+	}
+
+	return nil
+}
+
+// Validate Returns an error if the routes use an unknown event type
+func (routes Routes) Validate() error {
+	for eventType := range routes {
+		if !IsValidEventType(eventType) {
+			return errors.New(fmt.Sprintf("unknown event type %q", eventType))
+		}
+	}
+
+	return nil
+}
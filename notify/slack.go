@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"github.com/go-resty/resty/v2"
+	"time"
+)
+
+// Slack Posts events to a Slack incoming webhook
+type Slack struct {
+	Url    string
+	Client *resty.Client
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	ThumbUrl  string       `json:"thumb_url,omitempty"`
+	Fields    []slackField `json:"fields"`
+	Footer    string       `json:"footer"`
+	Timestamp int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// NewSlack Creates a sink that posts to a Slack incoming webhook URL
+func NewSlack(url string) *Slack {
+	return &Slack{Url: url, Client: newClient()}
+}
+
+// Notify Posts the event as a message with an attachment of its fields
+func (s *Slack) Notify(e Event) error {
+	return postJson(s.Client.R(), s.Url, newSlackMessage(e))
+}
+
+func newSlackMessage(e Event) slackMessage {
+	attachment := slackAttachment{
+		Color:     "danger",
+		Title:     e.Title(),
+		Fields:    []slackField{},
+		Footer:    "Nothing",
+		Timestamp: e.Time.Unix(),
+	}
+
+	if e.IsSuccess() {
+		attachment.Color = "good"
+	}
+
+	if e.Time.IsZero() {
+		attachment.Timestamp = time.Now().Unix()
+	}
+
+	if e.Checkout != nil {
+		attachment.TitleLink = e.Checkout.OrderLink
+		attachment.ThumbUrl = e.Checkout.ProductImage
+	}
+
+	if e.Automation != nil {
+		attachment.TitleLink = e.Automation.ProductUrl
+		attachment.ThumbUrl = e.Automation.ProductImage
+	}
+
+	for _, field := range e.Fields() {
+		attachment.Fields = append(attachment.Fields, slackField{field.Name, field.Value, field.Inline})
+	}
+
+	return slackMessage{
+		Text:        e.Title(),
+		Attachments: []slackAttachment{attachment},
+	}
+}
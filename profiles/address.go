@@ -1,17 +1,21 @@
 package profiles
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type Address struct {
-	Name     string
-	Email    string
-	Phone    string
-	Line1    string
-	Line2    string
-	PostCode string
-	City     string
-	Country  string
-	State    string
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Line1    string `json:"line1"`
+	Line2    string `json:"line2"`
+	PostCode string `json:"post_code"`
+	City     string `json:"city"`
+	Country  string `json:"country"`
+	State    string `json:"state"`
 }
 
 // GetFirstName Returns the first name of the address receiver
@@ -24,3 +28,32 @@ func (a *Address) GetLastName() string {
 	ss := strings.Split(a.Name, " ")
 	return ss[len(ss)-1]
 }
+
+// Validate Returns an error if a field that checkout requires is missing. Line2 and State are optional since not
+// every address has them.
+func (a *Address) Validate() error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"name", a.Name},
+		{"email", a.Email},
+		{"phone", a.Phone},
+		{"line1", a.Line1},
+		{"city", a.City},
+		{"post code", a.PostCode},
+		{"country", a.Country},
+	}
+
+	for _, field := range fields {
+		if strings.TrimSpace(field.value) == "" {
+			return errors.New(fmt.Sprintf("%v is required", field.name))
+		}
+	}
+
+	if !strings.Contains(a.Email, "@") {
+		return errors.New(fmt.Sprintf("invalid email %q", a.Email))
+	}
+
+	return nil
+}
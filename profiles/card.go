@@ -1,8 +1,83 @@
 package profiles
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Card struct {
-	Number      string
-	CVV         string
-	ExpiryMonth int
-	ExpiryYear  int
+	Number      string `json:"number"`
+	CVV         string `json:"cvv"`
+	ExpiryMonth int    `json:"expiry_month"`
+	ExpiryYear  int    `json:"expiry_year"`
+}
+
+// Validate Returns an error if the card number fails the Luhn check, the CVV isn't 3 or 4 digits, or the card has expired
+func (c *Card) Validate() error {
+	return c.validate(time.Now())
+}
+
+func (c *Card) validate(now time.Time) error {
+	if !IsLuhnValid(c.Number) {
+		return errors.New("invalid card number")
+	}
+
+	if len(c.CVV) < 3 || len(c.CVV) > 4 || !isDigits(c.CVV) {
+		return errors.New("cvv must be 3 or 4 digits")
+	}
+
+	if c.ExpiryMonth < 1 || c.ExpiryMonth > 12 {
+		return errors.New(fmt.Sprintf("invalid expiry month %v", c.ExpiryMonth))
+	}
+
+	if c.IsExpired(now) {
+		return errors.New(fmt.Sprintf("card expired %02d/%v", c.ExpiryMonth, c.ExpiryYear))
+	}
+
+	return nil
+}
+
+// IsExpired Returns if the card has expired by the given time. Cards are valid until the end of their expiry month.
+func (c *Card) IsExpired(now time.Time) bool {
+	year, month := now.Year(), int(now.Month())
+	return c.ExpiryYear < year || (c.ExpiryYear == year && c.ExpiryMonth < month)
+}
+
+// IsLuhnValid Returns if a card number is 12-19 digits and passes the Luhn checksum
+func IsLuhnValid(number string) bool {
+	if len(number) < 12 || len(number) > 19 || !isDigits(number) {
+		return false
+	}
+
+	sum := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+
+		if double {
+			digit *= 2
+
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// Returns if a string is made up of only digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return s != ""
 }
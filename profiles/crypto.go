@@ -0,0 +1,161 @@
+package profiles
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrPassphraseRequired The profile file is encrypted but no passphrase was given
+var ErrPassphraseRequired = errors.New("profile file is encrypted and needs a passphrase")
+
+// ErrWrongPassphrase The passphrase can't decrypt the profile file, or the file has been tampered with
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted profile file")
+
+// The scrypt cost used when encrypting. Files store the parameters they were encrypted with, so this can change
+// without breaking existing files. Lowered in tests.
+var scryptN, scryptR, scryptP = 1 << 15, 8, 1
+
+const (
+	encryptedFileVersion = 1
+	keyLength            = 32 // AES-256
+	saltLength           = 16
+)
+
+// The format of an encrypted profile file. The profiles are encrypted as JSON with AES-GCM, using a key derived from
+// the passphrase with scrypt.
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Kdf     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// A key derived from a passphrase along with what's needed to derive it again
+type encryptionKey struct {
+	key  []byte
+	salt []byte
+	n    int
+	r    int
+	p    int
+}
+
+// Derives a key from a passphrase with a new random salt
+func newEncryptionKey(passphrase string) (*encryptionKey, error) {
+	salt := make([]byte, saltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return deriveEncryptionKey(passphrase, salt, scryptN, scryptR, scryptP)
+}
+
+// Derives a key from a passphrase with known parameters
+func deriveEncryptionKey(passphrase string, salt []byte, n int, r int, p int) (*encryptionKey, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, keyLength)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptionKey{key, salt, n, r, p}, nil
+}
+
+// Returns if the passphrase derives this key
+func (k *encryptionKey) matches(passphrase string) bool {
+	other, err := deriveEncryptionKey(passphrase, k.salt, k.n, k.r, k.p)
+	return err == nil && subtle.ConstantTimeCompare(k.key, other.key) == 1
+}
+
+// Encrypts data into the encrypted file format. Every call uses a new nonce.
+func (k *encryptionKey) encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := newGcm(k.key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(encryptedFile{
+		Version: encryptedFileVersion,
+		Kdf:     "scrypt",
+		N:       k.n,
+		R:       k.r,
+		P:       k.p,
+		Salt:    k.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+}
+
+// Decrypts a file in the encrypted file format. Returns the key so the file can be saved again without the passphrase.
+func decryptFile(f *encryptedFile, passphrase string) ([]byte, *encryptionKey, error) {
+	if f.Version != encryptedFileVersion || f.Kdf != "scrypt" {
+		return nil, nil, errors.New(fmt.Sprintf("unsupported profile file encryption (version %v, %v)", f.Version, f.Kdf))
+	}
+
+	if passphrase == "" {
+		return nil, nil, ErrPassphraseRequired
+	}
+
+	key, err := deriveEncryptionKey(passphrase, f.Salt, f.N, f.R, f.P)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := newGcm(key.key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(f.Nonce) != gcm.NonceSize() {
+		return nil, nil, ErrWrongPassphrase
+	}
+
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+
+	if err != nil {
+		return nil, nil, ErrWrongPassphrase
+	}
+
+	return plaintext, key, nil
+}
+
+// Parses the file as the encrypted file format. Returns nil if it's a plaintext profile file.
+func parseEncryptedFile(data []byte) *encryptedFile {
+	var f encryptedFile
+
+	// Plaintext files are a JSON array of profiles, which fails to unmarshal into an object.
+	if err := json.Unmarshal(data, &f); err != nil || f.Version == 0 {
+		return nil
+	}
+
+	return &f
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
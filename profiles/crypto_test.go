@@ -0,0 +1,245 @@
+package profiles
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Lowers the scrypt cost for the rest of the test so encrypting is fast
+func useFastScrypt(t *testing.T) {
+	n := scryptN
+	scryptN = 1 << 10
+
+	t.Cleanup(func() {
+		scryptN = n
+	})
+}
+
+// Creates an encrypted store with a single profile and saves it to a temporary file
+func newTestEncryptedStore(t *testing.T, passphrase string) (*Store, string) {
+	useFastScrypt(t)
+
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store, err := LoadEncryptedStore(path, passphrase)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Add(newTestProfile("Main")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, path
+}
+
+// Tests that an encrypted store round trips through its file without storing anything in plaintext
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	_, path := newTestEncryptedStore(t, "hunter2")
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"4111111111111111", "542 6th Ave", "jappleseed124", "Main"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("expected %q to be encrypted", secret)
+		}
+	}
+
+	loaded, err := LoadEncryptedStore(path, "hunter2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.IsEncrypted() {
+		t.Fatal("expected the loaded store to stay encrypted")
+	}
+
+	p, ok := loaded.Get("Main")
+
+	if !ok || p.CreditCard.Number != "4111111111111111" {
+		t.Fatalf("expected the Main profile to round trip. got %+v", p)
+	}
+
+	// Saving again without the passphrase keeps the file encrypted with it
+	if err := loaded.Add(newTestProfile("Backup")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded, err = LoadEncryptedStore(path, "hunter2"); err != nil || len(loaded.Names()) != 2 {
+		t.Fatalf("expected 2 profiles after saving again. got %v", err)
+	}
+}
+
+// Tests that an encrypted file can't be loaded without the right passphrase
+func TestEncryptedStoreWrongPassphrase(t *testing.T) {
+	_, path := newTestEncryptedStore(t, "hunter2")
+
+	if _, err := LoadStore(path); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("expected ErrPassphraseRequired. got %v", err)
+	}
+
+	if _, err := LoadEncryptedStore(path, "hunter3"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase. got %v", err)
+	}
+}
+
+// Tests that changes to an encrypted file are detected
+func TestEncryptedStoreTampered(t *testing.T) {
+	_, path := newTestEncryptedStore(t, "hunter2")
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := parseEncryptedFile(data)
+	f.Data[len(f.Data)/2] ^= 1
+	data, _ = json.Marshal(f)
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadEncryptedStore(path, "hunter2"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected the tampered file to be rejected. got %v", err)
+	}
+}
+
+// Tests changing the passphrase of an encrypted store
+func TestEncryptedStoreChangePassphrase(t *testing.T) {
+	store, path := newTestEncryptedStore(t, "hunter2")
+
+	if err := store.ChangePassphrase("wrong", "correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected the current passphrase to be checked. got %v", err)
+	}
+
+	if err := store.ChangePassphrase("hunter2", ""); err == nil {
+		t.Fatal("expected an empty passphrase to be rejected")
+	}
+
+	if err := store.ChangePassphrase("hunter2", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadEncryptedStore(path, "hunter2"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected the old passphrase to stop working. got %v", err)
+	}
+
+	loaded, err := LoadEncryptedStore(path, "correct horse")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := loaded.Get("Main"); !ok {
+		t.Fatal("expected the Main profile after changing passphrase")
+	}
+}
+
+// Tests encrypting a plaintext profile file
+func TestEncryptPlaintextStore(t *testing.T) {
+	useFastScrypt(t)
+
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store := NewStore()
+
+	if err := store.Add(newTestProfile("Main")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadStore(path)
+
+	if err != nil || store.IsEncrypted() {
+		t.Fatalf("expected a plaintext store. got %v", err)
+	}
+
+	if err := store.ChangePassphrase("", "hunter2"); err == nil {
+		t.Fatal("expected ChangePassphrase to require an encrypted store")
+	}
+
+	if err := store.SetPassphrase("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SetPassphrase("hunter3"); err == nil {
+		t.Fatal("expected SetPassphrase to refuse to replace a passphrase")
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadStore(path); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("expected the file to be encrypted. got %v", err)
+	}
+
+	if _, err := LoadEncryptedStore(path, "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that loading a plaintext profile file with a passphrase leaves the file as it is until the store is saved
+func TestLoadEncryptedStoreKeepsPlaintext(t *testing.T) {
+	useFastScrypt(t)
+
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store := NewStore()
+
+	if err := store.Add(newTestProfile("Main")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = LoadEncryptedStore(path, "hunter2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(before) != string(after) {
+		t.Fatal("expected loading not to change the file")
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadStore(path); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("expected the file to be encrypted once saved. got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package profiles
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CsvHeader The columns profiles are exported with
+var CsvHeader = []string{
+	"profile_name", "email", "phone",
+	"shipping_name", "shipping_line1", "shipping_line2", "shipping_city", "shipping_state", "shipping_post_code", "shipping_country",
+	"same_billing_address_as_shipping",
+	"billing_name", "billing_email", "billing_phone",
+	"billing_line1", "billing_line2", "billing_city", "billing_state", "billing_post_code", "billing_country",
+	"card_number", "card_cvv", "card_expiry_month", "card_expiry_year",
+}
+
+// The field each column sets, by its name in lower case without spaces or punctuation. Covers CsvHeader and the
+// column names other bots commonly export with (ex. "Shipping First Name", "Zip Code", "Card Expiry").
+var csvColumns = map[string]string{
+	"profilename": "name", "profile": "name", "name": "name", "title": "name",
+
+	"email": "shipping.email", "shippingemail": "shipping.email", "emailaddress": "shipping.email",
+	"phone": "shipping.phone", "phonenumber": "shipping.phone", "shippingphone": "shipping.phone", "telephone": "shipping.phone",
+	"shippingname": "shipping.name", "fullname": "shipping.name", "shippingfullname": "shipping.name",
+	"shippingfirstname": "shipping.first", "firstname": "shipping.first",
+	"shippinglastname": "shipping.last", "lastname": "shipping.last",
+	"shippingline1": "shipping.line1", "shippingaddress": "shipping.line1", "shippingaddress1": "shipping.line1",
+	"shippingaddressline1": "shipping.line1", "address": "shipping.line1", "address1": "shipping.line1", "addressline1": "shipping.line1",
+	"shippingline2": "shipping.line2", "shippingaddress2": "shipping.line2", "shippingaddressline2": "shipping.line2",
+	"address2": "shipping.line2", "addressline2": "shipping.line2", "shippingapt": "shipping.line2", "apt": "shipping.line2",
+	"shippingcity": "shipping.city", "city": "shipping.city",
+	"shippingstate": "shipping.state", "shippingprovince": "shipping.state", "state": "shipping.state", "province": "shipping.state",
+	"shippingpostcode": "shipping.post_code", "shippingzip": "shipping.post_code", "shippingzipcode": "shipping.post_code",
+	"shippingpostalcode": "shipping.post_code", "postcode": "shipping.post_code", "postalcode": "shipping.post_code",
+	"zip": "shipping.post_code", "zipcode": "shipping.post_code",
+	"shippingcountry": "shipping.country", "country": "shipping.country", "shippingcountrycode": "shipping.country",
+
+	"samebillingaddressasshipping": "same_billing", "samebilling": "same_billing", "samebillingasshipping": "same_billing",
+	"billingsameasshipping": "same_billing", "useshippingforbilling": "same_billing", "billingasshipping": "same_billing",
+
+	"billingname": "billing.name", "billingfullname": "billing.name",
+	"billingfirstname": "billing.first", "billinglastname": "billing.last",
+	"billingemail": "billing.email", "billingphone": "billing.phone", "billingphonenumber": "billing.phone",
+	"billingline1": "billing.line1", "billingaddress": "billing.line1", "billingaddress1": "billing.line1", "billingaddressline1": "billing.line1",
+	"billingline2": "billing.line2", "billingaddress2": "billing.line2", "billingaddressline2": "billing.line2", "billingapt": "billing.line2",
+	"billingcity":  "billing.city",
+	"billingstate": "billing.state", "billingprovince": "billing.state",
+	"billingpostcode": "billing.post_code", "billingzip": "billing.post_code", "billingzipcode": "billing.post_code", "billingpostalcode": "billing.post_code",
+	"billingcountry": "billing.country", "billingcountrycode": "billing.country",
+
+	"cardnumber": "card.number", "ccnumber": "card.number", "creditcardnumber": "card.number",
+	"cardcvv": "card.cvv", "cvv": "card.cvv", "cvc": "card.cvv", "cardcvc": "card.cvv", "securitycode": "card.cvv",
+	"cardexpirymonth": "card.month", "expirymonth": "card.month", "expmonth": "card.month", "cardexpmonth": "card.month",
+	"cardexpiryyear": "card.year", "expiryyear": "card.year", "expyear": "card.year", "cardexpyear": "card.year",
+	"cardexpiry": "card.expiry", "expiry": "card.expiry", "expirationdate": "card.expiry", "expirydate": "card.expiry", "exp": "card.expiry",
+	"nameoncard": "card.name", "cardholder": "card.name", "cardholdername": "card.name", "cardname": "card.name",
+}
+
+// ReadCsv Reads profiles from a CSV file with a header row. Columns are matched by name, so both CsvHeader and common
+// bot layouts can be read. Unknown columns are ignored. Profiles aren't validated.
+func ReadCsv(r io.Reader) ([]Profile, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return []Profile{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, len(header))
+	known := false
+
+	for i, column := range header {
+		fields[i] = csvColumns[normalizeCsvColumn(column)]
+		known = known || fields[i] != ""
+	}
+
+	if !known {
+		return nil, errors.New("line 1: no known profile columns in header")
+	}
+
+	profiles := []Profile{}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		values := map[string]string{}
+		empty := true
+
+		for i, value := range record {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+
+			values[fields[i]] = strings.TrimSpace(value)
+			empty = empty && values[fields[i]] == ""
+		}
+
+		if empty {
+			continue
+		}
+
+		p, err := profileFromCsv(values)
+
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("line %v: %v", line, err))
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	return profiles, nil
+}
+
+// WriteCsv Writes profiles to a CSV file with CsvHeader as the header row
+func WriteCsv(w io.Writer, profiles []*Profile) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(CsvHeader); err != nil {
+		return err
+	}
+
+	for _, p := range profiles {
+		s, b, c := p.ShippingAddress, p.BillingAddress, p.CreditCard
+
+		record := []string{
+			p.Name, s.Email, s.Phone,
+			s.Name, s.Line1, s.Line2, s.City, s.State, s.PostCode, s.Country,
+			strconv.FormatBool(p.SameBillingAddressAsShipping),
+			b.Name, b.Email, b.Phone,
+			b.Line1, b.Line2, b.City, b.State, b.PostCode, b.Country,
+			c.Number, c.CVV, strconv.Itoa(c.ExpiryMonth), strconv.Itoa(c.ExpiryYear),
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportCsv Validates and adds every profile in a CSV file. Nothing is added if any profile is invalid or its name is
+// already taken. Returns the amount of profiles added.
+func (s *Store) ImportCsv(r io.Reader) (int, error) {
+	imported, err := ReadCsv(r)
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	names := map[string]struct{}{}
+
+	for _, p := range s.profiles {
+		names[p.Name] = struct{}{}
+	}
+
+	for i := range imported {
+		p := &imported[i]
+
+		if err := p.Validate(); err != nil {
+			return 0, errors.New(fmt.Sprintf("profile %v: %v", profileLabel(p, i), err))
+		}
+
+		if _, ok := names[p.Name]; ok {
+			return 0, errors.New(fmt.Sprintf("profile %v: duplicate name", p.Name))
+		}
+
+		names[p.Name] = struct{}{}
+	}
+
+	for i := range imported {
+		s.profiles = append(s.profiles, &imported[i])
+	}
+
+	return len(imported), nil
+}
+
+// ExportCsv Writes every profile in the store to a CSV file
+func (s *Store) ExportCsv(w io.Writer) error {
+	return WriteCsv(w, s.All())
+}
+
+// Creates a profile from a row of CSV values keyed by field
+func profileFromCsv(values map[string]string) (Profile, error) {
+	p := Profile{Name: values["name"]}
+	p.ShippingAddress = addressFromCsv(values, "shipping")
+	p.BillingAddress = addressFromCsv(values, "billing")
+
+	// Bots usually only export contact details once, so a separate billing address shares the shipping address's.
+	if p.BillingAddress.Line1 != "" {
+		if p.BillingAddress.Name == "" {
+			p.BillingAddress.Name = values["card.name"]
+		}
+
+		if p.BillingAddress.Email == "" {
+			p.BillingAddress.Email = p.ShippingAddress.Email
+		}
+
+		if p.BillingAddress.Phone == "" {
+			p.BillingAddress.Phone = p.ShippingAddress.Phone
+		}
+	}
+
+	// Without a column saying otherwise, a profile without a billing address bills to its shipping address.
+	if same, ok := values["same_billing"]; ok && same != "" {
+		parsed, err := parseCsvBool(same)
+
+		if err != nil {
+			return p, err
+		}
+
+		p.SameBillingAddressAsShipping = parsed
+	} else {
+		p.SameBillingAddressAsShipping = p.BillingAddress.Line1 == ""
+	}
+
+	p.CreditCard.Number = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+
+		return r
+	}, values["card.number"])
+
+	p.CreditCard.CVV = values["card.cvv"]
+
+	month, year := values["card.month"], values["card.year"]
+
+	if expiry := values["card.expiry"]; expiry != "" && (month == "" || year == "") {
+		parts := strings.FieldsFunc(expiry, func(r rune) bool { return r == '/' || r == '-' || r == ' ' })
+
+		if len(parts) != 2 {
+			return p, errors.New(fmt.Sprintf("invalid card expiry %q", expiry))
+		}
+
+		month, year = parts[0], parts[1]
+	}
+
+	var err error
+
+	if month != "" {
+		if p.CreditCard.ExpiryMonth, err = strconv.Atoi(month); err != nil {
+			return p, errors.New(fmt.Sprintf("invalid card expiry month %q", month))
+		}
+	}
+
+	if year != "" {
+		if p.CreditCard.ExpiryYear, err = strconv.Atoi(year); err != nil {
+			return p, errors.New(fmt.Sprintf("invalid card expiry year %q", year))
+		}
+
+		// Two digit years, ex. 04/28
+		if p.CreditCard.ExpiryYear < 100 {
+			p.CreditCard.ExpiryYear += 2000
+		}
+	}
+
+	return p, nil
+}
+
+// Creates an address from the CSV values with the given prefix ("shipping" or "billing")
+func addressFromCsv(values map[string]string, prefix string) Address {
+	get := func(field string) string {
+		return values[prefix+"."+field]
+	}
+
+	a := Address{
+		Name:     get("name"),
+		Email:    get("email"),
+		Phone:    get("phone"),
+		Line1:    get("line1"),
+		Line2:    get("line2"),
+		PostCode: get("post_code"),
+		City:     get("city"),
+		Country:  get("country"),
+		State:    get("state"),
+	}
+
+	if a.Name == "" {
+		a.Name = strings.TrimSpace(get("first") + " " + get("last"))
+	}
+
+	return a
+}
+
+// Parses the booleans bots export with, ex. "true", "TRUE", "yes", "1"
+func parseCsvBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	}
+
+	return false, errors.New(fmt.Sprintf("invalid boolean %q", s))
+}
+
+// Lower cases a column name and removes everything but letters and numbers, ex. "Shipping Zip-Code" -> "shippingzipcode"
+func normalizeCsvColumn(column string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+
+		return -1
+	}, column)
+}
@@ -0,0 +1,114 @@
+package profiles
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests exporting profiles to CSV and importing them into another store
+func TestCsvRoundTrip(t *testing.T) {
+	store := NewStore()
+	billing := newTestProfile("Billing")
+	billing.SameBillingAddressAsShipping = false
+	billing.BillingAddress = Address{
+		Name:     "J. Appleseed",
+		Email:    "jappleseed124@gmail.com",
+		Phone:    "1234567891",
+		Line1:    "1234 Parkway Ave",
+		Line2:    "Unit 2",
+		City:     "Brooklyn",
+		State:    "New York",
+		PostCode: "11201",
+		Country:  "United States",
+	}
+
+	for _, p := range []Profile{newTestProfile("Main"), billing} {
+		if err := store.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+
+	if err := store.ExportCsv(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewStore()
+	n, err := imported.ImportCsv(&b)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 profiles. got %v", n)
+	}
+
+	for _, original := range store.All() {
+		p, ok := imported.Get(original.Name)
+
+		if !ok || *p != *original {
+			t.Fatalf("expected %v to round trip.\nexpected %+v\ngot      %+v", original.Name, *original, p)
+		}
+	}
+}
+
+// Tests importing a layout with split names, a combined expiry and spaced card numbers
+func TestCsvImportBotLayout(t *testing.T) {
+	year := time.Now().Year()%100 + 2
+	data := "\ufeffProfile Name,Email Address,Phone Number,Shipping First Name,Shipping Last Name,Shipping Address,Shipping Address 2,Shipping City,Shipping Zip Code,Shipping State,Shipping Country,Billing Same As Shipping,Name On Card,Card Number,Expiry,CVV,Notes\n" +
+		fmt.Sprintf("Main,jappleseed124@gmail.com,1234567891,Johnny,Appleseeds,542 6th Ave,,New York,10011,NY,US,TRUE,Johnny Appleseeds,4111 1111 1111 1111,04/%v,123,ignored\n", year) +
+		",,,,,,,,,,,,,,,,\n"
+
+	store := NewStore()
+
+	if _, err := store.ImportCsv(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := store.Get("Main")
+
+	if !ok {
+		t.Fatalf("expected the Main profile. got %v", store.Names())
+	}
+
+	if p.ShippingAddress.Name != "Johnny Appleseeds" || p.ShippingAddress.PostCode != "10011" || !p.SameBillingAddressAsShipping {
+		t.Fatalf("unexpected shipping address %+v", p.ShippingAddress)
+	}
+
+	if p.CreditCard.Number != "4111111111111111" || p.CreditCard.ExpiryMonth != 4 || p.CreditCard.ExpiryYear != 2000+year {
+		t.Fatalf("unexpected card %+v", p.CreditCard)
+	}
+
+	if len(store.Names()) != 1 {
+		t.Fatalf("expected empty rows to be skipped. got %v", store.Names())
+	}
+}
+
+// Tests that nothing is imported when a row is invalid, and that errors point at the line
+func TestCsvImportInvalid(t *testing.T) {
+	tests := map[string]string{
+		"line 1: no known":          "foo,bar\n1,2\n",
+		"line 3: invalid card exp":  "profile_name,card_expiry_month\nMain,4\nBackup,April\n",
+		"profile Backup: credit":    "profile_name,email,phone,shipping_name,shipping_line1,shipping_city,shipping_post_code,shipping_country,card_number,card_cvv,card_expiry_month,card_expiry_year\nBackup,a@b.com,1,A B,1 St,City,1,US,4111111111111112,123,1,2099\n",
+		"line 2: invalid boolean":   "profile_name,same_billing\nMain,maybe\n",
+		"line 2: invalid card expi": "profile_name,card_expiry\nMain,0428\n",
+	}
+
+	for expected, data := range tests {
+		store := NewStore()
+		n, err := store.ImportCsv(strings.NewReader(data))
+
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Fatalf("expected an error starting with %q. got %v", expected, err)
+		}
+
+		if n != 0 || len(store.Names()) != 0 {
+			t.Fatalf("expected nothing to be imported. got %v", store.Names())
+		}
+	}
+}
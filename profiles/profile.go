@@ -1,11 +1,16 @@
 package profiles
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Profile struct {
-	Name                         string
-	ShippingAddress              Address
-	BillingAddress               Address
-	CreditCard                   Card
-	SameBillingAddressAsShipping bool
+	Name                         string  `json:"name"`
+	ShippingAddress              Address `json:"shipping_address"`
+	BillingAddress               Address `json:"billing_address"`
+	CreditCard                   Card    `json:"credit_card"`
+	SameBillingAddressAsShipping bool    `json:"same_billing_address_as_shipping"`
 }
 
 // GetBillingAddress Returns the address to be used for billing information
@@ -16,3 +21,26 @@ func (p *Profile) GetBillingAddress() Address {
 
 	return p.BillingAddress
 }
+
+// Validate Returns an error if the profile is missing anything needed to check out with it
+func (p *Profile) Validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if err := p.ShippingAddress.Validate(); err != nil {
+		return errors.New(fmt.Sprintf("shipping address: %v", err))
+	}
+
+	if !p.SameBillingAddressAsShipping {
+		if err := p.BillingAddress.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("billing address: %v", err))
+		}
+	}
+
+	if err := p.CreditCard.Validate(); err != nil {
+		return errors.New(fmt.Sprintf("credit card: %v", err))
+	}
+
+	return nil
+}
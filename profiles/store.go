@@ -0,0 +1,304 @@
+package profiles
+
+import (
+	"Mystery/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// Store Holds profiles by name and persists them to a JSON file. Stores with a passphrase encrypt the file, so
+// profiles are only ever decrypted in memory.
+type Store struct {
+	mutex    *sync.RWMutex
+	profiles []*Profile
+	path     string
+	key      *encryptionKey
+}
+
+// NewStore Creates an empty store that isn't backed by a file
+func NewStore() *Store {
+	return &Store{
+		mutex:    &sync.RWMutex{},
+		profiles: []*Profile{},
+	}
+}
+
+// LoadStore Creates a store from a JSON file of profiles. A file that doesn't exist yet results in an empty store
+// that is created on the first save. Returns ErrPassphraseRequired if the file is encrypted.
+func LoadStore(path string) (*Store, error) {
+	return LoadEncryptedStore(path, "")
+}
+
+// LoadEncryptedStore Creates a store from an encrypted profile file. Plaintext files and files that don't exist yet
+// are encrypted with the passphrase on the next save.
+func LoadEncryptedStore(path string, passphrase string) (*Store, error) {
+	s := NewStore()
+
+	if err := s.LoadEncrypted(path, passphrase); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load Replaces the profiles in the store with the ones in a JSON file and saves to that file from now on.
+// Every profile is validated, and nothing is replaced if any of them are invalid.
+func (s *Store) Load(path string) error {
+	return s.LoadEncrypted(path, "")
+}
+
+// LoadEncrypted Loads a profile file like Load, decrypting it with the passphrase. Loading never changes the file, so a
+// plaintext file stays plaintext until the store is saved. Without a passphrase, the store is saved in plaintext.
+func (s *Store) LoadEncrypted(path string, passphrase string) error {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var key *encryptionKey
+
+	if f := parseEncryptedFile(data); f != nil {
+		data, key, err = decryptFile(f, passphrase)
+
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+	} else if passphrase != "" {
+		if key, err = newEncryptionKey(passphrase); err != nil {
+			return err
+		}
+	}
+
+	loaded := []*Profile{}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return errors.New(fmt.Sprintf("parsing %v: %v", path, err))
+		}
+	}
+
+	names := map[string]struct{}{}
+
+	for i, p := range loaded {
+		if err := p.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("%v: profile %v: %v", path, profileLabel(p, i), err))
+		}
+
+		if _, ok := names[p.Name]; ok {
+			return errors.New(fmt.Sprintf("%v: profile %v: duplicate name", path, p.Name))
+		}
+
+		names[p.Name] = struct{}{}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.profiles = loaded
+	s.path = path
+	s.key = key
+	return nil
+}
+
+// Save Writes every profile to the file the store was loaded from
+func (s *Store) Save() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.path == "" {
+		return errors.New("profile store has no file to save to")
+	}
+
+	return s.save()
+}
+
+// SaveAs Writes every profile to a file and saves to that file from now on
+func (s *Store) SaveAs(path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.path = path
+	return s.save()
+}
+
+// IsEncrypted Returns if the store's file is encrypted
+func (s *Store) IsEncrypted() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.key != nil
+}
+
+// SetPassphrase Encrypts the store's file with a passphrase from the next save on. Stores that are already encrypted
+// have to use ChangePassphrase.
+func (s *Store) SetPassphrase(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	key, err := newEncryptionKey(passphrase)
+
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.key != nil {
+		return errors.New("profile store is already encrypted")
+	}
+
+	s.key = key
+	return nil
+}
+
+// ChangePassphrase Re-encrypts the store's file with a new passphrase. The current passphrase has to be given, even
+// though the profiles are already decrypted in memory.
+func (s *Store) ChangePassphrase(current string, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.key == nil {
+		return errors.New("profile store isn't encrypted")
+	}
+
+	if !s.key.matches(current) {
+		return ErrWrongPassphrase
+	}
+
+	key, err := newEncryptionKey(passphrase)
+
+	if err != nil {
+		return err
+	}
+
+	previous := s.key
+	s.key = key
+
+	if s.path == "" {
+		return nil
+	}
+
+	// Keep the old passphrase if the file couldn't be rewritten, since it's still what the file is encrypted with.
+	if err := s.save(); err != nil {
+		s.key = previous
+		return err
+	}
+
+	return nil
+}
+
+// Path Returns the file the store saves to
+func (s *Store) Path() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.path
+}
+
+// Get Returns the profile with the given name
+func (s *Store) Get(name string) (*Profile, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, p := range s.profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// All Returns every profile in the order they were added
+func (s *Store) All() []*Profile {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]*Profile{}, s.profiles...)
+}
+
+// Names Returns the name of every profile in the order they were added
+func (s *Store) Names() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := make([]string, len(s.profiles))
+
+	for i, p := range s.profiles {
+		names[i] = p.Name
+	}
+
+	return names
+}
+
+// Add Validates a profile and adds it to the store. Profiles must have unique names.
+func (s *Store) Add(p Profile) error {
+	if err := p.Validate(); err != nil {
+		return errors.New(fmt.Sprintf("profile %v: %v", profileLabel(&p, 0), err))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, existing := range s.profiles {
+		if existing.Name == p.Name {
+			return errors.New(fmt.Sprintf("profile %v: duplicate name", p.Name))
+		}
+	}
+
+	s.profiles = append(s.profiles, &p)
+	return nil
+}
+
+// Remove Removes the profile with the given name. Returns false if there was no such profile.
+func (s *Store) Remove(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, p := range s.profiles {
+		if p.Name == name {
+			s.profiles = append(s.profiles[:i], s.profiles[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Writes every profile to the store's file, encrypting it if the store has a passphrase. The file is replaced in one
+// step so readers never see a partial write.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.profiles, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if s.key != nil {
+		if data, err = s.key.encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	return utils.WriteFileAtomic(s.path, data)
+}
+
+// Returns the name of a profile for error messages, or its position if it has no name
+func profileLabel(p *Profile, i int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+
+	return fmt.Sprintf("%v", i+1)
+}
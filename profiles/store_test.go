@@ -0,0 +1,176 @@
+package profiles
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns a profile that passes validation
+func newTestProfile(name string) Profile {
+	return Profile{
+		Name: name,
+		ShippingAddress: Address{
+			Name:     "Johnny Appleseeds",
+			Email:    "jappleseed124@gmail.com",
+			Phone:    "1234567891",
+			Line1:    "542 6th Ave",
+			City:     "New York",
+			State:    "New York",
+			PostCode: "10011",
+			Country:  "United States",
+		},
+		CreditCard: Card{
+			Number:      "4111111111111111",
+			CVV:         "123",
+			ExpiryMonth: 4,
+			ExpiryYear:  time.Now().Year() + 2,
+		},
+		SameBillingAddressAsShipping: true,
+	}
+}
+
+// Tests the Luhn check against valid and invalid card numbers
+func TestIsLuhnValid(t *testing.T) {
+	tests := map[string]bool{
+		"4111111111111111":    true,
+		"5555555555554444":    true,
+		"378282246310005":     true,
+		"4111111111111112":    false,
+		"5555555555555555":    false,
+		"4111 1111 1111 1111": false,
+		"41111111111":         false,
+		"":                    false,
+	}
+
+	for number, valid := range tests {
+		if IsLuhnValid(number) != valid {
+			t.Fatalf("expected IsLuhnValid(%q) to be %v", number, valid)
+		}
+	}
+}
+
+// Tests that cards are valid until the end of their expiry month
+func TestCardExpiry(t *testing.T) {
+	now := time.Date(2026, time.April, 30, 12, 0, 0, 0, time.UTC)
+	card := Card{Number: "4111111111111111", CVV: "123", ExpiryMonth: 4, ExpiryYear: 2026}
+
+	if err := card.validate(now); err != nil {
+		t.Fatalf("expected the card to be valid through its expiry month. got %v", err)
+	}
+
+	if err := card.validate(now.AddDate(0, 0, 1)); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected the card to have expired. got %v", err)
+	}
+}
+
+// Tests that profiles missing required information are invalid
+func TestProfileValidate(t *testing.T) {
+	tests := map[string]func(p *Profile){
+		"name is required":              func(p *Profile) { p.Name = "" },
+		"shipping address: city":        func(p *Profile) { p.ShippingAddress.City = " " },
+		"shipping address: invalid":     func(p *Profile) { p.ShippingAddress.Email = "johnny" },
+		"billing address: name":         func(p *Profile) { p.SameBillingAddressAsShipping = false },
+		"credit card: invalid card":     func(p *Profile) { p.CreditCard.Number = "4111111111111112" },
+		"credit card: cvv":              func(p *Profile) { p.CreditCard.CVV = "12a" },
+		"credit card: invalid expiry":   func(p *Profile) { p.CreditCard.ExpiryMonth = 13 },
+		"credit card: card expired 04/": func(p *Profile) { p.CreditCard.ExpiryYear = 2020 },
+	}
+
+	valid := newTestProfile("Test")
+
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected the profile to be valid. got %v", err)
+	}
+
+	for expected, change := range tests {
+		p := newTestProfile("Test")
+		change(&p)
+
+		if err := p.Validate(); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Fatalf("expected an error starting with %q. got %v", expected, err)
+		}
+	}
+}
+
+// Tests saving profiles to a file and loading them back by name
+func TestStoreSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store, err := LoadStore(path)
+
+	if err != nil {
+		t.Fatalf("expected a missing file to load as an empty store. got %v", err)
+	}
+
+	for _, name := range []string{"Main", "Backup"} {
+		if err := store.Add(newTestProfile(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Add(newTestProfile("Main")); err == nil {
+		t.Fatal("expected duplicate names to be rejected")
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the file to only be readable by its owner. got %v", info.Mode().Perm())
+	}
+
+	loaded, err := LoadStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := strings.Join(loaded.Names(), ","); names != "Main,Backup" {
+		t.Fatalf("expected the profiles in the order they were added. got %v", names)
+	}
+
+	p, ok := loaded.Get("Backup")
+
+	if !ok || p.CreditCard.Number != "4111111111111111" || p.ShippingAddress.City != "New York" {
+		t.Fatalf("expected the Backup profile to round trip. got %+v", p)
+	}
+
+	if !loaded.Remove("Main") || loaded.Remove("Main") {
+		t.Fatal("expected Main to be removed once")
+	}
+
+	if _, ok := loaded.Get("Main"); ok {
+		t.Fatal("expected Main to be gone")
+	}
+}
+
+// Tests that a file with an invalid profile doesn't load
+func TestStoreLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	data := `[{"name": "Bad", "shipping_address": {"name": "Johnny"}, "same_billing_address_as_shipping": true}]`
+
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadStore(path); err == nil || !strings.Contains(err.Error(), "profile Bad: shipping address") {
+		t.Fatalf("expected the invalid profile to be reported. got %v", err)
+	}
+}
+
+// Tests that saving fails when the store has no file
+func TestStoreSaveWithoutFile(t *testing.T) {
+	if err := NewStore().Save(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,95 @@
+package proxies
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IsBanStatus Returns if a response status means the proxy has been blocked or rate limited by the site.
+// 430 is Shopify's status for requests it thinks are from a bot.
+func IsBanStatus(status int) bool {
+	return status == http.StatusForbidden || status == http.StatusTooManyRequests || status == 430
+}
+
+// CheckHealth Sends a request to the URL through every proxy at once. Proxies that can't connect or get a ban status
+// are marked as failed and the rest as working. Returns the amount of working proxies.
+func (pl *ProxyList) CheckHealth(ctx context.Context, checkUrl string, timeout time.Duration) int {
+	wg := &sync.WaitGroup{}
+	mutex := &sync.Mutex{}
+	healthy := 0
+
+	for _, proxy := range pl.Proxies {
+		wg.Add(1)
+
+		go func(proxy *Proxy) {
+			defer wg.Done()
+
+			if !checkProxy(ctx, proxy, checkUrl, timeout) {
+				// Checks cut short by stopping say nothing about the proxy
+				if ctx.Err() == nil {
+					pl.MarkFailure(proxy)
+				}
+
+				return
+			}
+
+			pl.MarkSuccess(proxy)
+
+			mutex.Lock()
+			healthy++
+			mutex.Unlock()
+		}(proxy)
+	}
+
+	wg.Wait()
+	return healthy
+}
+
+// StartHealthChecks Checks the health of every proxy now and then every interval until the returned function is called
+func (pl *ProxyList) StartHealthChecks(checkUrl string, interval time.Duration, timeout time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			pl.CheckHealth(ctx, checkUrl, timeout)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// Returns if a request to the URL through the proxy gets a response that isn't a ban
+func checkProxy(ctx context.Context, p *Proxy, checkUrl string, timeout time.Duration) bool {
+	client := &http.Client{
+		Transport: p.Transport(),
+		Timeout:   timeout,
+	}
+
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkUrl, nil)
+
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return false
+	}
+
+	resp.Body.Close()
+	return !IsBanStatus(resp.StatusCode)
+}
@@ -0,0 +1,50 @@
+package proxies
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Starts a server that acts as an HTTP proxy and answers every request with the status
+func newTestProxy(t *testing.T, status int) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			t.Errorf("expected the proxy to be sent an absolute URL. got %v", r.URL)
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// Tests that health checks mark proxies that can't connect or are banned as failed and the rest as working
+func TestCheckHealth(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	raw := newTestProxy(t, http.StatusOK) + "\n" +
+		newTestProxy(t, 430) + "\n" +
+		strings.TrimPrefix(closed.URL, "http://")
+
+	list := NewProxyList("Test", raw)
+
+	if healthy := list.CheckHealth(context.Background(), "http://example.com/health", time.Second); healthy != 1 {
+		t.Fatalf("expected 1 working proxy. got %v", healthy)
+	}
+
+	if list.IsBanned(list.Proxies[0]) || list.Health(list.Proxies[0]).Successes != 1 {
+		t.Fatalf("expected the working proxy to be marked as working. got %+v", list.Health(list.Proxies[0]))
+	}
+
+	for _, p := range list.Proxies[1:] {
+		if !list.IsBanned(p) {
+			t.Fatalf("expected proxy %v to be cooling down", p.Port)
+		}
+	}
+}
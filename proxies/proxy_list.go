@@ -1,16 +1,46 @@
 package proxies
 
 import (
+	"errors"
+	"math/rand"
 	"strings"
 	"sync"
+	"time"
 )
 
+type RotationStrategy string
+
+const (
+	StrategyRoundRobin RotationStrategy = "round_robin" // Proxies are used in order from top to bottom.
+	StrategyRandom     RotationStrategy = "random"
+	StrategyLru        RotationStrategy = "lru"    // The proxy that was used least recently is used next.
+	StrategySticky     RotationStrategy = "sticky" // Every task keeps its proxy until it fails, then moves to the next one in order.
+)
+
+// RotationStrategies Every strategy a proxy list can select proxies with
+var RotationStrategies = []RotationStrategy{StrategyRoundRobin, StrategyRandom, StrategyLru, StrategySticky}
+
+// DefaultCooldown How long a proxy isn't used for after it fails when the list doesn't set a cooldown
+const DefaultCooldown = time.Minute
+
 type ProxyList struct {
 	Name              string
 	List              string
 	Proxies           []*Proxy
 	CurrentProxyIndex int
 	Mutex             *sync.Mutex
+	Strategy          RotationStrategy // Defaults to round robin.
+	Cooldown          time.Duration    // How long a proxy isn't used for after it fails. Defaults to DefaultCooldown.
+	health            map[*Proxy]*ProxyHealth
+	sticky            map[string]*Proxy // The proxy of every task, by task ID, when the strategy is sticky.
+}
+
+// ProxyHealth How a proxy has been doing
+type ProxyHealth struct {
+	Failures    int // Failures since the proxy last worked.
+	Successes   int
+	LastUsed    time.Time
+	BannedUntil time.Time // The proxy isn't selected until this time unless every other proxy is cooling down too.
 }
 
 // NewProxyList Creates and returns a new parsed proxy list
@@ -25,40 +55,261 @@ func NewProxyList(name string, list string) ProxyList {
 	return pl
 }
 
-// SelectNextProxy Selects the next proxy in the list that is available to use.
-// ProxyLists go in order from top to bottom.
+// ParseProxyList Creates and returns a new parsed proxy list like NewProxyList, along with an error listing every
+// invalid line. The list holds the valid proxies even when there is an error.
+func ParseProxyList(name string, list string) (ProxyList, error) {
+	pl := ProxyList{
+		Name: name,
+		List: list,
+	}
+
+	lineErrors := pl.parse()
+
+	if len(lineErrors) == 0 {
+		return pl, nil
+	}
+
+	messages := make([]string, len(lineErrors))
+
+	for i, err := range lineErrors {
+		messages[i] = err.Error()
+	}
+
+	return pl, errors.New(strings.Join(messages, "; "))
+}
+
+// IsValidStrategy Returns if the strategy is known. An empty strategy is valid and means round robin.
+func IsValidStrategy(s RotationStrategy) bool {
+	if s == "" {
+		return true
+	}
+
+	for _, strategy := range RotationStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SelectNextProxy Selects the next proxy in the list that is available to use with the list's strategy.
+// Returns nil if the list is empty.
 func (pl *ProxyList) SelectNextProxy() *Proxy {
+	return pl.SelectProxy("")
+}
+
+// SelectProxy Selects a proxy for a task with the list's strategy. The key (ex. a task ID) is only used by the sticky
+// strategy. Proxies that are cooling down are skipped, unless every proxy is, in which case the one that recovers first
+// is used. Returns nil if the list is empty.
+func (pl *ProxyList) SelectProxy(key string) *Proxy {
 	if len(pl.Proxies) == 0 {
 		return nil
 	}
 
 	pl.Mutex.Lock()
 	defer pl.Mutex.Unlock()
-	
-	proxy := pl.Proxies[pl.CurrentProxyIndex]
-	pl.CurrentProxyIndex++
 
-	// Reset the index since the proxy list has already been exhausted
-	if pl.CurrentProxyIndex == len(pl.Proxies) {
-		pl.CurrentProxyIndex = 0
+	now := time.Now()
+	var proxy *Proxy
+
+	switch pl.Strategy {
+	case StrategyRandom:
+		proxy = pl.selectRandom(now)
+	case StrategyLru:
+		proxy = pl.selectLeastRecentlyUsed(now)
+	case StrategySticky:
+		if p, ok := pl.sticky[key]; ok && key != "" && !pl.isBanned(p, now) {
+			proxy = p
+		} else {
+			proxy = pl.selectRoundRobin(now)
+		}
+
+		if key != "" {
+			if pl.sticky == nil {
+				pl.sticky = map[string]*Proxy{}
+			}
+
+			pl.sticky[key] = proxy
+		}
+	default:
+		proxy = pl.selectRoundRobin(now)
 	}
 
+	pl.getHealth(proxy).LastUsed = now
 	return proxy
 }
 
-// Parses a raw proxy list
-func (pl *ProxyList) parse() {
+// Returns the next available proxy in order
+func (pl *ProxyList) selectRoundRobin(now time.Time) *Proxy {
+	if pl.CurrentProxyIndex >= len(pl.Proxies) {
+		pl.CurrentProxyIndex = 0
+	}
+
+	for i := 0; i < len(pl.Proxies); i++ {
+		proxy := pl.Proxies[pl.CurrentProxyIndex]
+		pl.CurrentProxyIndex++
+
+		// Reset the index since the proxy list has already been exhausted
+		if pl.CurrentProxyIndex == len(pl.Proxies) {
+			pl.CurrentProxyIndex = 0
+		}
+
+		if !pl.isBanned(proxy, now) {
+			return proxy
+		}
+	}
+
+	return pl.firstToRecover()
+}
+
+func (pl *ProxyList) selectRandom(now time.Time) *Proxy {
+	var available []*Proxy
+
+	for _, proxy := range pl.Proxies {
+		if !pl.isBanned(proxy, now) {
+			available = append(available, proxy)
+		}
+	}
+
+	if len(available) == 0 {
+		return pl.firstToRecover()
+	}
+
+	return available[rand.Intn(len(available))]
+}
+
+// Returns the available proxy that was used the longest time ago. Proxies that were never used come first.
+func (pl *ProxyList) selectLeastRecentlyUsed(now time.Time) *Proxy {
+	var selected *Proxy
+
+	for _, proxy := range pl.Proxies {
+		if pl.isBanned(proxy, now) {
+			continue
+		}
+
+		if selected == nil || pl.getHealth(proxy).LastUsed.Before(pl.getHealth(selected).LastUsed) {
+			selected = proxy
+		}
+	}
+
+	if selected == nil {
+		return pl.firstToRecover()
+	}
+
+	return selected
+}
+
+// Returns the proxy whose cooldown ends first
+func (pl *ProxyList) firstToRecover() *Proxy {
+	selected := pl.Proxies[0]
+
+	for _, proxy := range pl.Proxies[1:] {
+		if pl.getHealth(proxy).BannedUntil.Before(pl.getHealth(selected).BannedUntil) {
+			selected = proxy
+		}
+	}
+
+	return selected
+}
+
+// ReleaseProxy Forgets the proxy a task was given by the sticky strategy
+func (pl *ProxyList) ReleaseProxy(key string) {
+	if pl.Mutex == nil {
+		return
+	}
+
+	pl.Mutex.Lock()
+	defer pl.Mutex.Unlock()
+
+	delete(pl.sticky, key)
+}
+
+// MarkFailure Records that a request through the proxy failed (ex. a connection error or ban status) and cools the
+// proxy down
+func (pl *ProxyList) MarkFailure(p *Proxy) {
+	pl.Mutex.Lock()
+	defer pl.Mutex.Unlock()
+
+	health := pl.getHealth(p)
+	health.Failures++
+	health.BannedUntil = time.Now().Add(pl.cooldown())
+}
+
+// MarkSuccess Records that a request through the proxy worked, ending its cooldown
+func (pl *ProxyList) MarkSuccess(p *Proxy) {
+	pl.Mutex.Lock()
+	defer pl.Mutex.Unlock()
+
+	health := pl.getHealth(p)
+	health.Failures = 0
+	health.Successes++
+	health.BannedUntil = time.Time{}
+}
+
+// Health Returns how the proxy has been doing
+func (pl *ProxyList) Health(p *Proxy) ProxyHealth {
+	pl.Mutex.Lock()
+	defer pl.Mutex.Unlock()
+
+	return *pl.getHealth(p)
+}
+
+// IsBanned Returns if the proxy is cooling down after a failure
+func (pl *ProxyList) IsBanned(p *Proxy) bool {
+	pl.Mutex.Lock()
+	defer pl.Mutex.Unlock()
+
+	return pl.isBanned(p, time.Now())
+}
+
+func (pl *ProxyList) isBanned(p *Proxy, now time.Time) bool {
+	return now.Before(pl.getHealth(p).BannedUntil)
+}
+
+// Returns the health of a proxy, creating it if needed. The mutex must be held.
+func (pl *ProxyList) getHealth(p *Proxy) *ProxyHealth {
+	if pl.health == nil {
+		pl.health = map[*Proxy]*ProxyHealth{}
+	}
+
+	if pl.health[p] == nil {
+		pl.health[p] = &ProxyHealth{}
+	}
+
+	return pl.health[p]
+}
+
+func (pl *ProxyList) cooldown() time.Duration {
+	if pl.Cooldown > 0 {
+		return pl.Cooldown
+	}
+
+	return DefaultCooldown
+}
+
+// Parses a raw proxy list. Blank lines are skipped and invalid ones are left out and returned.
+func (pl *ProxyList) parse() []*LineError {
 	pl.Proxies = []*Proxy{}
 	pl.Mutex = &sync.Mutex{}
 	pl.CurrentProxyIndex = 0
 
-	for _, rawProxy := range strings.Split(strings.ReplaceAll(pl.List, "\r\n", "\n"), "\n") {
-		p := Parse(rawProxy)
+	var lineErrors []*LineError
 
-		if p.Host == "" {
+	for i, rawProxy := range strings.Split(strings.ReplaceAll(pl.List, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(rawProxy) == "" {
+			continue
+		}
+
+		p, err := ParseProxy(rawProxy)
+
+		if err != nil {
+			lineErrors = append(lineErrors, &LineError{Line: i + 1, Err: err})
 			continue
 		}
 
 		pl.Proxies = append(pl.Proxies, &p)
 	}
+
+	return lineErrors
 }
@@ -2,14 +2,16 @@ package proxies
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestParseProxyList(t *testing.T) {
 	raw := "127.0.0.1:8080:username:password\r\n" +
 		"192.68.1.1:1234\r\n" +
-		"255.255.255.255:0000:user:pass"
+		"255.255.255.255:8000:user:pass"
 
 	list := NewProxyList("Test", raw)
 
@@ -21,7 +23,7 @@ func TestParseProxyList(t *testing.T) {
 func TestSelectProxies(t *testing.T) {
 	raw := "127.0.0.1:8080:username:password\r\n" +
 		"192.68.1.1:1234\r\n" +
-		"255.255.255.255:0000:user:pass"
+		"255.255.255.255:8000:user:pass"
 
 	list := NewProxyList("Test", raw)
 
@@ -72,3 +74,138 @@ func TestSelectProxiesGoRoutines(t *testing.T) {
 
 	wg.Wait()
 }
+
+// Tests that an empty list selects no proxy instead of failing
+func TestSelectProxyEmptyList(t *testing.T) {
+	list := NewProxyList("Empty", "\r\n")
+
+	if p := list.SelectNextProxy(); p != nil {
+		t.Fatalf("expected no proxy. got %v", p)
+	}
+}
+
+// Tests that failed proxies are skipped until their cooldown ends or they work again
+func TestSelectProxySkipsBanned(t *testing.T) {
+	list := NewProxyList("Test", "127.0.0.1:1\n127.0.0.1:2\n127.0.0.1:3")
+	list.Cooldown = 50 * time.Millisecond
+	list.MarkFailure(list.Proxies[1])
+
+	for i, expected := range []int{0, 2, 0} {
+		if p := list.SelectNextProxy(); p != list.Proxies[expected] {
+			t.Fatalf("selection %v: expected proxy %v. got %v", i, expected, p.Port)
+		}
+	}
+
+	if !list.IsBanned(list.Proxies[1]) || list.Health(list.Proxies[1]).Failures != 1 {
+		t.Fatalf("expected the failed proxy to be cooling down. got %+v", list.Health(list.Proxies[1]))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if p := list.SelectNextProxy(); p != list.Proxies[1] || list.IsBanned(p) {
+		t.Fatalf("expected the proxy to be used again after its cooldown. got %v", p.Port)
+	}
+
+	list.Cooldown = time.Hour
+	list.MarkFailure(list.Proxies[0])
+	list.MarkFailure(list.Proxies[2])
+	list.MarkFailure(list.Proxies[1])
+
+	if p := list.SelectNextProxy(); p != list.Proxies[0] {
+		t.Fatalf("expected the proxy that recovers first when every proxy is banned. got %v", p.Port)
+	}
+
+	list.MarkSuccess(list.Proxies[2])
+
+	if p := list.SelectNextProxy(); p != list.Proxies[2] || list.Health(p).Failures != 0 {
+		t.Fatalf("expected the proxy that worked again to be used. got %v", p.Port)
+	}
+}
+
+// Tests selecting proxies with every strategy other than round robin
+func TestSelectProxyStrategies(t *testing.T) {
+	list := NewProxyList("Test", "127.0.0.1:1\n127.0.0.1:2\n127.0.0.1:3")
+	list.Strategy = StrategyLru
+
+	first := list.SelectNextProxy()
+	second := list.SelectNextProxy()
+	third := list.SelectNextProxy()
+
+	if first == second || second == third || first == third {
+		t.Fatalf("expected every proxy to be used once before any is reused")
+	}
+
+	time.Sleep(time.Millisecond)
+	list.SelectProxy("")
+
+	if p := list.SelectNextProxy(); p != second {
+		t.Fatalf("expected the least recently used proxy. got %v", p.Port)
+	}
+
+	list.Strategy = StrategySticky
+	a := list.SelectProxy("a")
+	b := list.SelectProxy("b")
+
+	if a == b || list.SelectProxy("a") != a || list.SelectProxy("b") != b {
+		t.Fatalf("expected every task to keep its own proxy")
+	}
+
+	list.MarkFailure(a)
+
+	if p := list.SelectProxy("a"); p == a || p != list.SelectProxy("a") {
+		t.Fatalf("expected the task to move to a new proxy after its proxy failed and keep it")
+	}
+
+	list.ReleaseProxy("b")
+
+	if list.SelectProxy("b") == nil {
+		t.Fatalf("expected a released task to get a new proxy")
+	}
+
+	list.Strategy = StrategyRandom
+	list.MarkSuccess(a)
+	list.MarkFailure(list.Proxies[0])
+	list.MarkFailure(list.Proxies[1])
+
+	for i := 0; i < 10; i++ {
+		if p := list.SelectNextProxy(); p != list.Proxies[2] {
+			t.Fatalf("expected only the proxy that isn't banned. got %v", p.Port)
+		}
+	}
+}
+
+// Tests which strategies are accepted
+func TestIsValidStrategy(t *testing.T) {
+	for _, s := range []RotationStrategy{"", StrategyRoundRobin, StrategyRandom, StrategyLru, StrategySticky} {
+		if !IsValidStrategy(s) {
+			t.Fatalf("expected %q to be valid", s)
+		}
+	}
+
+	if IsValidStrategy("fastest") {
+		t.Fatal("expected an unknown strategy to be invalid")
+	}
+}
+
+// Tests that invalid lines are reported with their line number and left out of the list
+func TestParseProxyListErrors(t *testing.T) {
+	raw := "127.0.0.1:8080\r\n" +
+		"\r\n" +
+		"127.0.0.1\r\n" +
+		"socks5://[::1]:1080\r\n" +
+		"ftp://127.0.0.1:21"
+
+	list, err := ParseProxyList("Test", raw)
+
+	if err == nil || !strings.Contains(err.Error(), "line 3: missing port") || !strings.Contains(err.Error(), "line 5: unsupported scheme") {
+		t.Fatalf("expected errors on lines 3 and 5. got %v", err)
+	}
+
+	if len(list.Proxies) != 2 || list.Proxies[1].Scheme != SchemeSocks5 {
+		t.Fatalf("expected the 2 valid proxies. got %v", len(list.Proxies))
+	}
+
+	if _, err := ParseProxyList("Test", "127.0.0.1:8080\n[::1]:8080\n"); err != nil {
+		t.Fatalf("expected no error. got %v", err)
+	}
+}
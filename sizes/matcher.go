@@ -0,0 +1,163 @@
+package sizes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Matcher Matches variant options against a list of size specs. A spec is a size ("10.5", "EU 44", "XL"), a range
+// ("8-10.5", "US 8 - 10", "S-L") or a comma separated list of both. Sizes in another system are converted with the
+// size chart before they are compared, so "9" matches "EU 42.5". Specs that aren't sizes, ex. "One Size", match
+// options with the same text.
+type Matcher struct {
+	specs []spec
+}
+
+// A size or range of sizes. Both ends are the same size for a single size.
+type spec struct {
+	text string // Lowercase, for options that aren't sizes.
+	low  Size
+	high Size
+}
+
+// NewMatcher Parses the size specs. A matcher without specs matches every option.
+func NewMatcher(specs []string) (*Matcher, error) {
+	m := &Matcher{}
+
+	for _, list := range specs {
+		for _, item := range strings.Split(list, ",") {
+			s, err := parseSpec(item)
+
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid size %q: %v", strings.TrimSpace(item), err))
+			}
+
+			m.specs = append(m.specs, s)
+		}
+	}
+
+	return m, nil
+}
+
+// Empty Returns if the matcher has no specs and matches every option
+func (m *Matcher) Empty() bool {
+	return len(m.specs) == 0
+}
+
+// Match Returns if the variant option holds a size within any of the specs
+func (m *Matcher) Match(option string) bool {
+	return m.Empty() || m.Rank(option) < len(m.specs)
+}
+
+// Rank Returns the index of the first spec the variant option matches, or the amount of specs if it matches none.
+// Every option is ranked 0 by a matcher without specs.
+func (m *Matcher) Rank(option string) int {
+	if m.Empty() {
+		return 0
+	}
+
+	text := strings.ToLower(strings.TrimSpace(option))
+	optionSizes := ParseSizes(option)
+
+	for i, s := range m.specs {
+		if s.text == text {
+			return i
+		}
+
+		for _, size := range optionSizes {
+			if s.contains(size) {
+				return i
+			}
+		}
+	}
+
+	return len(m.specs)
+}
+
+// Returns if the size is within the spec, converting it to the spec's system if needed
+func (s *spec) contains(size Size) bool {
+	// Specs that aren't sizes only match by text
+	if s.low == (Size{}) {
+		return false
+	}
+
+	if s.low.Letter != "" || size.Letter != "" {
+		i := letterIndex(size.Letter)
+		return s.low.Letter != "" && i >= letterIndex(s.low.Letter) && i <= letterIndex(s.high.Letter)
+	}
+
+	if s.low.Suffix != size.Suffix {
+		return false
+	}
+
+	for _, v := range Convert(size.Value, size.System, s.low.System) {
+		if v > s.low.Value-0.02 && v < s.high.Value+0.02 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parses a single size or a range of sizes. Specs that aren't sizes are kept as text.
+func parseSpec(item string) (spec, error) {
+	item = strings.TrimSpace(item)
+
+	if item == "" {
+		return spec{}, errors.New("empty size")
+	}
+
+	ends := strings.Split(item, "-")
+	var low, high Size
+	var lowOk, highOk bool
+
+	if len(ends) == 2 {
+		low, lowOk = ParseSize(ends[0])
+		high, highOk = ParseSize(ends[1])
+	}
+
+	if !lowOk || !highOk {
+		if size, ok := ParseSize(item); ok {
+			return spec{text: strings.ToLower(item), low: size, high: size}, nil
+		}
+
+		if lowOk || highOk {
+			return spec{}, errors.New("a range needs a size on both ends")
+		}
+
+		return spec{text: strings.ToLower(item)}, nil
+	}
+
+	// The system only has to be written once, ex. "EU 42-44" or "8-10 UK"
+	if hasSystem(ends[0]) && !hasSystem(ends[1]) {
+		high.System = low.System
+	} else if hasSystem(ends[1]) && !hasSystem(ends[0]) {
+		low.System = high.System
+	}
+
+	if (low.Letter == "") != (high.Letter == "") || low.System != high.System || low.Suffix != high.Suffix {
+		return spec{}, errors.New("both ends of a range need the same kind of size")
+	}
+
+	if low.Letter != "" && letterIndex(low.Letter) > letterIndex(high.Letter) || low.Value > high.Value {
+		return spec{}, errors.New("the range starts after it ends")
+	}
+
+	return spec{text: strings.ToLower(item), low: low, high: high}, nil
+}
+
+// Returns if a size is written with its system, ex. "EU 44"
+func hasSystem(s string) bool {
+	for _, word := range wordRegex.FindAllString(strings.ToLower(s), -1) {
+		if _, ok := systemWords[word]; ok {
+			return true
+		}
+
+		if _, ok := womenWords[word]; ok {
+			return true
+		}
+	}
+
+	return false
+}
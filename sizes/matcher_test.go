@@ -0,0 +1,90 @@
+package sizes
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests matching variant options against size specs
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		specs    []string
+		option   string
+		expected bool
+	}{
+		{nil, "Black", true},
+		{[]string{"10"}, "10", true},
+		{[]string{"10"}, "US 10", true},
+		{[]string{"10"}, "10.5", false},
+		{[]string{"10"}, "US 10 / EU 44", true},
+		{[]string{"10"}, "EU 44", true},
+		{[]string{"10"}, "UK 9", true},
+		{[]string{"10"}, "100", false},
+		{[]string{"10"}, "10C", false},
+		{[]string{"10"}, "W 11.5", true},
+		{[]string{"10.5"}, "10.5W", false},
+		{[]string{"W 10.5"}, "10.5W", true},
+		{[]string{"EU 44"}, "US 10", true},
+		{[]string{"EU 44"}, "44", false},
+		{[]string{"EU 44 2/3"}, "EU 44 2/3", true},
+		{[]string{"8-10.5"}, "9.5", true},
+		{[]string{"8-10.5"}, "EU 45", false},
+		{[]string{"8 - 10.5"}, "EU 42", true},
+		{[]string{"EU 42-44"}, "US 9.5", true},
+		{[]string{"EU 42-44"}, "EU 43 1/3", true},
+		{[]string{"8-10 UK"}, "US 10.5", true},
+		{[]string{"9, 11"}, "11", true},
+		{[]string{"9, 11"}, "10", false},
+		{[]string{"9", "11-12"}, "11.5", true},
+		{[]string{"5Y"}, "5Y", true},
+		{[]string{"5Y"}, "5", false},
+		{[]string{"M"}, "Medium", true},
+		{[]string{"S-L"}, "M", true},
+		{[]string{"S-L"}, "XL", false},
+		{[]string{"XL-3XL"}, "XXL", true},
+		{[]string{"L"}, "XL", false},
+		{[]string{"L"}, "10", false},
+		{[]string{"One Size"}, "ONE SIZE", true},
+		{[]string{"One-Size"}, "One-Size", true},
+		{[]string{"One Size"}, "M", false},
+		{[]string{"Black"}, "US 9 / EU 42.5", false},
+	}
+
+	for _, test := range tests {
+		m, err := NewMatcher(test.specs)
+
+		if err != nil {
+			t.Fatalf("%v: %v", test.specs, err)
+		}
+
+		if m.Match(test.option) != test.expected {
+			t.Fatalf("%v: expected matching %q to be %v", test.specs, test.option, test.expected)
+		}
+	}
+}
+
+// Tests that specs that can't be matched return an error naming the spec
+func TestNewMatcherErrors(t *testing.T) {
+	tests := map[string]string{
+		"":           "empty size",
+		"9,":         "empty size",
+		"8-":         "a range needs a size on both ends",
+		"10-8":       "the range starts after it ends",
+		"L-S":        "the range starts after it ends",
+		"US 8-EU 44": "both ends of a range need the same kind of size",
+		"8-L":        "both ends of a range need the same kind of size",
+		"5Y-7":       "both ends of a range need the same kind of size",
+	}
+
+	for spec, expected := range tests {
+		m, err := NewMatcher([]string{spec})
+
+		if err == nil || !strings.HasSuffix(err.Error(), expected) {
+			t.Fatalf("%q: expected an error ending with %q. got %v", spec, expected, err)
+		}
+
+		if m != nil {
+			t.Fatalf("%q: expected no matcher with the error", spec)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package sizes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OptionNames Returns the names of a product's options. Shopify lists them as objects with a name in /products.json
+// and as plain names in <product>.js.
+func OptionNames(options []interface{}) []string {
+	names := make([]string, len(options))
+
+	for i, option := range options {
+		switch o := option.(type) {
+		case string:
+			names[i] = o
+		case map[string]interface{}:
+			names[i] = fmt.Sprintf("%v", o["name"])
+		}
+	}
+
+	return names
+}
+
+// DetectOption Returns which option holds the size, from the option names and the values of every option of every
+// variant. An option named like a size wins, otherwise it's the option whose values are most often sizes. Returns -1
+// if no option looks like a size.
+func DetectOption(names []string, variantOptions [][]string) int {
+	for i, name := range names {
+		if strings.Contains(strings.ToLower(name), "size") {
+			return i
+		}
+	}
+
+	best, bestCount := -1, 0
+
+	for i := 0; i < optionCount(variantOptions); i++ {
+		count := 0
+
+		for _, options := range variantOptions {
+			if i < len(options) && len(ParseSizes(options[i])) > 0 {
+				count++
+			}
+		}
+
+		// More than half of the values have to be sizes so a color like "S/M" on one variant isn't mistaken for it
+		if count*2 > len(variantOptions) && count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+
+	return best
+}
+
+func optionCount(variantOptions [][]string) int {
+	count := 0
+
+	for _, options := range variantOptions {
+		if len(options) > count {
+			count = len(options)
+		}
+	}
+
+	return count
+}
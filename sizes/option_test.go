@@ -0,0 +1,39 @@
+package sizes
+
+import (
+	"testing"
+)
+
+// Tests finding the option that holds the size
+func TestDetectOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		options  [][]string
+		expected int
+	}{
+		{"named size", []string{"Color", "Shoe Size"}, [][]string{{"Black", "Black"}}, 1},
+		{"sizes in the second option", nil, [][]string{{"Black", "9"}, {"Black", "10"}, {"White", "10.5"}}, 1},
+		{"sizes in the first option", []string{"Title", "Color"}, [][]string{{"S", "Black"}, {"M", "Black"}}, 0},
+		{"mostly not sizes", nil, [][]string{{"Black", "Cotton"}, {"S/M", "Wool"}, {"White", "Cotton"}}, -1},
+		{"no variants", nil, nil, -1},
+	}
+
+	for _, test := range tests {
+		if option := DetectOption(test.names, test.options); option != test.expected {
+			t.Fatalf("%v: expected option %v. got %v", test.name, test.expected, option)
+		}
+	}
+}
+
+// Tests reading option names from /products.json and <product>.js
+func TestOptionNames(t *testing.T) {
+	products := []interface{}{map[string]interface{}{"name": "Color", "position": 1.0}, map[string]interface{}{"name": "Size"}}
+	js := []interface{}{"Color", "Size"}
+
+	for _, options := range [][]interface{}{products, js} {
+		if names := OptionNames(options); len(names) != 2 || names[0] != "Color" || names[1] != "Size" {
+			t.Fatalf("expected Color and Size. got %v", names)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package sizes
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type System string
+
+const (
+	SystemUS      System = "us" // Men's US sizes. Sizes without a system are US.
+	SystemUSWomen System = "us_women"
+	SystemUK      System = "uk"
+	SystemEU      System = "eu"
+	SystemCM      System = "cm"
+)
+
+// Size A size read from a variant option or size spec, ex. "US 10.5", "44 2/3" or "XL"
+type Size struct {
+	System System
+	Value  float64
+	Letter string // The normalized clothing size, ex. "xxl" for "2XL". Value is 0 when set.
+	Suffix string // What is left after the size, ex. "y" for youth or "gs". Sizes only match sizes with the same suffix.
+}
+
+// The men's sneaker size chart sizes are converted with. Brands differ by half a size at most.
+var conversionTable = []map[System]float64{
+	{SystemUS: 3.5, SystemUK: 3, SystemEU: 35.5, SystemCM: 22.5},
+	{SystemUS: 4, SystemUK: 3.5, SystemEU: 36, SystemCM: 23},
+	{SystemUS: 4.5, SystemUK: 4, SystemEU: 36.5, SystemCM: 23.5},
+	{SystemUS: 5, SystemUK: 4.5, SystemEU: 37.5, SystemCM: 23.5},
+	{SystemUS: 5.5, SystemUK: 5, SystemEU: 38, SystemCM: 24},
+	{SystemUS: 6, SystemUK: 5.5, SystemEU: 38.5, SystemCM: 24},
+	{SystemUS: 6.5, SystemUK: 6, SystemEU: 39, SystemCM: 24.5},
+	{SystemUS: 7, SystemUK: 6, SystemEU: 40, SystemCM: 25},
+	{SystemUS: 7.5, SystemUK: 6.5, SystemEU: 40.5, SystemCM: 25.5},
+	{SystemUS: 8, SystemUK: 7, SystemEU: 41, SystemCM: 26},
+	{SystemUS: 8.5, SystemUK: 7.5, SystemEU: 42, SystemCM: 26.5},
+	{SystemUS: 9, SystemUK: 8, SystemEU: 42.5, SystemCM: 27},
+	{SystemUS: 9.5, SystemUK: 8.5, SystemEU: 43, SystemCM: 27.5},
+	{SystemUS: 10, SystemUK: 9, SystemEU: 44, SystemCM: 28},
+	{SystemUS: 10.5, SystemUK: 9.5, SystemEU: 44.5, SystemCM: 28.5},
+	{SystemUS: 11, SystemUK: 10, SystemEU: 45, SystemCM: 29},
+	{SystemUS: 11.5, SystemUK: 10.5, SystemEU: 45.5, SystemCM: 29.5},
+	{SystemUS: 12, SystemUK: 11, SystemEU: 46, SystemCM: 30},
+	{SystemUS: 12.5, SystemUK: 11.5, SystemEU: 47, SystemCM: 30.5},
+	{SystemUS: 13, SystemUK: 12, SystemEU: 47.5, SystemCM: 31},
+	{SystemUS: 14, SystemUK: 13, SystemEU: 48.5, SystemCM: 32},
+	{SystemUS: 15, SystemUK: 14, SystemEU: 49.5, SystemCM: 33},
+}
+
+// Women's US sizes are this much bigger than men's US sizes
+const womenOffset = 1.5
+
+// Clothing sizes from smallest to largest, with the ways sites write them
+var letterSizes = []struct {
+	letter  string
+	aliases []string
+}{
+	{"xxs", []string{"2xs", "xxsmall"}},
+	{"xs", []string{"xsmall", "extrasmall"}},
+	{"s", []string{"small", "sm"}},
+	{"m", []string{"medium", "med", "md"}},
+	{"l", []string{"large", "lg"}},
+	{"xl", []string{"xlarge", "extralarge"}},
+	{"xxl", []string{"2xl", "xxlarge"}},
+	{"xxxl", []string{"3xl", "xxxlarge"}},
+}
+
+var systemWords = map[string]System{
+	"us":  SystemUS,
+	"usa": SystemUS,
+	"uk":  SystemUK,
+	"eu":  SystemEU,
+	"eur": SystemEU,
+	"fr":  SystemEU,
+	"cm":  SystemCM,
+	"jp":  SystemCM,
+}
+
+var menWords = map[string]struct{}{"m": {}, "men": {}, "mens": {}, "male": {}}
+
+var womenWords = map[string]struct{}{"w": {}, "wmn": {}, "wmns": {}, "women": {}, "womens": {}, "ladies": {}}
+
+var (
+	fractionRegex = regexp.MustCompile(`(\d+)(?:\s+(1/3|1/2|2/3)|\s*(⅓|½|⅔))`)
+	numberRegex   = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+	wordRegex     = regexp.MustCompile(`[a-z]+`)
+)
+
+// ParseSizes Returns every size in a variant option, ex. both sizes of "US 10 / EU 44". Parts that aren't sizes are
+// left out.
+func ParseSizes(s string) []Size {
+	s = replaceFractions(s)
+	var found []Size
+
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '|' || r == ';' }) {
+		if size, ok := ParseSize(part); ok {
+			found = append(found, size)
+		}
+	}
+
+	return found
+}
+
+// ParseSize Parses a single size, ex. "10.5", "EU 44", "10.5W", "5Y" or "XL". Returns false if it isn't one.
+func ParseSize(s string) (Size, bool) {
+	s = strings.TrimSpace(strings.ToLower(replaceFractions(s)))
+	s = strings.TrimSpace(strings.TrimPrefix(s, "size"))
+
+	if letter := parseLetter(s); letter != "" {
+		return Size{Letter: letter}, true
+	}
+
+	numbers := numberRegex.FindAllString(s, -1)
+
+	// A dash is a range, ex. "44-45", which isn't a single size
+	if len(numbers) != 1 || strings.Contains(s, "-") {
+		return Size{}, false
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(numbers[0], ",", ".", 1), 64)
+
+	if err != nil {
+		return Size{}, false
+	}
+
+	size := Size{Value: value}
+	women := false
+	rest := strings.NewReplacer("'", "", "’", "", ".", "").Replace(strings.Replace(s, numbers[0], " ", 1))
+
+	for _, word := range wordRegex.FindAllString(rest, -1) {
+		if system, ok := systemWords[word]; ok && size.System == "" {
+			size.System = system
+		} else if _, ok := womenWords[word]; ok {
+			women = true
+		} else if _, ok := menWords[word]; !ok {
+			size.Suffix += word
+		}
+	}
+
+	if size.System == "" {
+		size.System = SystemUS
+	}
+
+	if women && size.System == SystemUS {
+		size.System = SystemUSWomen
+	}
+
+	return size, true
+}
+
+// Convert Returns the value of a size in another system. Sizes that aren't on the size chart can't be converted.
+// Returns more than one value when the size chart has the same size in the system more than once.
+func Convert(value float64, from System, to System) []float64 {
+	if from == to {
+		return []float64{value}
+	}
+
+	if from == SystemUSWomen {
+		return Convert(value-womenOffset, SystemUS, to)
+	}
+
+	if to == SystemUSWomen {
+		var converted []float64
+
+		for _, v := range Convert(value, from, SystemUS) {
+			converted = append(converted, v+womenOffset)
+		}
+
+		return converted
+	}
+
+	var converted []float64
+
+	for _, row := range conversionTable {
+		if equal(row[from], value) {
+			converted = append(converted, row[to])
+		}
+	}
+
+	return converted
+}
+
+// Returns the normalized clothing size, or an empty string if it isn't one
+func parseLetter(s string) string {
+	compact := strings.NewReplacer(" ", "", "-", "", ".", "").Replace(s)
+
+	for _, l := range letterSizes {
+		if compact == l.letter {
+			return l.letter
+		}
+
+		for _, alias := range l.aliases {
+			if compact == alias {
+				return l.letter
+			}
+		}
+	}
+
+	return ""
+}
+
+// Returns where a clothing size is from smallest to largest
+func letterIndex(letter string) int {
+	for i, l := range letterSizes {
+		if l.letter == letter {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Replaces fractions of EU sizes, ex. "44 2/3", with decimals. A fraction needs a space before it so "11/12" is left alone.
+func replaceFractions(s string) string {
+	return fractionRegex.ReplaceAllStringFunc(s, func(match string) string {
+		groups := fractionRegex.FindStringSubmatch(match)
+		whole, _ := strconv.ParseFloat(groups[1], 64)
+
+		switch groups[2] + groups[3] {
+		case "1/3", "⅓":
+			whole += 1.0 / 3
+		case "1/2", "½":
+			whole += 0.5
+		case "2/3", "⅔":
+			whole += 2.0 / 3
+		}
+
+		return strconv.FormatFloat(math.Round(whole*100)/100, 'f', -1, 64)
+	})
+}
+
+// Sizes with thirds are rounded so they can be compared to what was parsed
+func equal(a float64, b float64) bool {
+	return math.Abs(a-b) < 0.02
+}
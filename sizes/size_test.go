@@ -0,0 +1,112 @@
+package sizes
+
+import (
+	"testing"
+)
+
+// Tests reading sizes the way sites write them
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Size
+		ok       bool
+	}{
+		{"10", Size{System: SystemUS, Value: 10}, true},
+		{"10.5", Size{System: SystemUS, Value: 10.5}, true},
+		{"US 10.5", Size{System: SystemUS, Value: 10.5}, true},
+		{"US M 9", Size{System: SystemUS, Value: 9}, true},
+		{"Men's 9", Size{System: SystemUS, Value: 9}, true},
+		{"10.5W", Size{System: SystemUSWomen, Value: 10.5}, true},
+		{"Women's US 8", Size{System: SystemUSWomen, Value: 8}, true},
+		{"EU 44", Size{System: SystemEU, Value: 44}, true},
+		{"44 EUR", Size{System: SystemEU, Value: 44}, true},
+		{"EU 44 2/3", Size{System: SystemEU, Value: 44.67}, true},
+		{"EU 42⅓", Size{System: SystemEU, Value: 42.33}, true},
+		{"UK 9", Size{System: SystemUK, Value: 9}, true},
+		{"28 cm", Size{System: SystemCM, Value: 28}, true},
+		{"JP 28.5", Size{System: SystemCM, Value: 28.5}, true},
+		{"10,5", Size{System: SystemUS, Value: 10.5}, true},
+		{"5Y", Size{System: SystemUS, Value: 5, Suffix: "y"}, true},
+		{"Size 7 GS", Size{System: SystemUS, Value: 7, Suffix: "gs"}, true},
+		{"M", Size{Letter: "m"}, true},
+		{"X-Large", Size{Letter: "xl"}, true},
+		{"2XL", Size{Letter: "xxl"}, true},
+		{"Small", Size{Letter: "s"}, true},
+		{"One Size", Size{}, false},
+		{"Black", Size{}, false},
+		{"10 / 11", Size{}, false},
+		{"", Size{}, false},
+	}
+
+	for _, test := range tests {
+		size, ok := ParseSize(test.input)
+
+		if ok != test.ok || size != test.expected {
+			t.Fatalf("%q: expected %+v (%v). got %+v (%v)", test.input, test.expected, test.ok, size, ok)
+		}
+	}
+}
+
+// Tests reading every size out of options that list a size in more than one system
+func TestParseSizes(t *testing.T) {
+	tests := map[string][]Size{
+		"US 10 / EU 44":        {{System: SystemUS, Value: 10}, {System: SystemEU, Value: 44}},
+		"UK 9 | EU 44 | 28 CM": {{System: SystemUK, Value: 9}, {System: SystemEU, Value: 44}, {System: SystemCM, Value: 28}},
+		"US 9.5 / EU 43 1/3":   {{System: SystemUS, Value: 9.5}, {System: SystemEU, Value: 43.33}},
+		"M 9 / W 10.5":         {{System: SystemUS, Value: 9}, {System: SystemUSWomen, Value: 10.5}},
+		"Black / Not A Size":   nil,
+		"L / Black":            {{Letter: "l"}},
+		"11/12":                {{System: SystemUS, Value: 11}, {System: SystemUS, Value: 12}},
+		"8½":                   {{System: SystemUS, Value: 8.5}},
+	}
+
+	for input, expected := range tests {
+		found := ParseSizes(input)
+
+		if len(found) != len(expected) {
+			t.Fatalf("%q: expected %+v. got %+v", input, expected, found)
+		}
+
+		for i := range expected {
+			if found[i] != expected[i] {
+				t.Fatalf("%q: expected %+v. got %+v", input, expected, found)
+			}
+		}
+	}
+}
+
+// Tests converting sizes between systems with the size chart
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from     System
+		to       System
+		expected []float64
+	}{
+		{10, SystemUS, SystemEU, []float64{44}},
+		{44, SystemEU, SystemUS, []float64{10}},
+		{8, SystemUK, SystemUS, []float64{9}},
+		{28, SystemCM, SystemUK, []float64{9}},
+		{24, SystemCM, SystemUS, []float64{5.5, 6}},
+		{10.5, SystemUSWomen, SystemUS, []float64{9}},
+		{9, SystemUS, SystemUSWomen, []float64{10.5}},
+		{10.5, SystemUSWomen, SystemEU, []float64{42.5}},
+		{9.5, SystemUS, SystemUS, []float64{9.5}},
+		{44.67, SystemEU, SystemUS, nil},
+		{20, SystemUS, SystemEU, nil},
+	}
+
+	for _, test := range tests {
+		converted := Convert(test.value, test.from, test.to)
+
+		if len(converted) != len(test.expected) {
+			t.Fatalf("%v %v to %v: expected %v. got %v", test.from, test.value, test.to, test.expected, converted)
+		}
+
+		for i := range converted {
+			if !equal(converted[i], test.expected[i]) {
+				t.Fatalf("%v %v to %v: expected %v. got %v", test.from, test.value, test.to, test.expected, converted)
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package sizes
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+type Strategy string
+
+const (
+	StrategyRandom    Strategy = "random"    // Any size in the range. The default.
+	StrategyPreferred Strategy = "preferred" // The first size in the order the sizes are listed.
+	StrategySpread    Strategy = "spread"    // The size the fewest tasks in the group are going for, so tasks don't all take the same size.
+	StrategyLargest   Strategy = "largest"
+	StrategySmallest  Strategy = "smallest"
+)
+
+// Strategies Every strategy a variant can be selected with
+var Strategies = []Strategy{StrategyRandom, StrategyPreferred, StrategySpread, StrategyLargest, StrategySmallest}
+
+// IsValidStrategy Returns if the strategy is known. An empty strategy is valid and means random.
+func IsValidStrategy(s Strategy) bool {
+	if s == "" {
+		return true
+	}
+
+	for _, strategy := range Strategies {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Choice A variant a selector can choose
+type Choice struct {
+	Key    string // Identifies the variant across the tasks of a spread, ex. its ID.
+	Option string // The option that holds the variant's size.
+}
+
+// Selector Chooses which variant in the size range a task checks out. The zero value chooses at random. A selector
+// belongs to one task and isn't safe to use from more than one goroutine at once.
+type Selector struct {
+	Strategy Strategy
+	Spread   *Spread // The tasks the spread strategy spreads across. Without one it chooses like the preferred strategy.
+	Owner    string  // The task the selector belongs to, ex. its ID, used to replace its earlier choice in the spread.
+	rand     *rand.Rand
+}
+
+// NewSelector Creates a selector with the strategy. A seed other than 0 makes random choices repeat in the same order
+// every time.
+func NewSelector(strategy Strategy, seed int64) *Selector {
+	s := &Selector{Strategy: strategy}
+
+	if seed != 0 {
+		s.rand = rand.New(rand.NewSource(seed))
+	}
+
+	return s
+}
+
+// Select Returns the index of the choice to check out. Choices are ranked by the order of the matcher's specs for the
+// preferred and spread strategies. Returns -1 if there are no choices.
+func (s *Selector) Select(matcher *Matcher, choices []Choice) int {
+	if len(choices) == 0 {
+		return -1
+	}
+
+	switch s.Strategy {
+	case StrategyPreferred:
+		return preferred(matcher, choices)
+	case StrategySpread:
+		if s.Spread == nil {
+			return preferred(matcher, choices)
+		}
+
+		return s.Spread.take(s.Owner, matcher, choices)
+	case StrategyLargest:
+		return bySize(choices, func(a float64, b float64) bool { return a > b })
+	case StrategySmallest:
+		return bySize(choices, func(a float64, b float64) bool { return a < b })
+	}
+
+	if s.rand != nil {
+		return s.rand.Intn(len(choices))
+	}
+
+	return rand.Intn(len(choices))
+}
+
+// Returns the first choice matching the earliest spec
+func preferred(matcher *Matcher, choices []Choice) int {
+	best := 0
+
+	for i, choice := range choices {
+		if matcher.Rank(choice.Option) < matcher.Rank(choices[best].Option) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// Returns the first choice whose size comes before every other one. Choices that aren't sizes are only chosen if no
+// choice is.
+func bySize(choices []Choice, before func(a float64, b float64) bool) int {
+	best, bestValue := 0, math.NaN()
+
+	for i, choice := range choices {
+		value, ok := sortValue(choice.Option)
+
+		if ok && (math.IsNaN(bestValue) || before(value, bestValue)) {
+			best, bestValue = i, value
+		}
+	}
+
+	return best
+}
+
+// Returns a value sizes can be ordered by. Shoe sizes are compared as men's US sizes when the size chart has them and
+// clothing sizes by their order.
+func sortValue(option string) (float64, bool) {
+	found := ParseSizes(option)
+
+	if len(found) == 0 {
+		return 0, false
+	}
+
+	size := found[0]
+
+	if size.Letter != "" {
+		return float64(letterIndex(size.Letter)), true
+	}
+
+	if converted := Convert(size.Value, size.System, SystemUS); len(converted) > 0 {
+		return converted[0], true
+	}
+
+	return size.Value, true
+}
+
+// Spread Keeps track of which variant every task in a group is going for so each task can choose the one the fewest
+// other tasks are. It's safe to share between tasks.
+type Spread struct {
+	mutex  *sync.Mutex
+	chosen map[string]string // The key of the choice of every task, by owner.
+}
+
+// NewSpread Creates an empty spread
+func NewSpread() *Spread {
+	return &Spread{
+		mutex:  &sync.Mutex{},
+		chosen: map[string]string{},
+	}
+}
+
+// Release Forgets the owner's choice, ex. when the task stops or leaves the group
+func (sp *Spread) Release(owner string) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	delete(sp.chosen, owner)
+}
+
+// Counts Returns the amount of tasks going for each key
+func (sp *Spread) Counts() map[string]int {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	return sp.counts("")
+}
+
+// Returns the amount of tasks going for each key, leaving out the owner. The mutex must be held.
+func (sp *Spread) counts(except string) map[string]int {
+	counts := map[string]int{}
+
+	for owner, key := range sp.chosen {
+		if owner != except {
+			counts[key]++
+		}
+	}
+
+	return counts
+}
+
+// Chooses the choice the fewest other tasks are going for, preferring earlier specs when it's a tie, and records it
+// as the owner's choice
+func (sp *Spread) take(owner string, matcher *Matcher, choices []Choice) int {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	counts := sp.counts(owner)
+	best := 0
+
+	for i, choice := range choices {
+		current := choices[best]
+
+		if counts[choice.Key] < counts[current.Key] ||
+			counts[choice.Key] == counts[current.Key] && matcher.Rank(choice.Option) < matcher.Rank(current.Option) {
+			best = i
+		}
+	}
+
+	if owner != "" {
+		sp.chosen[owner] = choices[best].Key
+	}
+
+	return best
+}
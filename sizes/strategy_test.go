@@ -0,0 +1,112 @@
+package sizes
+
+import (
+	"strings"
+	"testing"
+)
+
+var testChoices = []Choice{
+	{Key: "1", Option: "9"},
+	{Key: "2", Option: "EU 44"},
+	{Key: "3", Option: "8.5"},
+	{Key: "4", Option: "11"},
+}
+
+// Tests the choice every strategy makes
+func TestSelectorSelect(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		specs    []string
+		expected int
+	}{
+		{StrategyPreferred, []string{"11", "9"}, 3},
+		{StrategyPreferred, []string{"10-12"}, 1},
+		{StrategyPreferred, nil, 0},
+		{StrategySpread, []string{"9", "11"}, 0},
+		{StrategyLargest, nil, 3},
+		{StrategySmallest, nil, 2},
+		{StrategySmallest, []string{"11"}, 2},
+	}
+
+	for _, test := range tests {
+		m, err := NewMatcher(test.specs)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if chosen := NewSelector(test.strategy, 0).Select(m, testChoices); chosen != test.expected {
+			t.Fatalf("%v %v: expected choice %v. got %v", test.strategy, test.specs, test.expected, chosen)
+		}
+	}
+
+	if chosen := NewSelector(StrategyRandom, 0).Select(&Matcher{}, nil); chosen != -1 {
+		t.Fatalf("expected no choice without choices. got %v", chosen)
+	}
+}
+
+// Tests that sizes that aren't sizes are only chosen by size when nothing else is
+func TestSelectorSelectBySizeSkipsText(t *testing.T) {
+	choices := []Choice{{Key: "1", Option: "One Size"}, {Key: "2", Option: "M"}, {Key: "3", Option: "XL"}}
+
+	if chosen := NewSelector(StrategyLargest, 0).Select(&Matcher{}, choices); chosen != 2 {
+		t.Fatalf("expected XL. got %v", choices[chosen].Option)
+	}
+
+	if chosen := NewSelector(StrategySmallest, 0).Select(&Matcher{}, choices[:1]); chosen != 0 {
+		t.Fatalf("expected the only choice. got %v", chosen)
+	}
+}
+
+// Tests that selectors with the same seed make the same random choices
+func TestSelectorSeed(t *testing.T) {
+	a := NewSelector(StrategyRandom, 42)
+	b := NewSelector(StrategyRandom, 42)
+	counts := map[int]int{}
+
+	for i := 0; i < 100; i++ {
+		chosen := a.Select(&Matcher{}, testChoices)
+
+		if other := b.Select(&Matcher{}, testChoices); other != chosen {
+			t.Fatalf("choice %v: expected both selectors to choose %v. got %v", i, chosen, other)
+		}
+
+		counts[chosen]++
+	}
+
+	if len(counts) != len(testChoices) {
+		t.Fatalf("expected every choice to be made at some point. got %v", counts)
+	}
+}
+
+// Tests that tasks spreading across sizes go for different sizes until every size is taken
+func TestSpread(t *testing.T) {
+	spread := NewSpread()
+	m, _ := NewMatcher([]string{"11", "9", "EU 44"})
+	var keys []string
+
+	for _, owner := range []string{"a", "b", "c", "d"} {
+		s := NewSelector(StrategySpread, 0)
+		s.Spread = spread
+		s.Owner = owner
+		keys = append(keys, testChoices[s.Select(m, testChoices)].Key)
+	}
+
+	if strings.Join(keys, ",") != "4,1,2,3" {
+		t.Fatalf("expected the choices 4,1,2,3. got %v", keys)
+	}
+
+	// Choosing again replaces the task's earlier choice instead of adding to it
+	s := &Selector{Strategy: StrategySpread, Spread: spread, Owner: "a"}
+
+	if chosen := s.Select(m, testChoices); testChoices[chosen].Key != "4" {
+		t.Fatalf("expected task a to keep its size. got %v", testChoices[chosen].Key)
+	}
+
+	spread.Release("b")
+	counts := spread.Counts()
+
+	if counts["1"] != 0 || counts["4"] != 1 || len(counts) != 3 {
+		t.Fatalf("expected task b's choice to be released. got %v", counts)
+	}
+}
@@ -0,0 +1,112 @@
+package tasks
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TaskEventType string
+
+const (
+	EventTaskStarted     TaskEventType = "task_started"
+	EventTaskStopped     TaskEventType = "task_stopped"
+	EventStatusChanged   TaskEventType = "status_changed"
+	EventStepChanged     TaskEventType = "step_changed"
+	EventProductFound    TaskEventType = "product_found"
+	EventCarted          TaskEventType = "carted"
+	EventCheckoutSuccess TaskEventType = "checkout_success"
+	EventCheckoutDecline TaskEventType = "checkout_decline" // Sent for every decline, including ones the task retries after.
+)
+
+// TaskEvent Something that happened to a task. Only the fields for the event's type are set.
+type TaskEvent struct {
+	Type         TaskEventType `json:"type"`
+	Time         time.Time     `json:"time"`
+	TaskId       string        `json:"task_id"`
+	Group        string        `json:"group,omitempty"`
+	Status       *TaskStatus   `json:"status,omitempty"`        // EventStatusChanged
+	Step         string        `json:"step,omitempty"`          // EventStepChanged
+	PreviousStep string        `json:"previous_step,omitempty"` // EventStepChanged
+	Product      string        `json:"product,omitempty"`       // EventProductFound, EventCarted and checkout events
+	Size         string        `json:"size,omitempty"`          // EventProductFound, EventCarted and checkout events
+	Message      string        `json:"message,omitempty"`       // The decline reason for EventCheckoutDecline
+}
+
+// Subscription Receives the events of every task in a manager. Events are dropped instead of blocking the task that
+// sent them when Events is full, so subscribers should keep up or check Dropped.
+type Subscription struct {
+	dropped uint64 // First so it's 64-bit aligned for atomic operations on 32-bit platforms.
+	Events  <-chan TaskEvent
+	events  chan TaskEvent
+	manager *Manager
+	once    *sync.Once
+}
+
+// The amount of events a subscription holds before it starts dropping them
+const subscriptionBufferSize = 256
+
+// Subscribe Returns a subscription to the events of every task in the manager. Call Unsubscribe once it's no longer
+// needed.
+func (m *Manager) Subscribe() *Subscription {
+	events := make(chan TaskEvent, subscriptionBufferSize)
+
+	s := &Subscription{
+		Events:  events,
+		events:  events,
+		manager: m,
+		once:    &sync.Once{},
+	}
+
+	m.SubscriptionMutex.Lock()
+	defer m.SubscriptionMutex.Unlock()
+
+	m.Subscriptions[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe Stops the subscription and closes its channel
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.manager.SubscriptionMutex.Lock()
+		defer s.manager.SubscriptionMutex.Unlock()
+
+		delete(s.manager.Subscriptions, s)
+		close(s.events)
+	})
+}
+
+// Dropped Returns the amount of events dropped because the subscription's channel was full
+func (s *Subscription) Dropped() int {
+	return int(atomic.LoadUint64(&s.dropped))
+}
+
+// Publish Sends an event to every subscription without waiting on any of them
+func (m *Manager) Publish(e TaskEvent) {
+	m.SubscriptionMutex.RLock()
+	defer m.SubscriptionMutex.RUnlock()
+
+	for s := range m.Subscriptions {
+		select {
+		case s.events <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// Emit Publishes an event from the task to its manager's subscribers. The time, task ID and group are filled in.
+func (t *Task) Emit(e TaskEvent) {
+	if t.TaskManager == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	e.TaskId = t.Id
+
+	if t.Group != nil {
+		e.Group = t.Group.Name
+	}
+
+	t.TaskManager.Publish(e)
+}
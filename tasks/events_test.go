@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"testing"
+)
+
+// Tests that every subscriber receives a task's events in order with its ID and group
+func TestSubscribe(t *testing.T) {
+	m := NewManager()
+	group := NewTaskGroup("Dunks")
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+	group.AddTask(&task)
+	m.AddTask(&task)
+
+	a, b := m.Subscribe(), m.Subscribe()
+	defer b.Unsubscribe()
+
+	task.Begin()
+	task.SetStep("Monitoring")
+	task.SetStep("Monitoring")
+	task.UpdateStatus(&TaskStatus{Value: "Monitoring"}, false)
+	task.SetStep("Cart")
+	task.Finish()
+
+	a.Unsubscribe()
+	a.Unsubscribe()
+
+	expected := []TaskEventType{EventTaskStarted, EventStepChanged, EventStatusChanged, EventStepChanged, EventTaskStopped}
+	var received []TaskEvent
+
+	for e := range a.Events {
+		received = append(received, e)
+	}
+
+	if len(received) != len(expected) {
+		t.Fatalf("expected %v events. got %+v", len(expected), received)
+	}
+
+	for i, e := range received {
+		if e.Type != expected[i] || e.TaskId != task.Id || e.Group != "Dunks" || e.Time.IsZero() {
+			t.Fatalf("unexpected event %v: %+v", i, e)
+		}
+	}
+
+	if received[3].Step != "Cart" || received[3].PreviousStep != "Monitoring" || received[2].Status.Value != "Monitoring" {
+		t.Fatalf("unexpected event details %+v", received)
+	}
+
+	if len(b.Events) != len(expected) {
+		t.Fatalf("expected the second subscriber to receive %v events. got %v", len(expected), len(b.Events))
+	}
+}
+
+// Tests that a subscriber that doesn't read never blocks a task
+func TestSubscribeSlowConsumer(t *testing.T) {
+	m := NewManager()
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+	m.AddTask(&task)
+
+	s := m.Subscribe()
+	defer s.Unsubscribe()
+
+	for i := 0; i < subscriptionBufferSize+10; i++ {
+		task.UpdateStatus(&TaskStatus{Value: "Monitoring"}, false)
+	}
+
+	if len(s.Events) != subscriptionBufferSize || s.Dropped() != 10 {
+		t.Fatalf("expected a full channel and 10 dropped events. got %v and %v", len(s.Events), s.Dropped())
+	}
+}
@@ -2,28 +2,72 @@ package tasks
 
 import (
 	"Mystery/automation"
-	"log"
+	"Mystery/history"
+	"Mystery/logs"
+	"Mystery/notify"
+	"Mystery/profiles"
+	"Mystery/proxies"
+	"Mystery/sizes"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type Manager struct {
-	TaskMutex      *sync.Mutex
-	TaskGroupMutex *sync.Mutex
-	TaskWaitGroup  *sync.WaitGroup
-	Tasks          map[*Task]struct{}
-	TaskGroups     map[*TaskGroup]struct{}
-	Automations    []*automation.Automation
+	TaskMutex         *sync.Mutex
+	TaskGroupMutex    *sync.Mutex
+	TaskWaitGroup     *sync.WaitGroup
+	Tasks             map[*Task]struct{}
+	TaskGroups        map[*TaskGroup]struct{}
+	Automations       []*automation.Automation
+	AutomationMutex   *sync.Mutex
+	AutomationGroups  map[string]*AutomationGroup
+	Profiles          map[string]*profiles.Profile  // Profiles automations can use, by name.
+	ProxyLists        map[string]*proxies.ProxyList // Proxy lists automations can use, by name.
+	NewTask           TaskFactory                   // Creates the tasks for automations. Automations are ignored if this isn't set.
+	Notifications     *notify.Router                // Sends checkout and automation events to notifiers. Nothing is sent if this isn't set.
+	Logger            *logs.Logger                  // The logger tasks log to. The default logger is used if this isn't set.
+	TaskLogDir        string                        // Every task also logs to <TaskLogDir>/<task id>.log if this is set.
+	History           *history.Store                // Saves every checkout attempt. Nothing is saved if this isn't set.
+	SubscriptionMutex *sync.RWMutex
+	Subscriptions     map[*Subscription]struct{}
 }
 
+// TaskFactory Creates a task that is ready to be started. The tasks package doesn't know about specific sites, so the
+// package that does (ex. shopify) provides the factory.
+type TaskFactory func(site Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode TaskMode, inputs []string, sizes []string) *Task
+
+// AutomationGroup A task group started by an automation for a product
+type AutomationGroup struct {
+	Automation *automation.Automation
+	Product    *automation.ZephyrMonitorLive
+	Group      *TaskGroup
+	timer      *time.Timer
+}
+
+// The unit of Automation.StopAfterMinutes. Shortened in tests.
+var automationStopUnit = time.Minute
+
 // NewManager Creates a new Task manager object
 func NewManager() Manager {
 	return Manager{
-		TaskMutex:      &sync.Mutex{},
-		TaskGroupMutex: &sync.Mutex{},
-		TaskWaitGroup:  &sync.WaitGroup{},
-		Tasks:          map[*Task]struct{}{},
-		TaskGroups:     map[*TaskGroup]struct{}{},
-		Automations:    []*automation.Automation{},
+		TaskMutex:         &sync.Mutex{},
+		TaskGroupMutex:    &sync.Mutex{},
+		TaskWaitGroup:     &sync.WaitGroup{},
+		Tasks:             map[*Task]struct{}{},
+		TaskGroups:        map[*TaskGroup]struct{}{},
+		Automations:       []*automation.Automation{},
+		AutomationMutex:   &sync.Mutex{},
+		AutomationGroups:  map[string]*AutomationGroup{},
+		SubscriptionMutex: &sync.RWMutex{},
+		Subscriptions:     map[*Subscription]struct{}{},
+		Profiles:          map[string]*profiles.Profile{},
+		ProxyLists:        map[string]*proxies.ProxyList{},
 	}
 }
 
@@ -46,8 +90,41 @@ func (m *Manager) RemoveTask(t *Task) {
 	m.TaskMutex.Lock()
 	defer m.TaskMutex.Unlock()
 
+	t.CloseLogFile()
 	t.TaskManager = nil
 	delete(m.Tasks, t)
+
+	if t.ProxyList != nil {
+		t.ProxyList.ReleaseProxy(t.Id)
+	}
+}
+
+// GetTask Returns the task with the given ID, or nil if the manager doesn't have one
+func (m *Manager) GetTask(id string) *Task {
+	m.TaskMutex.Lock()
+	defer m.TaskMutex.Unlock()
+
+	for t := range m.Tasks {
+		if t.Id == id {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// GetTaskGroup Returns the task group with the given name, or nil if the manager doesn't have one
+func (m *Manager) GetTaskGroup(name string) *TaskGroup {
+	m.TaskGroupMutex.Lock()
+	defer m.TaskGroupMutex.Unlock()
+
+	for g := range m.TaskGroups {
+		if g.Name == name {
+			return g
+		}
+	}
+
+	return nil
 }
 
 // AddTaskGroup Adds a task group to the manager
@@ -67,6 +144,27 @@ func (m *Manager) AddTaskGroup(g *TaskGroup) {
 	}
 }
 
+// AddTaskGroupIfAbsent Adds a task group to the manager unless it already has a group with the same name. Returns false
+// if it does.
+func (m *Manager) AddTaskGroupIfAbsent(g *TaskGroup) bool {
+	m.TaskGroupMutex.Lock()
+	defer m.TaskGroupMutex.Unlock()
+
+	for group := range m.TaskGroups {
+		if group.Name == g.Name {
+			return false
+		}
+	}
+
+	m.TaskGroups[g] = struct{}{}
+
+	for _, task := range g.Tasks {
+		m.AddTask(task)
+	}
+
+	return true
+}
+
 // RemoveTaskGroup Removes a task group from the manager. This also stops and gets rid of all tasks.
 func (m *Manager) RemoveTaskGroup(g *TaskGroup) {
 	m.TaskGroupMutex.Lock()
@@ -76,9 +174,8 @@ func (m *Manager) RemoveTaskGroup(g *TaskGroup) {
 	for i := len(g.Tasks) - 1; i >= 0; i-- {
 		task := g.Tasks[i]
 
-		switch t := interface{}(task).(type) {
-		case TaskRunner:
-			m.StopTask(t)
+		if task.Runner != nil {
+			m.StopTask(task.Runner)
 		}
 
 		g.RemoveTask(task)
@@ -98,6 +195,48 @@ func (m *Manager) StopTask(t TaskRunner) {
 	t.Stop()
 }
 
+// StartTaskGroup Starts every task in a group
+func (m *Manager) StartTaskGroup(g *TaskGroup) {
+	for _, task := range g.GetTasks() {
+		if task.Runner == nil {
+			continue
+		}
+
+		m.StartTask(task.Runner)
+	}
+}
+
+// StopTaskGroup Stops every task in a group
+func (m *Manager) StopTaskGroup(g *TaskGroup) {
+	for _, task := range g.GetTasks() {
+		if task.Runner == nil {
+			continue
+		}
+
+		m.StopTask(task.Runner)
+	}
+}
+
+// StartAllTaskGroups Starts every task in every group that is in the manager
+func (m *Manager) StartAllTaskGroups() {
+	m.TaskGroupMutex.Lock()
+	defer m.TaskGroupMutex.Unlock()
+
+	for g := range m.TaskGroups {
+		m.StartTaskGroup(g)
+	}
+}
+
+// StopAllTaskGroups Stops every task in every group that is in the manager
+func (m *Manager) StopAllTaskGroups() {
+	m.TaskGroupMutex.Lock()
+	defer m.TaskGroupMutex.Unlock()
+
+	for g := range m.TaskGroups {
+		m.StopTaskGroup(g)
+	}
+}
+
 // WaitForAllTasks Waits for every task to call wg.Done()
 func (m *Manager) WaitForAllTasks() {
 	m.TaskWaitGroup.Wait()
@@ -105,27 +244,251 @@ func (m *Manager) WaitForAllTasks() {
 
 // HandleNewAutomationProducts Handles incoming products from automation
 func (m *Manager) HandleNewAutomationProducts() {
-	log.Println("Starting automation product handler.")
+	logs.Info("Starting automation product handler.")
 
 	go func() {
 		for {
 			select {
 			case product := <-automation.ZephyrMonitorChannel:
-				for _, auto := range m.Automations {
-					if !auto.IsPriceMatch(product) || !auto.IsWebsiteMatch(product) || !auto.IsProductMatch(product) {
-						continue
-					}
+				m.HandleAutomationProduct(product)
+			}
+		}
+	}()
+}
 
-					variants := auto.GetMatchingSizeVariants(product)
+// GetAutomations Returns a copy of the manager's automations
+func (m *Manager) GetAutomations() []*automation.Automation {
+	m.AutomationMutex.Lock()
+	defer m.AutomationMutex.Unlock()
 
-					if len(variants) == 0 {
-						continue
-					}
+	return append([]*automation.Automation{}, m.Automations...)
+}
 
-					// TODO: Check for existing automation group with the exact same variants.
-					auto.SendWebhook(true, product)
-				}
-			}
+// AddAutomation Adds an automation that is checked against every new product. Names must be unique.
+func (m *Manager) AddAutomation(auto *automation.Automation) error {
+	m.AutomationMutex.Lock()
+	defer m.AutomationMutex.Unlock()
+
+	for _, a := range m.Automations {
+		if a.Name == auto.Name {
+			return errors.New(fmt.Sprintf("automation %v already exists", auto.Name))
 		}
-	}()
+	}
+
+	m.Automations = append(m.Automations, auto)
+	return nil
+}
+
+// RemoveAutomation Removes the automation with the given name so it no longer starts groups. Groups it already started
+// keep running. Returns false if there is no automation with the name.
+func (m *Manager) RemoveAutomation(name string) bool {
+	m.AutomationMutex.Lock()
+	defer m.AutomationMutex.Unlock()
+
+	for i, a := range m.Automations {
+		if a.Name == name {
+			m.Automations = append(m.Automations[:i:i], m.Automations[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// HandleAutomationProduct Starts a task group for every automation that matches the product
+func (m *Manager) HandleAutomationProduct(product *automation.ZephyrMonitorLive) {
+	for _, auto := range m.GetAutomations() {
+		if !auto.IsPriceMatch(product) || !auto.IsWebsiteMatch(product) || !auto.IsProductMatch(product) {
+			continue
+		}
+
+		variants := auto.GetMatchingSizeVariants(product)
+
+		if len(variants) == 0 {
+			continue
+		}
+
+		m.StartAutomationGroup(auto, product, variants)
+	}
+}
+
+// StartAutomationGroup Creates and starts a group of Automation.TotalTaskCount tasks that check out the given variants.
+// Profiles are spread evenly across the tasks. Returns nil if the automation already has a group for the same variants.
+func (m *Manager) StartAutomationGroup(auto *automation.Automation, product *automation.ZephyrMonitorLive, variants []automation.ZephyrMonitorLiveProductVariant) *TaskGroup {
+	if m.NewTask == nil {
+		logs.Warn("No task factory set. Ignoring product.", "automation", auto.Name, "product", product.Body.Payload.Product.Title)
+		return nil
+	}
+
+	key := automationGroupKey(auto, variants)
+
+	m.AutomationMutex.Lock()
+
+	if _, ok := m.AutomationGroups[key]; ok {
+		m.AutomationMutex.Unlock()
+		return nil
+	}
+
+	site := automationWebsite(product)
+	inputs := make([]string, len(variants))
+
+	for i, variant := range variants {
+		inputs[i] = strconv.FormatInt(variant.Id, 10)
+	}
+
+	group := NewTaskGroup(fmt.Sprintf("%v - %v", auto.Name, product.Body.Payload.Product.Title))
+	group.Notify = auto.Notify
+	var selector *sizes.Selector
+
+	if auto.VariantStrategy != "" {
+		selector = sizes.NewSelector(auto.VariantStrategy, auto.VariantSeed)
+		selector.Spread = group.SizeSpread()
+	}
+
+	for i := 0; i < auto.TotalTaskCount; i++ {
+		var profile *profiles.Profile
+
+		if len(auto.Profiles) > 0 {
+			profile = m.Profiles[auto.Profiles[i%len(auto.Profiles)]]
+		}
+
+		task := m.NewTask(site, profile, m.ProxyLists[auto.ProxyList], ModeShopifySafe, inputs, []string{})
+
+		if selector != nil {
+			selector.Owner = task.Id
+			task.MonitorInputs = chooseAutomationVariant(auto, product, variants, inputs, selector)
+		}
+
+		if auto.Quantity > 0 {
+			task.Quantity = auto.Quantity
+		}
+
+		task.PaymentRetries = auto.PaymentRetries
+
+		if auto.PaymentRetryDelayMs > 0 {
+			task.RetryPolicies.Steps = map[RetryStep]RetryPolicy{RetryStepDecline: {DelayMs: auto.PaymentRetryDelayMs}}
+		}
+
+		group.AddTask(task)
+	}
+
+	g := &AutomationGroup{
+		Automation: auto,
+		Product:    product,
+		Group:      &group,
+	}
+
+	if auto.StopAfterMinutes > 0 {
+		g.timer = time.AfterFunc(time.Duration(auto.StopAfterMinutes)*automationStopUnit, func() {
+			m.StopAutomationGroup(key)
+		})
+	}
+
+	m.AutomationGroups[key] = g
+	m.AutomationMutex.Unlock()
+
+	m.AddTaskGroup(&group)
+	m.StartTaskGroup(&group)
+	m.Notifications.SendAndLog(auto.Notify, auto.NewEvent(true, product))
+
+	return &group
+}
+
+// StopAutomationGroup Stops and removes an automation's task group and sends the automation stopped event
+func (m *Manager) StopAutomationGroup(key string) {
+	m.AutomationMutex.Lock()
+	g, ok := m.AutomationGroups[key]
+	delete(m.AutomationGroups, key)
+	m.AutomationMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+
+	m.RemoveTaskGroup(g.Group)
+	m.Notifications.SendAndLog(g.Automation.Notify, g.Automation.NewEvent(false, g.Product))
+}
+
+// StopAllAutomationGroups Stops and removes every task group started by an automation
+func (m *Manager) StopAllAutomationGroups() {
+	m.AutomationMutex.Lock()
+	keys := make([]string, 0, len(m.AutomationGroups))
+
+	for key := range m.AutomationGroups {
+		keys = append(keys, key)
+	}
+
+	m.AutomationMutex.Unlock()
+
+	for _, key := range keys {
+		m.StopAutomationGroup(key)
+	}
+}
+
+// Returns a task's variant inputs with the variant the selector chooses first, since tasks go for their first variant
+// input. The inputs are returned as they are if the selector doesn't choose one.
+func chooseAutomationVariant(auto *automation.Automation, product *automation.ZephyrMonitorLive, variants []automation.ZephyrMonitorLiveProductVariant, inputs []string, selector *sizes.Selector) []string {
+	matcher, err := sizes.NewMatcher(auto.Sizes)
+
+	if err != nil {
+		matcher = &sizes.Matcher{}
+	}
+
+	option := product.Body.Payload.Product.SizeOption()
+	choices := make([]sizes.Choice, len(variants))
+
+	for i, variant := range variants {
+		choices[i] = sizes.Choice{Key: strconv.FormatInt(variant.Id, 10), Option: variant.Option(option)}
+	}
+
+	chosen := selector.Select(matcher, choices)
+
+	if chosen == -1 {
+		return inputs
+	}
+
+	ordered := []string{choices[chosen].Key}
+
+	for i, choice := range choices {
+		if i != chosen {
+			ordered = append(ordered, choice.Key)
+		}
+	}
+
+	return ordered
+}
+
+// Identifies an automation's group by the automation's name and the sorted variant IDs it checks out
+func automationGroupKey(auto *automation.Automation, variants []automation.ZephyrMonitorLiveProductVariant) string {
+	ids := make([]int64, len(variants))
+
+	for i, variant := range variants {
+		ids[i] = variant.Id
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parts := make([]string, len(ids))
+
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+
+	return fmt.Sprintf("%v:%v", auto.Name, strings.Join(parts, ","))
+}
+
+// Creates the website a product from the monitor was found on, ex. "https://kith.com/" -> {kith.com https://kith.com}
+func automationWebsite(product *automation.ZephyrMonitorLive) Website {
+	store := strings.TrimRight(product.Body.Payload.Store, "/")
+	name := store
+
+	if u, err := url.Parse(store); err == nil && u.Host != "" {
+		name = u.Host
+	}
+
+	return Website{Name: name, Url: store}
 }
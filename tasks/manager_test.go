@@ -2,8 +2,15 @@ package tasks
 
 import (
 	"Mystery/automation"
+	"Mystery/notify"
+	"Mystery/profiles"
+	"Mystery/proxies"
+	"Mystery/sizes"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestTaskGroups(t *testing.T) {
@@ -81,3 +88,344 @@ func TestAutomationChannel(t *testing.T) {
 	go automation.ConnectToZephyrMonitor()
 	wg.Wait()
 }
+
+type testRunner struct {
+	mutex   *sync.Mutex
+	started int
+	stopped int
+}
+
+func (r *testRunner) Start() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.started++
+}
+
+func (r *testRunner) Stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stopped++
+}
+
+// Sends every event to a channel
+type testNotifier chan notify.Event
+
+func (n testNotifier) Notify(e notify.Event) error {
+	n <- e
+	return nil
+}
+
+// Creates a manager with test profiles and proxies whose automation tasks use testRunner.
+// Automation events are sent to the returned channel.
+func newTestAutomationManager(t *testing.T) (*Manager, testNotifier) {
+	m := NewManager()
+	m.Profiles = map[string]*profiles.Profile{"A": {Name: "A"}, "B": {Name: "B"}}
+	m.ProxyLists = map[string]*proxies.ProxyList{"Live": {Name: "Live"}}
+
+	m.NewTask = func(site Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode TaskMode, inputs []string, sizes []string) *Task {
+		task := NewTask(site, profile, proxyList, mode, inputs, sizes)
+		task.Runner = &testRunner{mutex: &sync.Mutex{}}
+		return &task
+	}
+
+	events := make(testNotifier, 10)
+	m.Notifications = notify.NewRouter()
+	m.Notifications.AddSink("test", events)
+	m.Notifications.Defaults = notify.Routes{
+		notify.EventAutomationStarted: {"test"},
+		notify.EventAutomationStopped: {"test"},
+	}
+
+	return &m, events
+}
+
+func newTestAutomationProduct(variants ...int64) *automation.ZephyrMonitorLive {
+	live := &automation.ZephyrMonitorLive{
+		Body: automation.ZephyrMonitorLiveBody{
+			Payload: automation.ZephyrMonitorLivePayload{
+				Product: automation.ZephyrMonitorLiveProduct{
+					Title:  "Nike Dunk Low Retro - Panda",
+					Handle: "nike-dunk-low-retro-panda",
+				},
+				Store: "https://kith.com/",
+			},
+		},
+	}
+
+	for _, id := range variants {
+		live.Body.Payload.Product.Variants = append(live.Body.Payload.Product.Variants, automation.ZephyrMonitorLiveProductVariant{
+			Id:        id,
+			Title:     fmt.Sprintf("%v", id%100),
+			Price:     "110.00",
+			Available: true,
+		})
+	}
+
+	return live
+}
+
+// Tests that a matched product starts a group of tasks for the automation
+func TestAutomationStartsGroup(t *testing.T) {
+	m, events := newTestAutomationManager(t)
+
+	m.Automations = append(m.Automations, &automation.Automation{
+		Name:                "Dunks",
+		MonitorInputs:       []string{"+dunk"},
+		Sizes:               []string{"9", "10"},
+		Profiles:            []string{"A", "B"},
+		ProxyList:           "Live",
+		PriceMaximum:        200,
+		Quantity:            2,
+		TotalTaskCount:      5,
+		PaymentRetries:      3,
+		PaymentRetryDelayMs: 250,
+	})
+
+	m.HandleAutomationProduct(newTestAutomationProduct(108, 109, 110))
+
+	if len(m.TaskGroups) != 1 || len(m.Tasks) != 5 {
+		t.Fatalf("expected 1 group of 5 tasks. got %v groups and %v tasks", len(m.TaskGroups), len(m.Tasks))
+	}
+
+	profileCounts := map[string]int{}
+
+	for task := range m.Tasks {
+		profileCounts[task.Profile.Name]++
+
+		if task.Site.Url != "https://kith.com" || task.Site.Name != "kith.com" {
+			t.Fatalf("unexpected site %+v", task.Site)
+		}
+
+		if task.ProxyList == nil || task.ProxyList.Name != "Live" {
+			t.Fatalf("task %v has the wrong proxy list", task.Id)
+		}
+
+		if task.Quantity != 2 {
+			t.Fatalf("expected a quantity of 2. got %v", task.Quantity)
+		}
+
+		if task.PaymentRetries != 3 || task.GetRetryPolicy(RetryStepDecline).DelayMs != 250 {
+			t.Fatalf("expected 3 payment retries 250ms apart. got %v retries", task.PaymentRetries)
+		}
+
+		if strings.Join(task.MonitorInputs, ",") != "109,110" {
+			t.Fatalf("expected the matching variants as inputs. got %v", task.MonitorInputs)
+		}
+
+		if runner := task.Runner.(*testRunner); runner.started != 1 {
+			t.Fatalf("expected task %v to be started once. got %v", task.Id, runner.started)
+		}
+	}
+
+	if profileCounts["A"] != 3 || profileCounts["B"] != 2 {
+		t.Fatalf("expected profiles to be spread across tasks. got %v", profileCounts)
+	}
+
+	if e := <-events; e.Type != notify.EventAutomationStarted {
+		t.Fatalf("expected the automation started event. got %v", e.Type)
+	}
+
+	// The same variants in a different order shouldn't start another group
+	m.HandleAutomationProduct(newTestAutomationProduct(110, 109))
+
+	if len(m.TaskGroups) != 1 {
+		t.Fatalf("expected duplicate groups to be suppressed. got %v groups", len(m.TaskGroups))
+	}
+
+	m.HandleAutomationProduct(newTestAutomationProduct(109))
+
+	if len(m.TaskGroups) != 2 {
+		t.Fatalf("expected a group for different variants. got %v groups", len(m.TaskGroups))
+	}
+}
+
+// Tests that an automation's group is stopped and removed after StopAfterMinutes
+func TestAutomationStopsGroup(t *testing.T) {
+	m, events := newTestAutomationManager(t)
+
+	unit := automationStopUnit
+	automationStopUnit = time.Millisecond
+
+	t.Cleanup(func() {
+		automationStopUnit = unit
+	})
+
+	auto := &automation.Automation{
+		Name:             "Dunks",
+		Profiles:         []string{"A"},
+		TotalTaskCount:   2,
+		StopAfterMinutes: 20,
+	}
+
+	product := newTestAutomationProduct(109)
+	group := m.StartAutomationGroup(auto, product, product.Body.Payload.Product.Variants)
+
+	if group == nil {
+		t.Fatal("expected a group to be started")
+	}
+
+	runners := []*testRunner{group.Tasks[0].Runner.(*testRunner), group.Tasks[1].Runner.(*testRunner)}
+
+	if e := <-events; e.Type != notify.EventAutomationStarted {
+		t.Fatalf("expected the automation started event. got %v", e.Type)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != notify.EventAutomationStopped {
+			t.Fatalf("expected the automation stopped event. got %v", e.Type)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the group was never stopped")
+	}
+
+	m.AutomationMutex.Lock()
+	remaining := len(m.AutomationGroups)
+	m.AutomationMutex.Unlock()
+
+	if remaining != 0 || len(m.TaskGroups) != 0 || len(m.Tasks) != 0 {
+		t.Fatalf("expected the group to be removed. got %v automation groups, %v groups and %v tasks", remaining, len(m.TaskGroups), len(m.Tasks))
+	}
+
+	for _, runner := range runners {
+		runner.mutex.Lock()
+
+		if runner.stopped != 1 {
+			t.Fatalf("expected every task to be stopped once. got %v", runner.stopped)
+		}
+
+		runner.mutex.Unlock()
+	}
+
+	// The variants can be automated again once the group has stopped
+	if m.StartAutomationGroup(auto, product, product.Body.Payload.Product.Variants) == nil {
+		t.Fatal("expected a new group once the previous one stopped")
+	}
+
+	m.StopAllAutomationGroups()
+}
+
+// Tests that an automation's variant strategy chooses the variant each task goes for first
+func TestAutomationVariantStrategy(t *testing.T) {
+	tests := map[sizes.Strategy]map[string]int{
+		sizes.StrategySpread:    {"110": 2, "109": 2},
+		sizes.StrategyPreferred: {"110": 4},
+		sizes.StrategySmallest:  {"109": 4},
+	}
+
+	for strategy, expected := range tests {
+		m, _ := newTestAutomationManager(t)
+		auto := &automation.Automation{
+			Name:            "Dunks",
+			Sizes:           []string{"10", "9"},
+			Profiles:        []string{"A"},
+			TotalTaskCount:  4,
+			VariantStrategy: strategy,
+		}
+
+		group := m.StartAutomationGroup(auto, newTestAutomationProduct(109, 110), []automation.ZephyrMonitorLiveProductVariant{{Id: 109, Title: "9"}, {Id: 110, Title: "10"}})
+		first := map[string]int{}
+
+		for _, task := range group.Tasks {
+			if len(task.MonitorInputs) != 2 {
+				t.Fatalf("%v: expected both variants as inputs. got %v", strategy, task.MonitorInputs)
+			}
+
+			first[task.MonitorInputs[0]]++
+		}
+
+		if fmt.Sprint(first) != fmt.Sprint(expected) {
+			t.Fatalf("%v: expected tasks to go for %v. got %v", strategy, expected, first)
+		}
+	}
+}
+
+// Tests that only one of many groups added at once with the same name is added
+func TestAddTaskGroupIfAbsent(t *testing.T) {
+	m := NewManager()
+	var wg sync.WaitGroup
+	added := make(chan bool, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			g := NewTaskGroup("Dunks")
+			added <- m.AddTaskGroupIfAbsent(&g)
+		}()
+	}
+
+	wg.Wait()
+	close(added)
+	count := 0
+
+	for ok := range added {
+		if ok {
+			count++
+		}
+	}
+
+	if count != 1 || len(m.TaskGroups) != 1 {
+		t.Fatalf("expected one group to be added. got %v added and %v groups", count, len(m.TaskGroups))
+	}
+}
+
+// Tests that a task keeps its inputs when the selector has no variant to choose
+func TestChooseAutomationVariantNoChoice(t *testing.T) {
+	auto := &automation.Automation{Name: "Dunks", VariantStrategy: sizes.StrategyPreferred}
+	inputs := chooseAutomationVariant(auto, newTestAutomationProduct(), nil, []string{"109"}, sizes.NewSelector(auto.VariantStrategy, 0))
+
+	if len(inputs) != 1 || inputs[0] != "109" {
+		t.Fatalf("expected the original inputs. got %v", inputs)
+	}
+}
+
+// A runner that chooses the task's variant while it's being stopped, like a task that is still monitoring
+type variantRunner struct {
+	task *Task
+}
+
+func (r variantRunner) Start() {}
+
+func (r variantRunner) Stop() {
+	r.task.VariantSelector()
+}
+
+// Tests that a group can be stopped while its tasks choose their variants, and that stopped tasks release their size
+func TestStopTaskGroupSpread(t *testing.T) {
+	m := NewManager()
+	group := NewTaskGroup("Spread")
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, []string{"9"})
+	task.Id = "a"
+	task.VariantStrategy = sizes.StrategySpread
+	task.Runner = variantRunner{&task}
+	group.AddTask(&task)
+	m.AddTaskGroup(&group)
+
+	stopped := make(chan struct{})
+
+	go func() {
+		m.StopTaskGroup(&group)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stopping the group didn't return")
+	}
+
+	matcher, _ := sizes.NewMatcher(task.Sizes)
+	task.VariantSelector().Select(matcher, []sizes.Choice{{Key: "1", Option: "9"}})
+
+	if counts := group.SizeSpread().Counts(); counts["1"] != 1 {
+		t.Fatalf("expected the task to go for variant 1. got %v", counts)
+	}
+
+	task.ReleaseVariant()
+
+	if counts := group.SizeSpread().Counts(); len(counts) != 0 {
+		t.Fatalf("expected the task's variant to be released. got %v", counts)
+	}
+}
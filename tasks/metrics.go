@@ -0,0 +1,105 @@
+package tasks
+
+import (
+	"Mystery/metrics"
+	"context"
+	"errors"
+	"github.com/go-resty/resty/v2"
+	"strconv"
+	"time"
+)
+
+// The steps the monitoring and queue gauges count tasks on. Sites name their checkout steps with these.
+const (
+	StepMonitoring = "Monitoring"
+	StepQueue      = "Queue"
+)
+
+var (
+	flowDuration = metrics.NewHistogramVec("nothing_flow_duration_seconds",
+		"Time spent in each flow of a task, including retries, ex. submit_payment.", metrics.DefaultBuckets, "flow", "site", "mode")
+	stepDuration = metrics.NewHistogramVec("nothing_step_duration_seconds",
+		"Time spent on each checkout step before moving to another one.", metrics.DefaultBuckets, "step", "site", "mode")
+	requestDuration = metrics.NewHistogramVec("nothing_http_request_duration_seconds",
+		"Time taken by each HTTP request a task sends, by the checkout step it was sent on.", metrics.DefaultBuckets, "step", "site", "mode")
+	requestErrors = metrics.NewCounterVec("nothing_http_errors_total",
+		"HTTP requests that failed, by status. Requests that never got a response have the status \"error\".", "site", "mode", "status")
+)
+
+// RegisterMetrics Adds the task metrics and gauges of the manager's running, monitoring and queued tasks to the registry
+func (m *Manager) RegisterMetrics(r *metrics.Registry) {
+	r.Register(
+		flowDuration,
+		stepDuration,
+		requestDuration,
+		requestErrors,
+		metrics.NewGaugeFunc("nothing_tasks_running", "Tasks that are running.", func() float64 {
+			return float64(m.countRunningTasks(""))
+		}),
+		metrics.NewGaugeFunc("nothing_tasks_monitoring", "Running tasks that are monitoring for a product.", func() float64 {
+			return float64(m.countRunningTasks(StepMonitoring))
+		}),
+		metrics.NewGaugeFunc("nothing_tasks_in_queue", "Running tasks that are waiting in a checkout queue.", func() float64 {
+			return float64(m.countRunningTasks(StepQueue))
+		}),
+	)
+}
+
+// Returns the amount of running tasks on the step, or every running task if the step is empty
+func (m *Manager) countRunningTasks(step string) int {
+	m.TaskMutex.Lock()
+	defer m.TaskMutex.Unlock()
+
+	count := 0
+
+	for t := range m.Tasks {
+		if t.IsRunning() && (step == "" || t.GetStep() == step) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// TimeFlow Starts timing a flow, ex. submitting payment. Call the returned function once the flow is finished.
+//
+//	defer t.TimeFlow("submit_payment")()
+func (t *Task) TimeFlow(flow string) func() {
+	start := time.Now()
+
+	return func() {
+		flowDuration.Observe(time.Since(start).Seconds(), flow, t.Site.Name, ModeToString(t.Mode))
+	}
+}
+
+// Times every request the task's client sends and counts the ones that fail
+func (t *Task) instrumentClient() {
+	t.Client.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		mode := ModeToString(t.Mode)
+		requestDuration.Observe(resp.Time().Seconds(), t.GetStep(), t.Site.Name, mode)
+
+		if resp.StatusCode() >= 400 {
+			requestErrors.Inc(t.Site.Name, mode, strconv.Itoa(resp.StatusCode()))
+		}
+
+		return nil
+	})
+
+	t.Client.OnError(func(req *resty.Request, err error) {
+		if !isTransportError(err) {
+			return
+		}
+
+		requestErrors.Inc(t.Site.Name, ModeToString(t.Mode), "error")
+	})
+}
+
+// Returns if a request failed without getting a response. Requests that errored after a response were already handled
+// by the OnAfterResponse hooks, and cancelled ones were stopped on purpose.
+func isTransportError(err error) bool {
+	if re, ok := err.(*resty.ResponseError); ok && re.Response.RawResponse != nil {
+		return false
+	}
+
+	return !errors.Is(err, context.Canceled)
+}
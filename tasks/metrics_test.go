@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"Mystery/metrics"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Tests the gauges of running, monitoring and queued tasks
+func TestManagerMetrics(t *testing.T) {
+	m := NewManager()
+	r := metrics.NewRegistry()
+	m.RegisterMetrics(r)
+
+	for _, step := range []string{StepMonitoring, StepMonitoring, StepQueue, "Payment Method", ""} {
+		task := NewTask(Website{Name: "Kith"}, nil, nil, ModeShopifySafe, nil, nil)
+		m.AddTask(&task)
+		task.Begin()
+		task.SetStep(step)
+		defer task.Finish()
+	}
+
+	stopped := NewTask(Website{Name: "Kith"}, nil, nil, ModeShopifySafe, nil, nil)
+	stopped.SetStep(StepMonitoring)
+	m.AddTask(&stopped)
+
+	var b bytes.Buffer
+	r.WriteText(&b)
+
+	for _, expected := range []string{"nothing_tasks_running 5\n", "nothing_tasks_monitoring 2\n", "nothing_tasks_in_queue 1\n"} {
+		if !strings.Contains(b.String(), expected) {
+			t.Fatalf("expected %q. got\n%v", expected, b.String())
+		}
+	}
+}
+
+// Tests that a running task times its requests and steps and counts failed requests by status
+func TestTaskRequestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+
+	defer server.Close()
+
+	task := NewTask(Website{Name: "Metrics Test"}, nil, nil, ModeShopifyFast, nil, nil)
+	task.Begin()
+	defer task.Finish()
+
+	task.SetStep("Adding To Cart")
+	task.Request().Get(server.URL + "/ok")
+	task.Request().Get(server.URL + "/fail")
+	task.SetStep("Payment Method")
+	task.Request().Get(server.URL + "/fail")
+
+	server.Close()
+	task.Request().Get(server.URL)
+
+	if n := requestDuration.Count("Adding To Cart", "Metrics Test", "Fast"); n != 2 {
+		t.Fatalf("expected 2 timed requests while adding to cart. got %v", n)
+	}
+
+	if n := requestErrors.Value("Metrics Test", "Fast", "429"); n != 2 {
+		t.Fatalf("expected 2 rate limited requests. got %v", n)
+	}
+
+	if n := requestErrors.Value("Metrics Test", "Fast", "error"); n != 1 {
+		t.Fatalf("expected 1 request without a response. got %v", n)
+	}
+
+	if n := stepDuration.Count("Adding To Cart", "Metrics Test", "Fast"); n != 1 {
+		t.Fatalf("expected the add to cart step to be timed once. got %v", n)
+	}
+
+	done := task.TimeFlow("submit_payment")
+	done()
+
+	if n := flowDuration.Count("submit_payment", "Metrics Test", "Fast"); n != 1 {
+		t.Fatalf("expected the flow to be timed once. got %v", n)
+	}
+}
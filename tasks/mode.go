@@ -1,9 +1,14 @@
 package tasks
 
+import (
+	"fmt"
+	"strings"
+)
+
 type TaskMode int
 
 const (
-	ModeShopifySafe = iota
+	ModeShopifySafe TaskMode = iota
 	ModeShopifyFast
 )
 
@@ -18,3 +23,15 @@ func ModeToString(mode TaskMode) string {
 		return "None"
 	}
 }
+
+// ModeFromString Returns the task mode from its stringified version (ex. "Safe" / "Fast")
+func ModeFromString(s string) (TaskMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "safe":
+		return ModeShopifySafe, nil
+	case "fast":
+		return ModeShopifyFast, nil
+	default:
+		return ModeShopifySafe, fmt.Errorf("unknown task mode: %v", s)
+	}
+}
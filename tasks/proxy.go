@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"Mystery/proxies"
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultRotateProxyAfter The amount of proxy errors in a row after which new tasks switch proxy
+const DefaultRotateProxyAfter = 3
+
+// GetProxy Returns the proxy the task is using, or nil if it's using localhost
+func (t *Task) GetProxy() *proxies.Proxy {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.proxy
+}
+
+func (t *Task) setProxy(proxy *proxies.Proxy) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.proxy = proxy
+	t.proxyErrors = 0
+	t.rotateProxy = false
+}
+
+// Reports how every request through the task's proxy went to its proxy list. Ban statuses and connection errors cool
+// the proxy down, and enough of them in a row make the task switch proxy before its next request.
+func (t *Task) trackProxyHealth() {
+	t.Client.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		if proxies.IsBanStatus(resp.StatusCode()) {
+			t.proxyFailed()
+		} else {
+			t.proxyWorked()
+		}
+
+		return nil
+	})
+
+	t.Client.OnError(func(req *resty.Request, err error) {
+		if !isTransportError(err) {
+			return
+		}
+
+		t.proxyFailed()
+	})
+}
+
+func (t *Task) proxyFailed() {
+	t.mutex.Lock()
+	proxy := t.proxy
+	list := t.ProxyList
+
+	if proxy == nil || list == nil {
+		t.mutex.Unlock()
+		return
+	}
+
+	t.proxyErrors++
+
+	if t.RotateProxyAfter > 0 && t.proxyErrors >= t.RotateProxyAfter {
+		t.rotateProxy = true
+	}
+
+	t.mutex.Unlock()
+	list.MarkFailure(proxy)
+}
+
+func (t *Task) proxyWorked() {
+	t.mutex.Lock()
+	proxy := t.proxy
+	list := t.ProxyList
+
+	if proxy == nil || list == nil {
+		t.mutex.Unlock()
+		return
+	}
+
+	t.proxyErrors = 0
+	t.mutex.Unlock()
+	list.MarkSuccess(proxy)
+}
+
+// Returns if the task should switch proxy before its next request, clearing the flag
+func (t *Task) takeProxyRotation() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	rotate := t.rotateProxy
+	t.rotateProxy = false
+	return rotate
+}
@@ -0,0 +1,73 @@
+package tasks
+
+import (
+	"Mystery/proxies"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Tests that a task with an empty proxy list uses localhost instead of failing
+func TestSelectProxyEmptyList(t *testing.T) {
+	list := proxies.NewProxyList("Empty", "")
+	task := NewTask(Website{}, nil, &list, ModeShopifySafe, nil, nil)
+	task.SelectProxy()
+
+	if task.GetProxy() != nil {
+		t.Fatalf("expected no proxy. got %v", task.GetProxy())
+	}
+}
+
+// Tests that a task switches proxy after enough ban statuses in a row and that the banned proxy cools down
+func TestProxyRotation(t *testing.T) {
+	var raw []string
+
+	for _, status := range []int{430, http.StatusOK} {
+		status := status
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		defer server.Close()
+		raw = append(raw, strings.TrimPrefix(server.URL, "http://"))
+	}
+
+	list := proxies.NewProxyList("Test", strings.Join(raw, "\n"))
+	task := NewTask(Website{Name: "Proxy Test"}, nil, &list, ModeShopifySafe, nil, nil)
+	task.RotateProxyAfter = 2
+	task.Begin()
+	defer task.Finish()
+
+	task.SelectProxy()
+
+	if task.GetProxy() != list.Proxies[0] {
+		t.Fatalf("expected the first proxy")
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := task.Request().Get("http://example.com")
+
+		if err != nil || resp.StatusCode() != 430 {
+			t.Fatalf("expected a ban status through the first proxy. got %v %v", resp.StatusCode(), err)
+		}
+
+		if task.GetProxy() != list.Proxies[0] {
+			t.Fatalf("expected the task to keep its proxy until its next request")
+		}
+	}
+
+	if !list.IsBanned(list.Proxies[0]) || list.Health(list.Proxies[0]).Failures != 2 {
+		t.Fatalf("expected the banned proxy to be cooling down. got %+v", list.Health(list.Proxies[0]))
+	}
+
+	resp, err := task.Request().Get("http://example.com")
+
+	if err != nil || resp.StatusCode() != http.StatusOK || task.GetProxy() != list.Proxies[1] {
+		t.Fatalf("expected the task to switch to the second proxy. got %v %v", resp.StatusCode(), err)
+	}
+
+	if list.Health(list.Proxies[1]).Successes != 1 {
+		t.Fatalf("expected the second proxy to be marked as working")
+	}
+}
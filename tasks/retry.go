@@ -0,0 +1,213 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type RetryStep string
+
+const (
+	RetryStepMonitor    RetryStep = "monitor"
+	RetryStepCart       RetryStep = "cart"
+	RetryStepCheckout   RetryStep = "checkout"
+	RetryStepQueue      RetryStep = "queue"
+	RetryStepContact    RetryStep = "contact"
+	RetryStepShipping   RetryStep = "shipping"
+	RetryStepTaxes      RetryStep = "taxes"
+	RetryStepPayment    RetryStep = "payment"
+	RetryStepProcessing RetryStep = "processing"
+
+	// Polling steps wait on the server to finish something rather than retrying after an error.
+	RetryStepPollShipping   RetryStep = "poll_shipping"
+	RetryStepPollTaxes      RetryStep = "poll_taxes"
+	RetryStepPollQueue      RetryStep = "poll_queue"
+	RetryStepPollProcessing RetryStep = "poll_processing"
+
+	// Waits before submitting payment again after a decline. The amount of resubmissions is set by Task.PaymentRetries.
+	RetryStepDecline RetryStep = "decline"
+)
+
+// RetrySteps Every step that can have its own retry policy
+var RetrySteps = []RetryStep{
+	RetryStepMonitor, RetryStepCart, RetryStepCheckout, RetryStepQueue, RetryStepContact, RetryStepShipping, RetryStepTaxes,
+	RetryStepPayment, RetryStepProcessing, RetryStepPollShipping, RetryStepPollTaxes, RetryStepPollQueue, RetryStepPollProcessing,
+	RetryStepDecline,
+}
+
+type RetryPolicy struct {
+	DelayMs         int                 `json:"delay_ms"`         // The delay before the first retry.
+	MaxDelayMs      int                 `json:"max_delay_ms"`     // The longest the delay can grow to with backoff. Leave at 0 for no limit.
+	Backoff         float64             `json:"backoff"`          // The delay is multiplied by this after every attempt. Values below 1 keep the delay constant.
+	Jitter          float64             `json:"jitter"`           // Randomly changes the delay by up to this fraction of it (ex. 0.2 = +/- 20%).
+	MaxAttempts     int                 `json:"max_attempts"`     // The amount of attempts before the step fails. Leave at 0 to retry forever.
+	StatusOverrides map[int]RetryPolicy `json:"status_overrides"` // Policies to use instead when a request fails with a specific HTTP status.
+}
+
+type RetryPolicies struct {
+	Default *RetryPolicy              `json:"default"`
+	Steps   map[RetryStep]RetryPolicy `json:"steps"`
+}
+
+// DefaultRetryPolicies The policies used when neither the task nor its group has one for a step. Its step policies are
+// still used before a task's or group's default.
+var DefaultRetryPolicies = RetryPolicies{
+	Default: &RetryPolicy{DelayMs: 3500, MaxAttempts: 20},
+	Steps: map[RetryStep]RetryPolicy{
+		RetryStepMonitor:        {DelayMs: 3500},
+		RetryStepPollShipping:   {DelayMs: 1000},
+		RetryStepPollTaxes:      {DelayMs: 1},
+		RetryStepPollQueue:      {DelayMs: 3000},
+		RetryStepPollProcessing: {DelayMs: 1000},
+		RetryStepDecline:        {DelayMs: 3000},
+	},
+}
+
+// Validate Returns an error if the policy has negative values
+func (p RetryPolicy) Validate() error {
+	if p.DelayMs < 0 || p.MaxDelayMs < 0 || p.MaxAttempts < 0 || p.Backoff < 0 || p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("delays, attempts and backoff cannot be negative and jitter must be between 0 and 1")
+	}
+
+	for status, override := range p.StatusOverrides {
+		if err := override.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("status %v: %v", status, err))
+		}
+	}
+
+	return nil
+}
+
+// Validate Returns an error if any of the policies are invalid or are for a step that doesn't exist
+func (p *RetryPolicies) Validate() error {
+	if p.Default != nil {
+		if err := p.Default.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("default retry policy: %v", err))
+		}
+	}
+
+	for step, policy := range p.Steps {
+		known := false
+
+		for _, s := range RetrySteps {
+			if s == step {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			return errors.New(fmt.Sprintf("unknown retry step %q", step))
+		}
+
+		if err := policy.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("%v retry policy: %v", step, err))
+		}
+	}
+
+	return nil
+}
+
+// ForStatus Returns the policy to use after a request failed with the given status code
+func (p RetryPolicy) ForStatus(status int) RetryPolicy {
+	override, ok := p.StatusOverrides[status]
+
+	if !ok {
+		return p
+	}
+
+	if override.MaxAttempts == 0 {
+		override.MaxAttempts = p.MaxAttempts
+	}
+
+	return override
+}
+
+// GetDelay Returns how long to wait before the given attempt. Attempts start at 1.
+func (p RetryPolicy) GetDelay(attempt int) time.Duration {
+	delay := float64(p.DelayMs) * float64(time.Millisecond)
+
+	if p.Backoff > 1 && attempt > 1 {
+		delay *= math.Pow(p.Backoff, float64(attempt-1))
+	}
+
+	if p.MaxDelayMs > 0 && delay > float64(p.MaxDelayMs)*float64(time.Millisecond) {
+		delay = float64(p.MaxDelayMs) * float64(time.Millisecond)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	if delay < 0 {
+		return 0
+	}
+
+	return time.Duration(delay)
+}
+
+// GetRetryPolicy Returns the policy for a step. Policies for the step itself take precedence over default policies, so a
+// task's or group's default doesn't replace the built-in policy of a step like monitoring. The task's own policies take
+// precedence over its group's.
+func (t *Task) GetRetryPolicy(step RetryStep) RetryPolicy {
+	if policy, ok := t.RetryPolicies.Steps[step]; ok {
+		return policy
+	}
+
+	if t.Group != nil {
+		if policy, ok := t.Group.RetryPolicies.Steps[step]; ok {
+			return policy
+		}
+	}
+
+	if policy, ok := DefaultRetryPolicies.Steps[step]; ok {
+		return policy
+	}
+
+	if t.RetryPolicies.Default != nil {
+		return *t.RetryPolicies.Default
+	}
+
+	if t.Group != nil && t.Group.RetryPolicies.Default != nil {
+		return *t.Group.RetryPolicies.Default
+	}
+
+	return *DefaultRetryPolicies.Default
+}
+
+// Retry Counts a failed attempt at a step and waits before the next one. The status code of the failed request
+// is used to pick a status override, and can be 0 if there was no response. Returns false without waiting if
+// the step has run out of attempts, or false if the task was stopped while waiting.
+func (t *Task) Retry(step RetryStep, status int) bool {
+	if t.attempts == nil {
+		t.attempts = map[RetryStep]int{}
+	}
+
+	t.attempts[step]++
+	attempt := t.attempts[step]
+	policy := t.GetRetryPolicy(step).ForStatus(status)
+
+	if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+		return false
+	}
+
+	return t.Sleep(policy.GetDelay(attempt))
+}
+
+// GetAttempts Returns the amount of failed attempts at a step since it last succeeded
+func (t *Task) GetAttempts(step RetryStep) int {
+	return t.attempts[step]
+}
+
+// ResetAttempts Resets the failed attempts of a step after it has succeeded
+func (t *Task) ResetAttempts(step RetryStep) {
+	delete(t.attempts, step)
+}
+
+// ResetAllAttempts Resets the failed attempts of every step
+func (t *Task) ResetAllAttempts() {
+	t.attempts = nil
+}
@@ -0,0 +1,145 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the delay grows with backoff and is capped
+func TestRetryPolicyGetDelay(t *testing.T) {
+	policy := RetryPolicy{DelayMs: 100, MaxDelayMs: 500, Backoff: 2}
+	expected := []time.Duration{100, 200, 400, 500, 500}
+
+	for i, delay := range expected {
+		if d := policy.GetDelay(i + 1); d != delay*time.Millisecond {
+			t.Fatalf("attempt %v: expected %v. got %v", i+1, delay*time.Millisecond, d)
+		}
+	}
+
+	policy = RetryPolicy{DelayMs: 1000, Jitter: 0.2}
+
+	for i := 0; i < 100; i++ {
+		if d := policy.GetDelay(1); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("delay with jitter out of range - %v", d)
+		}
+	}
+}
+
+// Tests that status overrides replace the policy and keep its attempts if they don't set their own
+func TestRetryPolicyForStatus(t *testing.T) {
+	policy := RetryPolicy{DelayMs: 100, MaxAttempts: 5, StatusOverrides: map[int]RetryPolicy{429: {DelayMs: 10000}}}
+
+	if p := policy.ForStatus(500); p.DelayMs != 100 {
+		t.Fatalf("expected the base policy. got %+v", p)
+	}
+
+	if p := policy.ForStatus(429); p.DelayMs != 10000 || p.MaxAttempts != 5 {
+		t.Fatalf("expected the override with 5 attempts. got %+v", p)
+	}
+}
+
+// Tests that task policies take precedence over group policies, which take precedence over the defaults
+func TestGetRetryPolicy(t *testing.T) {
+	group := NewTaskGroup("Test")
+	group.RetryPolicies = RetryPolicies{
+		Default: &RetryPolicy{DelayMs: 1},
+		Steps:   map[RetryStep]RetryPolicy{RetryStepCart: {DelayMs: 2}},
+	}
+
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+
+	if p := task.GetRetryPolicy(RetryStepPollQueue); p.DelayMs != DefaultRetryPolicies.Steps[RetryStepPollQueue].DelayMs {
+		t.Fatalf("expected the default policy. got %+v", p)
+	}
+
+	group.AddTask(&task)
+
+	if p := task.GetRetryPolicy(RetryStepCart); p.DelayMs != 2 {
+		t.Fatalf("expected the group's cart policy. got %+v", p)
+	}
+
+	if p := task.GetRetryPolicy(RetryStepContact); p.DelayMs != 1 {
+		t.Fatalf("expected the group's default policy. got %+v", p)
+	}
+
+	if p := task.GetRetryPolicy(RetryStepPollQueue); p.DelayMs != DefaultRetryPolicies.Steps[RetryStepPollQueue].DelayMs {
+		t.Fatalf("expected the built-in poll queue policy. got %+v", p)
+	}
+
+	task.RetryPolicies.Steps = map[RetryStep]RetryPolicy{RetryStepCart: {DelayMs: 3}}
+
+	if p := task.GetRetryPolicy(RetryStepCart); p.DelayMs != 3 {
+		t.Fatalf("expected the task's cart policy. got %+v", p)
+	}
+}
+
+// Tests that a group's default policy doesn't limit monitoring, which retries forever by default
+func TestGetRetryPolicyDefaultKeepsBuiltInSteps(t *testing.T) {
+	group := NewTaskGroup("Test")
+	group.RetryPolicies = RetryPolicies{Default: &RetryPolicy{DelayMs: 100, MaxAttempts: 20}}
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+	group.AddTask(&task)
+
+	if p := task.GetRetryPolicy(RetryStepMonitor); p.MaxAttempts != 0 || p.DelayMs != DefaultRetryPolicies.Steps[RetryStepMonitor].DelayMs {
+		t.Fatalf("expected the built-in monitor policy. got %+v", p)
+	}
+
+	group.RetryPolicies.Steps = map[RetryStep]RetryPolicy{RetryStepMonitor: {DelayMs: 500}}
+
+	if p := task.GetRetryPolicy(RetryStepMonitor); p.DelayMs != 500 {
+		t.Fatalf("expected the group's monitor policy. got %+v", p)
+	}
+}
+
+// Tests that a step stops retrying once it runs out of attempts and starts over after being reset
+func TestTaskRetry(t *testing.T) {
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+	task.RetryPolicies.Default = &RetryPolicy{DelayMs: 1, MaxAttempts: 3}
+
+	if !task.Begin() {
+		t.Fatal("expected the task to begin")
+	}
+
+	defer task.Finish()
+
+	for i := 0; i < 2; i++ {
+		if !task.Retry(RetryStepCart, 0) {
+			t.Fatalf("attempt %v: expected to retry", i+1)
+		}
+	}
+
+	if task.Retry(RetryStepCart, 0) {
+		t.Fatal("expected to run out of attempts")
+	}
+
+	if task.GetAttempts(RetryStepCart) != 3 {
+		t.Fatalf("expected 3 attempts. got %v", task.GetAttempts(RetryStepCart))
+	}
+
+	task.ResetAttempts(RetryStepCart)
+
+	if !task.Retry(RetryStepCart, 0) {
+		t.Fatal("expected to retry after resetting")
+	}
+}
+
+// Tests that unknown steps and negative values are rejected
+func TestRetryPoliciesValidate(t *testing.T) {
+	valid := RetryPolicies{Steps: map[RetryStep]RetryPolicy{RetryStepCart: {DelayMs: 1, StatusOverrides: map[int]RetryPolicy{429: {DelayMs: 5}}}}}
+
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []RetryPolicies{
+		{Steps: map[RetryStep]RetryPolicy{"nope": {}}},
+		{Default: &RetryPolicy{DelayMs: -1}},
+		{Steps: map[RetryStep]RetryPolicy{RetryStepCart: {StatusOverrides: map[int]RetryPolicy{429: {Jitter: 2}}}}},
+	}
+
+	for i, policies := range invalid {
+		if err := policies.Validate(); err == nil {
+			t.Fatalf("policies %v: expected an error", i+1)
+		}
+	}
+}
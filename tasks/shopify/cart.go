@@ -59,7 +59,7 @@ type ClearCartResponse struct {
 // AddToCart Adds a variant to the cart
 func (t *Task) AddToCart(variant int64) (AddToCartResponse, *resty.Response, error) {
 
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "application/json, text/javascript, */*. q=0.01").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -109,7 +109,7 @@ func (t *Task) AddToCart(variant int64) (AddToCartResponse, *resty.Response, err
 
 // ClearCart Removes all items from the cart
 func (t *Task) ClearCart() (ClearCartResponse, *resty.Response, error) {
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "application/json, text/javascript, */*. q=0.01").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
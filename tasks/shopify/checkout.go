@@ -12,7 +12,7 @@ func (t *Task) CreateCheckout() (string, *resty.Response, error) {
 	// Disabling redirects here because the first redirect is always the checkout URL.
 	t.Client.SetRedirectPolicy(resty.NoRedirectPolicy())
 
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -46,7 +46,7 @@ func (t *Task) CreateCheckout() (string, *resty.Response, error) {
 	}
 
 	if strings.Contains(url.String(), "/checkouts/") {
-		t.CheckoutUrl = fmt.Sprintf("https://%v%v", url.Host, url.Path)
+		t.CheckoutUrl = fmt.Sprintf("%v://%v%v", url.Scheme, url.Host, url.Path)
 	} else {
 		t.CurrentPage = Page{Url: url.String()}
 	}
@@ -66,7 +66,7 @@ func (t *Task) CreateCheckoutFast(variant int64) (string, *resty.Response, error
 	redirectUrl := resp.RawResponse.Request.URL
 
 	if strings.Contains(redirectUrl.String(), "/checkouts/") {
-		t.CheckoutUrl = fmt.Sprintf("https://%v%v", redirectUrl.Host, redirectUrl.Path)
+		t.CheckoutUrl = fmt.Sprintf("%v://%v%v", redirectUrl.Scheme, redirectUrl.Host, redirectUrl.Path)
 	}
 
 	// If the variant is invalid, Shopify will redirect to an empty cart.
@@ -79,7 +79,7 @@ func (t *Task) CreateCheckoutFast(variant int64) (string, *resty.Response, error
 
 // GetCheckoutPage Fetches a given URL's checkout page
 func (t *Task) GetCheckoutPage(url string) (Page, *resty.Response, error) {
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -76,7 +76,7 @@ func (t *Task) SubmitContactInfo() (Page, *resty.Response, error) {
 	body.Add("checkout[client_details][java_enabled]", "false")
 	body.Add("checkout[client_details][browser_tz]", "240")
 
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -1,20 +1,122 @@
 package shopify
 
-func GetGateway(shopId int) int {
-	switch shopId {
-	// A-Ma-Maniere
-	case 6269065:
-		return 26102467
-	// Slam Jam
-	case 57677054136:
-		return 66119860408
-	// Oneness Boutique
-	case 1875180:
-		return 3919159
-	// Sneaker Politics
-	case 2147974:
-		return 73944301756
-	default:
-		return 0
+import (
+	"Mystery/utils"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// GatewayRegistry Maps shop IDs to their payment gateway IDs. Knowing a shop's gateway lets fast mode submit the
+// shipping rate and payment in a single step.
+type GatewayRegistry struct {
+	mutex    *sync.RWMutex
+	gateways map[int]int
+	path     string
+}
+
+// Gateways The registry every task reads gateways from and records newly found gateways to
+var Gateways = NewGatewayRegistry(map[int]int{
+	6269065:     26102467,    // A-Ma-Maniere
+	57677054136: 66119860408, // Slam Jam
+	1875180:     3919159,     // Oneness Boutique
+	2147974:     73944301756, // Sneaker Politics
+})
+
+// NewGatewayRegistry Creates a registry seeded with the given gateways
+func NewGatewayRegistry(seed map[int]int) *GatewayRegistry {
+	r := &GatewayRegistry{
+		mutex:    &sync.RWMutex{},
+		gateways: map[int]int{},
+	}
+
+	for shopId, gateway := range seed {
+		r.gateways[shopId] = gateway
+	}
+
+	return r
+}
+
+// Get Returns the gateway of a shop, or 0 if it isn't known
+func (r *GatewayRegistry) Get(shopId int) int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.gateways[shopId]
+}
+
+// Set Records the gateway of a shop. If the registry was loaded from a file, the file is updated.
+func (r *GatewayRegistry) Set(shopId int, gateway int) error {
+	if shopId == 0 || gateway == 0 {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.gateways[shopId] == gateway {
+		return nil
+	}
+
+	r.gateways[shopId] = gateway
+
+	if r.path == "" {
+		return nil
+	}
+
+	return r.save()
+}
+
+// All Returns a copy of every known gateway
+func (r *GatewayRegistry) All() map[int]int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	gateways := map[int]int{}
+
+	for shopId, gateway := range r.gateways {
+		gateways[shopId] = gateway
 	}
+
+	return gateways
+}
+
+// Load Adds the gateways from a JSON file of shop IDs to gateway IDs, ex. {"6269065": 26102467}.
+// Gateways learned afterwards are saved back to the file. A file that doesn't exist yet is created on the first save.
+func (r *GatewayRegistry) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var gateways map[int]int
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &gateways); err != nil {
+			return err
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for shopId, gateway := range gateways {
+		r.gateways[shopId] = gateway
+	}
+
+	r.path = path
+	return nil
+}
+
+// Writes every gateway to the registry's file. The file is replaced in one step so readers never see a partial write.
+func (r *GatewayRegistry) save() error {
+	data, err := json.MarshalIndent(r.gateways, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(r.path, data)
 }
@@ -0,0 +1,139 @@
+package shopify
+
+import (
+	"Mystery/tasks"
+	"Mystery/tasks/shopify/shopifytest"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Tests that gateways are loaded from a file and that new ones are saved back to it
+func TestGatewayRegistryLoadAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateways.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{"6269065": 26102467}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewGatewayRegistry(nil)
+
+	if err := r.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Get(6269065) != 26102467 {
+		t.Fatalf("expected the gateway from the file. got %v", r.Get(6269065))
+	}
+
+	if err := r.Set(1000000, 2000000); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved map[int]int
+
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(saved) != 2 || saved[6269065] != 26102467 || saved[1000000] != 2000000 {
+		t.Fatalf("unexpected saved gateways: %v", saved)
+	}
+}
+
+// Tests that loading a file that doesn't exist yet creates it once a gateway is learned
+func TestGatewayRegistryLoadMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateways.json")
+	r := NewGatewayRegistry(map[int]int{1: 2})
+
+	if err := r.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Set(3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewGatewayRegistry(nil)
+
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Get(1) != 2 || loaded.Get(3) != 4 {
+		t.Fatalf("unexpected loaded gateways: %v", loaded.All())
+	}
+}
+
+// Tests reading and writing gateways from many tasks at once. Meant to be run with -race.
+func TestGatewayRegistryConcurrent(t *testing.T) {
+	r := NewGatewayRegistry(nil)
+	wg := sync.WaitGroup{}
+
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+
+		go func(shopId int) {
+			defer wg.Done()
+
+			_ = r.Set(shopId, shopId*10)
+			r.Get(shopId - 1)
+			r.All()
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(r.All()) != 50 {
+		t.Fatalf("expected 50 gateways. got %v", len(r.All()))
+	}
+}
+
+// Tests that a safe mode checkout records the shop's gateway and that fast mode then uses it to skip submitting
+// the shipping rate
+func TestRunLearnsGateway(t *testing.T) {
+	server := newTestStore(t, shopifytest.Scenario{})
+
+	safe := newTestStoreTaskOn(server, tasks.ModeShopifySafe, []string{"201"}, nil)
+	safe.Run()
+
+	if gateway := Gateways.Get(int(server.ShopId)); gateway != int(server.GatewayId) {
+		t.Fatalf("expected gateway %v to be learned. got %v", server.GatewayId, gateway)
+	}
+
+	fast := newTestStoreTaskOn(server, tasks.ModeShopifyFast, []string{"201"}, nil)
+	fast.Run()
+
+	orders := server.Orders()
+
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders. got %v", len(orders))
+	}
+
+	if orders[0].ShippingWithPayment || !orders[1].ShippingWithPayment {
+		t.Fatalf("expected only the fast mode order to submit shipping with payment. got %+v", orders)
+	}
+}
+
+// Tests that the credit card gateway is submitted when gift card and PayPal gateways are listed before it
+func TestRunChoosesCardGateway(t *testing.T) {
+	server := newTestStore(t, shopifytest.Scenario{OtherGateways: true})
+	task := newTestStoreTaskOn(server, tasks.ModeShopifySafe, []string{"201"}, nil)
+	task.Run()
+
+	if orders := server.Orders(); len(orders) != 1 {
+		t.Fatalf("expected an order. got %v", len(orders))
+	}
+
+	if gateway := Gateways.Get(int(server.ShopId)); gateway != int(server.GatewayId) {
+		t.Fatalf("expected the card gateway %v to be learned. got %v", server.GatewayId, gateway)
+	}
+}
@@ -1,12 +1,12 @@
 package shopify
 
 import (
+	"Mystery/keywords"
+	"Mystery/sizes"
 	"Mystery/utils"
 	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
-	"math/rand"
-	"regexp"
 	"strconv"
 )
 
@@ -14,43 +14,72 @@ var ErrProductNotFound = errors.New("product not found")
 
 // MonitorProducts Monitors for a product to check out with all different monitoring input types
 func (t *Task) MonitorProducts() (*resty.Response, error) {
-	var keywords []string
+	target, err := parseMonitorInputs(t.MonitorInputs)
 
-	for _, input := range t.MonitorInputs {
+	if err != nil {
+		return nil, err
+	}
+
+	if target.variantId != 0 {
+		t.Variant = ProductVariant{Id: target.variantId}
+		return nil, nil
+	}
+
+	var resp *resty.Response
+	var product Product
+	var variant ProductVariant
+
+	if target.url != "" {
+		resp, product, variant, err = t.MonitorProductUrl(target.url)
+	} else {
+		resp, product, variant, err = t.MonitorProductKeywords(target.keywords)
+	}
+
+	if err != nil {
+		return resp, err
+	}
+
+	t.Product = product
+	t.Variant = variant
+	return resp, nil
+}
+
+// What a task's monitor inputs point to. The first variant or URL input is used on its own, otherwise every keyword
+// input is.
+type monitorTarget struct {
+	variantId int64
+	url       string
+	keywords  []*keywords.Query
+}
+
+// Reads the target of monitor inputs
+func parseMonitorInputs(inputs []string) (monitorTarget, error) {
+	var target monitorTarget
+
+	for _, input := range inputs {
 		switch utils.GetMonitorInputTypeFromString(input) {
 		case utils.MonitorInputTypeVariant:
 			i, err := strconv.ParseInt(input, 10, 64)
 
 			if err != nil {
-				return nil, err
+				return monitorTarget{}, err
 			}
 
-			t.Variant = ProductVariant{Id: i}
-			return nil, nil
+			return monitorTarget{variantId: i}, nil
 		case utils.MonitorInputTypeUrl:
-			resp, product, variant, err := t.MonitorProductUrl(input)
+			return monitorTarget{url: input}, nil
+		case utils.MonitorInputTypeKeywords:
+			query, err := keywords.Parse(input)
 
 			if err != nil {
-				return resp, err
+				return monitorTarget{}, errors.New(fmt.Sprintf("invalid keywords %q: %v", input, err))
 			}
 
-			t.Product = product
-			t.Variant = variant
-			return resp, nil
-		case utils.MonitorInputTypeKeywords:
-			keywords = append(keywords, input)
+			target.keywords = append(target.keywords, query)
 		}
 	}
 
-	resp, product, variant, err := t.MonitorProductKeywords(keywords)
-
-	if err != nil {
-		return resp, err
-	}
-
-	t.Product = product
-	t.Variant = variant
-	return resp, nil
+	return target, nil
 }
 
 // MonitorProductUrl Monitors for a product by URL
@@ -65,7 +94,7 @@ func (t *Task) MonitorProductUrl(url string) (*resty.Response, Product, ProductV
 		return resp, Product{}, ProductVariant{}, errors.New(fmt.Sprintf("request failed with status code: %v", resp.StatusCode()))
 	}
 
-	variant, err := getCheckoutVariant(product.Variants, t.Sizes)
+	variant, err := getCheckoutVariant(product, t.Sizes, t.VariantSelector())
 
 	if err != nil {
 		return resp, Product{}, ProductVariant{}, err
@@ -75,7 +104,7 @@ func (t *Task) MonitorProductUrl(url string) (*resty.Response, Product, ProductV
 }
 
 // MonitorProductKeywords Monitors for a product by keywords
-func (t *Task) MonitorProductKeywords(keywordSets []string) (*resty.Response, Product, ProductVariant, error) {
+func (t *Task) MonitorProductKeywords(keywordSets []*keywords.Query) (*resty.Response, Product, ProductVariant, error) {
 	resp, products, err := t.GetProducts()
 
 	if err != nil {
@@ -86,9 +115,23 @@ func (t *Task) MonitorProductKeywords(keywordSets []string) (*resty.Response, Pr
 		return resp, Product{}, ProductVariant{}, errors.New(fmt.Sprintf("request failed with status code: %v", resp.StatusCode()))
 	}
 
+	product, variant, err := findKeywordMatch(products, keywordSets, t.Sizes, t.VariantSelector())
+
+	if err != nil {
+		// No product was found at all
+		return nil, Product{}, ProductVariant{}, err
+	}
+
+	return resp, product, variant, nil
+}
+
+// Finds the first product whose title matches any of the keyword sets and picks its variant in the size range.
+// Titles are checked first and take precedence over handles. Keywords with a field are checked against that field.
+func findKeywordMatch(products []Product, keywordSets []*keywords.Query, sizeRange []string, selector *sizes.Selector) (Product, ProductVariant, error) {
 	checkKeywords := func(usingHandle bool) (Product, ProductVariant, error) {
 		for _, product := range products {
 			foundProduct := false
+			doc := productDocument(product)
 
 			// Check if any of the keyword sets match the products title or handle
 			for _, keywordSet := range keywordSets {
@@ -100,14 +143,14 @@ func (t *Task) MonitorProductKeywords(keywordSets []string) (*resty.Response, Pr
 					value = product.Title
 				}
 
-				if utils.IsKeywordMatch(value, keywordSet) {
+				if keywordSet.Match(value, doc) {
 					foundProduct = true
 					break
 				}
 			}
 
 			if foundProduct {
-				variant, err := getCheckoutVariant(product.Variants, t.Sizes)
+				variant, err := getCheckoutVariant(product, sizeRange, selector)
 
 				if err != nil {
 					return Product{}, ProductVariant{}, err
@@ -124,75 +167,69 @@ func (t *Task) MonitorProductKeywords(keywordSets []string) (*resty.Response, Pr
 	product, variant, err := checkKeywords(false)
 
 	if err == nil {
-		return resp, product, variant, nil
+		return product, variant, nil
 	}
 
 	// Now check the handles since no product was found
-	product, variant, err = checkKeywords(true)
+	return checkKeywords(true)
+}
 
-	if err == nil {
-		return resp, product, variant, nil
+// Returns the fields of a product that keywords can be limited to
+func productDocument(product Product) keywords.Document {
+	doc := keywords.Document{
+		keywords.FieldTitle:  {product.Title},
+		keywords.FieldHandle: {product.Handle},
+		keywords.FieldVendor: {product.Vendor},
+		keywords.FieldTag:    product.Tags,
 	}
 
-	// No product was found at all
-	return nil, Product{}, ProductVariant{}, err
+	for _, variant := range product.Variants {
+		doc[keywords.FieldSku] = append(doc[keywords.FieldSku], variant.Sku)
+	}
+
+	return doc
 }
 
-// Retrieves the variant that is in the size range.
-// Available / in-stock variants take precedence. If there are no in-stock items, it selects a random one within the range.
-func getCheckoutVariant(variants []ProductVariant, sizeRange []string) (ProductVariant, error) {
-	var filtered []ProductVariant
+// Retrieves the variant that is in the size range, reading the size from whichever option of the product holds it.
+// Available / in-stock variants take precedence. If there are no in-stock items, it selects one within the range.
+// The selector chooses between the variants, a nil selector chooses at random.
+func getCheckoutVariant(product Product, sizeRange []string, selector *sizes.Selector) (ProductVariant, error) {
+	matcher, err := sizes.NewMatcher(sizeRange)
 
-	// No size range specified.
-	if len(sizeRange) == 0 {
-		available := getAvailableVariants(variants)
+	if err != nil {
+		return ProductVariant{}, err
+	}
 
-		if len(available) > 0 {
-			filtered = available
-		} else {
-			filtered = variants
-		}
-		// Size range specified. Find all the variants that are within the size range.
-	} else {
-		var inSizeRange []ProductVariant
-
-		for _, variant := range variants {
-			for _, size := range sizeRange {
-				// Shoe sizes are determined if the variant option contains ANY number
-				if regexp.MustCompile(`\d`).MatchString(variant.Option1) {
-					// Isolating the numbers from the size, so we can check against that
-					regex := regexp.MustCompile("[^0-9.]+")
-					rawSize := regex.ReplaceAllString(variant.Option1, "")
-
-					if rawSize == size {
-						inSizeRange = append(inSizeRange, variant)
-					}
-				} else {
-					// Otherwise, we can safely assume it's clothing and match the exact size.
-					// Not using contains because "L" can pick up XL, XXL, etc.
-					if variant.Option1 == size {
-						inSizeRange = append(inSizeRange, variant)
-					}
-				}
-			}
+	option := product.SizeOption()
+	var inSizeRange []ProductVariant
+
+	for _, variant := range product.Variants {
+		if matcher.Match(variant.Option(option)) {
+			inSizeRange = append(inSizeRange, variant)
 		}
+	}
 
-		available := getAvailableVariants(inSizeRange)
+	filtered := getAvailableVariants(inSizeRange)
 
-		if len(available) > 0 {
-			filtered = available
-		} else {
-			filtered = inSizeRange
-		}
+	if len(filtered) == 0 {
+		filtered = inSizeRange
 	}
 
 	if len(filtered) == 0 {
 		return ProductVariant{}, errors.New("no variant found")
 	}
 
-	// Select a variant at random
-	n := rand.Int() % len(filtered)
-	return filtered[n], nil
+	if selector == nil {
+		selector = &sizes.Selector{}
+	}
+
+	choices := make([]sizes.Choice, len(filtered))
+
+	for i, variant := range filtered {
+		choices[i] = sizes.Choice{Key: strconv.FormatInt(variant.Id, 10), Option: variant.Option(option)}
+	}
+
+	return filtered[selector.Select(matcher, choices)], nil
 }
 
 // Gets only the available variants from a slice of them
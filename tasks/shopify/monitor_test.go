@@ -2,6 +2,7 @@ package shopify
 
 import (
 	"Mystery/profiles"
+	"Mystery/sizes"
 	"Mystery/tasks"
 	"Mystery/utils"
 	"strconv"
@@ -121,3 +122,135 @@ func TestKws(t *testing.T) {
 		t.Fatal("no match")
 	}
 }
+
+// Tests the target read from each type of monitor input
+func TestParseMonitorInputs(t *testing.T) {
+	target, err := parseMonitorInputs([]string{`"dunk low"`, "vendor:nike", "+jordan|dunk,-kids"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if target.variantId != 0 || target.url != "" || len(target.keywords) != 3 {
+		t.Fatalf("expected 3 keyword sets. got %+v", target)
+	}
+
+	if target, _ = parseMonitorInputs([]string{"+dunk", "39402"}); target.variantId != 39402 {
+		t.Fatalf("expected the variant input to be used. got %+v", target)
+	}
+
+	if target, _ = parseMonitorInputs([]string{"https://kith.com/products/dunk"}); target.url != "https://kith.com/products/dunk" {
+		t.Fatalf("expected the url input to be used. got %+v", target)
+	}
+
+	if _, err = parseMonitorInputs([]string{"+dunk,"}); err == nil {
+		t.Fatal("expected an error for keywords with an empty term")
+	}
+}
+
+// Tests that titles are matched before handles and that keywords with a field are matched against that field
+func TestFindKeywordMatch(t *testing.T) {
+	products := []Product{
+		{Id: 1, Title: "Dunk Low Kids", Handle: "dunk-low-kids", Vendor: "Nike", Variants: []ProductVariant{{Id: 11, Sku: "DH9765-001"}}},
+		{Id: 2, Title: "Air Jordan 1", Handle: "dunk-low-jordan", Vendor: "Jordan", Variants: []ProductVariant{{Id: 21, Sku: "DD1391-100"}}},
+		{Id: 3, Title: "Dunk Low Retro", Handle: "nike-dunk", Vendor: "Nike", Tags: []string{"mens"}, Variants: []ProductVariant{{Id: 31, Sku: "DD1391-100"}}},
+	}
+
+	tests := map[string]int64{
+		`+"dunk low",-kids`:          3,
+		`+dunk-low-j`:                2,
+		"+sku:dd1391,+vendor:nike":   3,
+		"+tag:mens|womens":           3,
+		"+vendor:jordan,+/^air\\s/":  2,
+		"+~jordn":                    2,
+		"+sku:dh9765,-vendor:jordan": 1,
+		"+/dunk-low-(kids|jordan)$/": 1,
+	}
+
+	for input, expected := range tests {
+		target, err := parseMonitorInputs([]string{input})
+
+		if err != nil {
+			t.Fatalf("%v: %v", input, err)
+		}
+
+		product, variant, err := findKeywordMatch(products, target.keywords, nil, nil)
+
+		if err != nil || product.Id != expected || variant.Id != expected*10+1 {
+			t.Fatalf("%v: expected product %v. got %v (%v)", input, expected, product.Id, err)
+		}
+	}
+
+	target, _ := parseMonitorInputs([]string{"+vendor:adidas"})
+
+	if _, _, err := findKeywordMatch(products, target.keywords, nil, nil); err != ErrProductNotFound {
+		t.Fatalf("expected no product to be found. got %v", err)
+	}
+}
+
+// Tests picking a variant by the option that holds the size
+func TestGetCheckoutVariant(t *testing.T) {
+	product := Product{
+		Options: []interface{}{"Color", "Size"},
+		Variants: []ProductVariant{
+			{Id: 1, Option1: "Black", Option2: "US 9 / EU 42.5", Available: true},
+			{Id: 2, Option1: "Black", Option2: "US 10 / EU 44", Available: false},
+			{Id: 3, Option1: "Black", Option2: "US 12W", Available: true},
+		},
+	}
+
+	tests := map[string]int64{
+		"9":       1,
+		"EU 44":   2,
+		"W 12":    3,
+		"9.5-10":  2,
+		"UK 8":    1,
+		"Size 10": 2,
+	}
+
+	for spec, expected := range tests {
+		variant, err := getCheckoutVariant(product, []string{spec}, nil)
+
+		if err != nil || variant.Id != expected {
+			t.Fatalf("%v: expected variant %v. got %v (%v)", spec, expected, variant.Id, err)
+		}
+	}
+
+	if _, err := getCheckoutVariant(product, []string{"12"}, nil); err == nil {
+		t.Fatal("expected no variant in size 12")
+	}
+
+	if _, err := getCheckoutVariant(product, []string{"10-9"}, nil); err == nil {
+		t.Fatal("expected an error for a backwards range")
+	}
+}
+
+// Tests that tasks in a group that spread across sizes choose different variants while they can
+func TestGetCheckoutVariantSpread(t *testing.T) {
+	product := Product{Variants: []ProductVariant{
+		{Id: 1, Option1: "9", Available: true},
+		{Id: 2, Option1: "10", Available: true},
+		{Id: 3, Option1: "11", Available: false},
+	}}
+
+	group := tasks.NewTaskGroup("Spread")
+	chosen := map[int64]int{}
+
+	for i := 0; i < 4; i++ {
+		task := NewTaskShopify(tasks.Website{}, nil, nil, tasks.ModeShopifySafe, nil, []string{"10", "9", "11"})
+		task.VariantStrategy = sizes.StrategySpread
+		group.AddTask(&task.Task)
+
+		variant, err := getCheckoutVariant(product, task.Sizes, task.VariantSelector())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chosen[variant.Id]++
+	}
+
+	if chosen[1] != 2 || chosen[2] != 2 {
+		t.Fatalf("expected the available variants to be chosen twice each. got %v", chosen)
+	}
+}
@@ -1,6 +1,8 @@
 package shopify
 
 import (
+	"Mystery/logs"
+	"Mystery/tasks"
 	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
@@ -31,14 +33,61 @@ const (
 	CheckoutStepCheckpoint
 	CheckoutStepLogin
 	CheckoutStepLoginChallenge
+
+	// Steps before a checkout page exists. These are never returned by Page.GetCheckoutStep.
+	CheckoutStepMonitoring
+	CheckoutStepAddingToCart
+	CheckoutStepCreatingCheckout
+	CheckoutStepDone
 )
 
+// String Returns a readable name for the checkout step
+func (s CheckoutStep) String() string {
+	switch s {
+	case CheckoutStepNone:
+		return "None"
+	case CheckoutStepContact:
+		return "Contact"
+	case CheckoutStepShippingMethod:
+		return "Shipping Method"
+	case CheckoutStepCalculatingTaxes:
+		return "Calculating Taxes"
+	case CheckoutStepPaymentMethod:
+		return "Payment Method"
+	case CheckoutStepProcessing:
+		return "Processing"
+	case CheckoutStepQueue:
+		return tasks.StepQueue
+	case CheckoutStepOrderConfirmation:
+		return "Order Confirmation"
+	case CheckoutStepCheckpoint:
+		return "Checkpoint"
+	case CheckoutStepLogin:
+		return "Login"
+	case CheckoutStepLoginChallenge:
+		return "Login Challenge"
+	case CheckoutStepMonitoring:
+		return tasks.StepMonitoring
+	case CheckoutStepAddingToCart:
+		return "Adding To Cart"
+	case CheckoutStepCreatingCheckout:
+		return "Creating Checkout"
+	case CheckoutStepDone:
+		return "Done"
+	default:
+		return fmt.Sprintf("Unknown (%d)", int(s))
+	}
+}
+
 // ErrNoShippingRateAvailable No shipping rates have not been calculated yet
 var ErrNoShippingRateAvailable = errors.New("shipping rate not found")
 var ErrNoTotalPriceFound = errors.New("total price not found")
 var ErrNoGatewayFound = errors.New("gateway not found")
 var ErrNoNoticeTextFound = errors.New("notice__text not found")
 
+var gatewaySelectRegex = regexp.MustCompile("data-select-gateway=\\\"(\\d+)\\\"")
+var gatewayIdRegex = regexp.MustCompile("payment_gateway_(\\d+)")
+
 // NewPage Creates a new page struct instance and parses the associated HTML
 func NewPage(url string, html string) Page {
 	return Page{url, html, nil}
@@ -172,22 +221,74 @@ func (p *Page) GetTotalPrice() (string, error) {
 	return rs[1], nil
 }
 
-// GetPaymentGateway Retrieves the payment gateway from the page if one exists. Otherwise, it returns 0 and an error.
+// GetPaymentGateway Retrieves the credit card payment gateway from the page if one exists. Otherwise, it returns 0 and
+// an error. Shops often list gift card and express gateways before the credit card one, so gateways that aren't for
+// cards are only used when the page has no other gateway.
 func (p *Page) GetPaymentGateway() (string, error) {
-	gateway := GetGateway(p.GetShopId())
+	var err error
+	p.Document, err = goquery.NewDocumentFromReader(strings.NewReader(p.Html))
+
+	if err == nil {
+		var card, unknown, other string
+
+		p.Document.Find("[data-select-gateway]").Each(func(i int, s *goquery.Selection) {
+			id := s.AttrOr("data-select-gateway", "")
+
+			switch gatewayKind(s) {
+			case gatewayKindCard:
+				card = firstNonEmpty(card, id)
+			case gatewayKindUnknown:
+				unknown = firstNonEmpty(unknown, id)
+			default:
+				other = firstNonEmpty(other, id)
+			}
+		})
 
-	if gateway != 0 {
-		return strconv.Itoa(gateway), nil
+		if id := firstNonEmpty(card, unknown, other); id != "" {
+			return id, nil
+		}
 	}
 
-	rgx := regexp.MustCompile("payment_gateway_(\\d+)")
-	rs := rgx.FindStringSubmatch(p.Html)
+	for _, rgx := range []*regexp.Regexp{gatewaySelectRegex, gatewayIdRegex} {
+		rs := rgx.FindStringSubmatch(p.Html)
 
-	if len(rs) <= 1 {
-		return "0", ErrNoGatewayFound
+		if len(rs) > 1 {
+			return rs[1], nil
+		}
 	}
 
-	return rs[1], nil
+	return "0", ErrNoGatewayFound
+}
+
+const (
+	gatewayKindCard = iota
+	gatewayKindUnknown
+	gatewayKindOther // Gift card, express and offsite gateways, ex. PayPal.
+)
+
+// Returns what a gateway on the payment page is for, from its gateway group or its label
+func gatewayKind(s *goquery.Selection) int {
+	group, hasGroup := s.Attr("data-gateway-group")
+	text := strings.ToLower(s.Text())
+
+	switch {
+	case hasGroup && group != "direct", strings.Contains(text, "gift card"):
+		return gatewayKindOther
+	case group == "direct", strings.Contains(text, "credit card"), strings.Contains(text, "debit card"):
+		return gatewayKindCard
+	default:
+		return gatewayKindUnknown
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
 }
 
 // GetNoticeText Retrieves the notice text from the page if one exists
@@ -256,7 +357,7 @@ func (p *Page) GetShopId() int {
 	id, err := strconv.Atoi(rs[1])
 
 	if err != nil {
-		fmt.Println(err)
+		logs.Error("Invalid shop ID", "url", p.Url, "error", err)
 		return 0
 	}
 
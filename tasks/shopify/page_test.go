@@ -50,6 +50,35 @@ func TestGetPaymentGateway(t *testing.T) {
 	t.Log(fmt.Sprintf("Gateway: %v", gateway))
 }
 
+// Tests that the credit card gateway is chosen over gift card and express gateways listed before it
+func TestGetPaymentGatewayCard(t *testing.T) {
+	tests := map[string]string{
+		`<div data-select-gateway="1"><label>Gift card</label></div>
+<div data-select-gateway="2" data-gateway-group="express"><label>Shop Pay</label></div>
+<div data-select-gateway="3" data-gateway-group="direct"><label>Credit card</label></div>`: "3",
+		`<div data-select-gateway="1" data-gateway-group="offsite"><label>PayPal</label></div>
+<div data-select-gateway="2"><label>Credit card</label></div>`: "2",
+		`<div data-select-gateway="1"><label>Gift card</label></div>
+<div data-select-gateway="2"><label>Pay now</label></div>`: "2",
+		`<div data-select-gateway="1" data-gateway-group="offsite"><label>PayPal</label></div>`: "1",
+		`<input type="radio" id="checkout_payment_gateway_4">`:                                  "4",
+	}
+
+	for html, expected := range tests {
+		p := Page{Html: html}
+
+		if gateway, err := p.GetPaymentGateway(); err != nil || gateway != expected {
+			t.Fatalf("%v: expected gateway %v. got %v %v", html, expected, gateway, err)
+		}
+	}
+
+	p := Page{Html: "<p>No gateways</p>"}
+
+	if _, err := p.GetPaymentGateway(); err != ErrNoGatewayFound {
+		t.Fatalf("expected no gateway. got %v", err)
+	}
+}
+
 // Tests getting the notice text from the page
 func TestGetNoticeText(t *testing.T) {
 	p := Page{
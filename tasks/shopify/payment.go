@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"github.com/go-resty/resty/v2"
 	"math"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
+// DepositUrl The URL payment sessions are created at. Only changed when testing against a fake store.
+var DepositUrl = "https://deposit.us.shopifycs.com/sessions"
+
 // LoadPaymentPage Retrieves the payment page
 func (t *Task) LoadPaymentPage() (*resty.Response, error) {
 	_, resp, err := t.GetCheckoutPage(fmt.Sprintf("%v?step=payment_method", t.CheckoutUrl))
@@ -28,13 +32,19 @@ func (t *Task) LoadPaymentPage() (*resty.Response, error) {
 
 // GetPaymentToken Retrieves the payment session id from deposits
 func (t *Task) GetPaymentToken() (string, *resty.Response, error) {
-	resp, err := t.Client.R().
+	depositUrl, err := url.Parse(DepositUrl)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	resp, err := t.Request().
 		SetHeader("Accept", "application/json").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
 		SetHeader("Connection", "keep-alive").
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Host", "deposit.us.shopifycs.com").
+		SetHeader("Host", depositUrl.Host).
 		SetHeader("Origin", "https://checkout.shopifycs.com").
 		SetHeader("Referer", "https://checkout.shopifycs.com/").
 		SetHeader("Sec-Fetch-Dest", "empty").
@@ -54,7 +64,7 @@ func (t *Task) GetPaymentToken() (string, *resty.Response, error) {
 			},
 			"payment_session_scope": t.Site.GetHostName(),
 		}).
-		Post("https://deposit.us.shopifycs.com/sessions")
+		Post(DepositUrl)
 
 	if err != nil {
 		return "", resp, err
@@ -81,8 +91,9 @@ func (t *Task) GetPaymentToken() (string, *resty.Response, error) {
 // SubmitPayment Submits the payment
 func (t *Task) SubmitPayment(fast bool, sessionId string, rate string) (Page, *resty.Response, error) {
 	token, _ := t.CurrentPage.GetAuthenticityToken()
-	gateway, _ := t.CurrentPage.GetPaymentGateway()
+	gateway := t.getPaymentGateway()
 	price := t.GetTotalPaymentPrice()
+	t.PaymentPrice = price
 
 	body := utils.FormBody{}
 	body.Add("_method", "patch")
@@ -138,7 +149,7 @@ func (t *Task) SubmitPayment(fast bool, sessionId string, rate string) (Page, *r
 	body.Add("checkout[client_details][java_enabled]", "false")
 	body.Add("checkout[client_details][browser_tz]", "240")
 
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -178,6 +189,31 @@ func (t *Task) SubmitPayment(fast bool, sessionId string, rate string) (Page, *r
 	return page, resp, nil
 }
 
+// Returns the payment gateway from the current page, falling back to the gateway registry if the page doesn't have one.
+// Gateways found on a page are recorded so that later fast mode tasks on the same shop can skip submitting the shipping rate.
+func (t *Task) getPaymentGateway() string {
+	shopId := t.CurrentPage.GetShopId()
+	gateway, err := t.CurrentPage.GetPaymentGateway()
+
+	if err == nil {
+		if id, err := strconv.Atoi(gateway); err == nil && shopId != 0 && Gateways.Get(shopId) != id {
+			t.Log(fmt.Sprintf("Found payment gateway %v for shop %v", id, shopId))
+
+			if err := Gateways.Set(shopId, id); err != nil {
+				t.Log(err)
+			}
+		}
+
+		return gateway
+	}
+
+	if id := Gateways.Get(shopId); id != 0 {
+		return strconv.Itoa(id)
+	}
+
+	return gateway
+}
+
 // GetTotalPaymentPrice Attempts to retrieve the total payment price on the page.
 func (t *Task) GetTotalPaymentPrice() string {
 	price, err := t.CurrentPage.GetTotalPrice()
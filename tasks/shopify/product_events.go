@@ -0,0 +1,112 @@
+package shopify
+
+import (
+	"Mystery/history"
+	"Mystery/notify"
+	"Mystery/tasks"
+	"fmt"
+	"time"
+)
+
+type ProductEventType string
+
+const (
+	ProductEventNew         ProductEventType = "new_product"
+	ProductEventNewVariant  ProductEventType = "new_variant" // A product that was already known got a new variant.
+	ProductEventRestock     ProductEventType = "restock"
+	ProductEventSoldOut     ProductEventType = "sold_out"
+	ProductEventPriceChange ProductEventType = "price_change"
+)
+
+// ProductEvent A change between two snapshots of a site's products
+type ProductEvent struct {
+	Type    ProductEventType
+	Time    time.Time
+	Site    tasks.Website
+	Product Product        // The product after the change.
+	Variant ProductVariant // The variant after the change. Empty for new products.
+	Before  ProductVariant // The variant before the change. Empty for new products and variants.
+}
+
+// DiffProducts Compares two snapshots of a site's products and returns what changed, in the order of the new snapshot.
+// Products missing from the new snapshot aren't reported since /products.json only lists the most recent ones.
+func DiffProducts(before []Product, after []Product) []ProductEvent {
+	previous := map[int64]Product{}
+
+	for _, product := range before {
+		previous[product.Id] = product
+	}
+
+	var events []ProductEvent
+
+	for _, product := range after {
+		old, ok := previous[product.Id]
+
+		if !ok {
+			events = append(events, ProductEvent{Type: ProductEventNew, Product: product})
+			continue
+		}
+
+		oldVariants := map[int64]ProductVariant{}
+
+		for _, variant := range old.Variants {
+			oldVariants[variant.Id] = variant
+		}
+
+		for _, variant := range product.Variants {
+			oldVariant, ok := oldVariants[variant.Id]
+
+			if !ok {
+				events = append(events, ProductEvent{Type: ProductEventNewVariant, Product: product, Variant: variant})
+				continue
+			}
+
+			if !oldVariant.Available && variant.Available {
+				events = append(events, ProductEvent{Type: ProductEventRestock, Product: product, Variant: variant, Before: oldVariant})
+			} else if oldVariant.Available && !variant.Available {
+				events = append(events, ProductEvent{Type: ProductEventSoldOut, Product: product, Variant: variant, Before: oldVariant})
+			}
+
+			if oldVariant.PriceCents() != variant.PriceCents() {
+				events = append(events, ProductEvent{Type: ProductEventPriceChange, Product: product, Variant: variant, Before: oldVariant})
+			}
+		}
+	}
+
+	return events
+}
+
+// Notification Returns the event as it is sent to notifiers
+func (e *ProductEvent) Notification() notify.Event {
+	var eventType notify.EventType
+	var before, after string
+
+	switch e.Type {
+	case ProductEventNew:
+		eventType = notify.EventProductNew
+	case ProductEventNewVariant:
+		eventType = notify.EventVariantNew
+	case ProductEventRestock:
+		eventType = notify.EventVariantRestock
+		before, after = "Sold Out", "Available"
+	case ProductEventSoldOut:
+		eventType = notify.EventVariantSoldOut
+		before, after = "Available", "Sold Out"
+	case ProductEventPriceChange:
+		eventType = notify.EventPriceChange
+		before, after = history.FormatPrice(e.Before.PriceCents()), history.FormatPrice(e.Variant.PriceCents())
+	}
+
+	n := notify.NewProductEvent(eventType, notify.ProductEvent{
+		Site:         e.Site.Name,
+		ProductTitle: e.Product.Title,
+		ProductUrl:   fmt.Sprintf("%v/products/%v", e.Site.Url, e.Product.Handle),
+		Size:         e.Variant.Option1,
+		VariantId:    e.Variant.Id,
+		Before:       before,
+		After:        after,
+	})
+
+	n.Time = e.Time
+	return n
+}
@@ -0,0 +1,90 @@
+package shopify
+
+import (
+	"Mystery/notify"
+	"Mystery/tasks"
+	"testing"
+)
+
+// Tests every type of change between two product lists
+func TestDiffProducts(t *testing.T) {
+	before := []Product{
+		{Id: 1, Title: "Dunk", Variants: []ProductVariant{
+			{Id: 101, Option1: "9", Available: true, Price: "110.00"},
+			{Id: 102, Option1: "10", Available: false, Price: "110.00"},
+			{Id: 103, Option1: "11", Available: true, Price: "110.00"},
+		}},
+		{Id: 2, Title: "Hoodie", Variants: []ProductVariant{{Id: 201, Option1: "M", Available: true, Price: "90.00"}}},
+	}
+
+	after := []Product{
+		{Id: 3, Title: "Jordan 1", Variants: []ProductVariant{{Id: 301, Option1: "9", Available: true, Price: "180.00"}}},
+		{Id: 1, Title: "Dunk", Variants: []ProductVariant{
+			{Id: 101, Option1: "9", Available: false, Price: "110.00"},
+			{Id: 102, Option1: "10", Available: true, Price: "120.00"},
+			{Id: 103, Option1: "11", Available: true, Price: "110.0"},
+			{Id: 104, Option1: "12", Available: true, Price: "110.00"},
+		}},
+	}
+
+	events := DiffProducts(before, after)
+	expected := []struct {
+		eventType ProductEventType
+		variant   int64
+	}{
+		{ProductEventNew, 0},
+		{ProductEventSoldOut, 101},
+		{ProductEventRestock, 102},
+		{ProductEventPriceChange, 102},
+		{ProductEventNewVariant, 104},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v events. got %+v", len(expected), events)
+	}
+
+	for i, e := range expected {
+		if events[i].Type != e.eventType || events[i].Variant.Id != e.variant {
+			t.Fatalf("event %v: expected %v of %v. got %v of %v", i, e.eventType, e.variant, events[i].Type, events[i].Variant.Id)
+		}
+	}
+
+	if price := events[3]; price.Before.PriceCents() != 11000 || price.Variant.PriceCents() != 12000 {
+		t.Fatalf("expected the price change to carry both prices. got %v and %v", price.Before.Price, price.Variant.Price)
+	}
+
+	if len(DiffProducts(after, after)) != 0 {
+		t.Fatal("expected no events between identical lists")
+	}
+}
+
+// Tests the notification a price change is sent as
+func TestProductEventNotification(t *testing.T) {
+	e := ProductEvent{
+		Type:    ProductEventPriceChange,
+		Site:    tasks.Website{Name: "Kith", Url: "https://kith.com"},
+		Product: Product{Title: "Dunk", Handle: "dunk"},
+		Variant: ProductVariant{Id: 102, Option1: "10", Price: "120.00"},
+		Before:  ProductVariant{Id: 102, Option1: "10", Price: "110.00"},
+	}
+
+	n := e.Notification()
+
+	if n.Type != notify.EventPriceChange || n.Product == nil {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+
+	expected := notify.ProductEvent{
+		Site:         "Kith",
+		ProductTitle: "Dunk",
+		ProductUrl:   "https://kith.com/products/dunk",
+		Size:         "10",
+		VariantId:    102,
+		Before:       "110.00",
+		After:        "120.00",
+	}
+
+	if *n.Product != expected {
+		t.Fatalf("expected %+v. got %+v", expected, *n.Product)
+	}
+}
@@ -1,9 +1,13 @@
 package shopify
 
 import (
+	"Mystery/sizes"
 	"encoding/json"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"math"
+	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -30,6 +34,7 @@ type Product struct {
 	CompareAtPriceMax    json.Number      `json:"compare_at_price_max,omitempty"`
 	CompareAtPriceVaries interface{}      `json:"compare_at_price_varies,omitempty"`
 	Variants             []ProductVariant `json:"variants,omitempty"`
+	Options              []interface{}    `json:"options,omitempty"`
 }
 
 type ProductVariant struct {
@@ -52,9 +57,68 @@ type ProductVariant struct {
 	UpdatedAt        time.Time   `json:"updated_at,omitempty"`
 }
 
+// SizeOption Returns which option of the product's variants holds the size, from 0 for Option1 to 2 for Option3.
+// Defaults to Option1 when no option looks like a size.
+func (p *Product) SizeOption() int {
+	variantOptions := make([][]string, len(p.Variants))
+
+	for i, variant := range p.Variants {
+		variantOptions[i] = []string{variant.Option1, variant.Option2, variant.Option3}
+	}
+
+	if option := sizes.DetectOption(sizes.OptionNames(p.Options), variantOptions); option != -1 {
+		return option
+	}
+
+	return 0
+}
+
+// Option Returns the variant's option from 0 for Option1 to 2 for Option3
+func (v *ProductVariant) Option(i int) string {
+	switch i {
+	case 1:
+		return v.Option2
+	case 2:
+		return v.Option3
+	}
+
+	return v.Option1
+}
+
+// PriceCents Returns the price of the variant in cents. /products.json has prices as strings in dollars, ex. "110.00",
+// while <product>.js has them as numbers in cents. Returns 0 if the price is missing.
+func (v *ProductVariant) PriceCents() int64 {
+	switch price := v.Price.(type) {
+	case string:
+		if dollars, err := strconv.ParseFloat(price, 64); err == nil {
+			return int64(math.Round(dollars * 100))
+		}
+	case float64:
+		return int64(math.Round(price))
+	}
+
+	return 0
+}
+
 // GetProducts Fetches the most recent loaded products
 func (t *Task) GetProducts() (*resty.Response, []Product, error) {
-	resp, err := t.Client.R().
+	return t.GetProductsIfChanged("", "")
+}
+
+// GetProductsIfChanged Fetches the most recent loaded products unless they haven't changed since the response with the
+// given ETag or Last-Modified header. The response has status 304 and no products are returned when they haven't.
+func (t *Task) GetProductsIfChanged(etag string, lastModified string) (*resty.Response, []Product, error) {
+	req := t.Request()
+
+	if etag != "" {
+		req.SetHeader("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		req.SetHeader("If-Modified-Since", lastModified)
+	}
+
+	resp, err := req.
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -76,7 +140,7 @@ func (t *Task) GetProducts() (*resty.Response, []Product, error) {
 		return resp, nil, err
 	}
 
-	if resp.IsError() {
+	if resp.IsError() || resp.StatusCode() == http.StatusNotModified {
 		return resp, nil, nil
 	}
 
@@ -96,7 +160,7 @@ func (t *Task) GetProducts() (*resty.Response, []Product, error) {
 
 // GetProductSpecific Fetch a specific product's information
 func (t *Task) GetProductSpecific(url string) (*resty.Response, Product, error) {
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -53,7 +53,7 @@ func (t *Task) PollQueue() (PollQueueResponse, *resty.Response, error) {
 		return PollQueueResponse{}, nil, errors.New("no checkout queue token cookie found")
 	}
 
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "*/*").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -0,0 +1,245 @@
+package shopify
+
+import (
+	"Mystery/history"
+	"Mystery/tasks"
+	"Mystery/tasks/shopify/shopifytest"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+var testStoreProducts = []shopifytest.Product{
+	{
+		Id:     1,
+		Title:  "Nike Dunk Low Retro - Panda",
+		Handle: "nike-dunk-low-retro-panda",
+		Vendor: "Nike",
+		Variants: []shopifytest.Variant{
+			{Id: 101, Title: "9", Sku: "DD1391-100-9", Price: 11000, Available: true},
+			{Id: 102, Title: "10", Sku: "DD1391-100-10", Price: 11000, Available: true},
+		},
+	},
+	{
+		Id:     2,
+		Title:  "Essentials Hoodie",
+		Handle: "essentials-hoodie",
+		Vendor: "Fear of God",
+		Variants: []shopifytest.Variant{
+			{Id: 201, Title: "M", Price: 9000, Available: true},
+		},
+	},
+}
+
+// Starts a fake store and points payments and the gateway registry at it for the rest of the test
+func newTestStore(t *testing.T, scenario shopifytest.Scenario) *shopifytest.Server {
+	server := shopifytest.NewServer(testStoreProducts, scenario)
+	t.Cleanup(server.Close)
+
+	depositUrl, gateways := DepositUrl, Gateways
+	DepositUrl, Gateways = server.DepositUrl(), NewGatewayRegistry(nil)
+
+	t.Cleanup(func() {
+		DepositUrl, Gateways = depositUrl, gateways
+	})
+
+	return server
+}
+
+// Creates a task that checks out on a fake store with retry delays short enough for tests
+func newTestStoreTaskOn(server *shopifytest.Server, mode tasks.TaskMode, inputs []string, sizes []string) *Task {
+	task := NewTaskShopify(tasks.Website{Name: "Fake Store", Url: server.URL}, &testProfile, nil, mode, inputs, sizes)
+	task.RetryPolicies.Default = &tasks.RetryPolicy{DelayMs: 1, MaxAttempts: 10}
+	return task
+}
+
+// Creates a task on a new fake store
+func newTestStoreTask(t *testing.T, scenario shopifytest.Scenario, mode tasks.TaskMode, inputs []string, sizes []string) (*Task, *shopifytest.Server) {
+	server := newTestStore(t, scenario)
+	return newTestStoreTaskOn(server, mode, inputs, sizes), server
+}
+
+// Runs a task and checks that it placed a single order for the given variant
+func runAndExpectOrder(t *testing.T, task *Task, server *shopifytest.Server, variant int64) {
+	task.Run()
+
+	if status := task.GetStatus(); status.Value != "Checked Out!" {
+		t.Fatalf("expected the task to check out. got %q", status.Value)
+	}
+
+	orders := server.Orders()
+
+	if len(orders) != 1 {
+		t.Fatalf("expected 1 order. got %v", len(orders))
+	}
+
+	if len(orders[0].Items) != 1 || orders[0].Items[0].VariantId != variant {
+		t.Fatalf("expected an order for variant %v. got %+v", variant, orders[0].Items)
+	}
+
+	if orders[0].Email != testProfile.ShippingAddress.Email {
+		t.Fatalf("expected the order to use the profile's email. got %v", orders[0].Email)
+	}
+}
+
+// Tests checking out a product found by keywords on safe mode
+func TestRunSafeKeywords(t *testing.T) {
+	task, server := newTestStoreTask(t, shopifytest.Scenario{}, tasks.ModeShopifySafe, []string{"+dunk,+panda"}, []string{"10"})
+	runAndExpectOrder(t, task, server, 102)
+
+	if orders := server.Orders(); orders[0].Total != 12000 {
+		t.Fatalf("expected a total of 12000 including shipping. got %v", orders[0].Total)
+	}
+}
+
+// Tests checking out a product found by its URL
+func TestRunSafeProductUrl(t *testing.T) {
+	task, server := newTestStoreTask(t, shopifytest.Scenario{}, tasks.ModeShopifySafe, []string{""}, []string{"M"})
+	task.MonitorInputs = []string{server.ProductUrl("essentials-hoodie")}
+	runAndExpectOrder(t, task, server, 201)
+}
+
+// Tests checking out through every step that makes a task wait
+func TestRunSafeQueueShippingTaxesProcessing(t *testing.T) {
+	scenario := shopifytest.Scenario{
+		MonitorMisses:     2,
+		QueuePolls:        2,
+		ShippingRatePolls: 2,
+		TaxPolls:          2,
+		ProcessingPolls:   2,
+	}
+
+	task, server := newTestStoreTask(t, scenario, tasks.ModeShopifySafe, []string{"+hoodie"}, []string{})
+	runAndExpectOrder(t, task, server, 201)
+
+	if n := server.Requests(shopifytest.RouteProducts); n != 3 {
+		t.Fatalf("expected 3 product requests. got %v", n)
+	}
+
+	if n := server.Requests(shopifytest.RouteQueuePoll); n != 3 {
+		t.Fatalf("expected 3 queue polls. got %v", n)
+	}
+
+	if n := server.Requests(shopifytest.RouteShippingRates); n != 3 {
+		t.Fatalf("expected 3 shipping rate requests. got %v", n)
+	}
+}
+
+// Tests that a task keeps trying to cart a sold out product until it restocks
+func TestRunSoldOutRestock(t *testing.T) {
+	task, server := newTestStoreTask(t, shopifytest.Scenario{SoldOutAttempts: 3}, tasks.ModeShopifySafe, []string{"101"}, []string{})
+	runAndExpectOrder(t, task, server, 101)
+
+	if n := server.Requests(shopifytest.RouteCartAdd); n != 4 {
+		t.Fatalf("expected 4 add to cart attempts. got %v", n)
+	}
+}
+
+// Tests that a declined payment is submitted again with a new payment session
+func TestRunDeclineThenSuccess(t *testing.T) {
+	task, server := newTestStoreTask(t, shopifytest.Scenario{Declines: 1, ProcessingPolls: 1}, tasks.ModeShopifySafe, []string{"201"}, []string{})
+	task.PaymentRetries = 1
+
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"))
+
+	if err != nil {
+		t.Fatalf("failed to open history: %v", err)
+	}
+
+	defer store.Close()
+
+	m := tasks.NewManager()
+	m.History = store
+	m.AddTask(&task.Task)
+	events := m.Subscribe()
+
+	runAndExpectOrder(t, task, server, 201)
+	events.Unsubscribe()
+
+	var types []tasks.TaskEventType
+
+	for e := range events.Events {
+		if e.Type != tasks.EventStatusChanged && e.Type != tasks.EventStepChanged {
+			types = append(types, e.Type)
+		}
+	}
+
+	expected := []tasks.TaskEventType{tasks.EventTaskStarted, tasks.EventProductFound, tasks.EventCarted, tasks.EventCheckoutDecline, tasks.EventCheckoutSuccess, tasks.EventTaskStopped}
+
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Fatalf("expected events %v. got %v", expected, types)
+	}
+
+	if server.Declines() != 1 {
+		t.Fatalf("expected 1 decline. got %v", server.Declines())
+	}
+
+	if n := server.Requests(shopifytest.RouteSessions); n != 2 {
+		t.Fatalf("expected 2 payment sessions. got %v", n)
+	}
+
+	records, err := store.Records(history.Filter{})
+
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+
+	if len(records) != 2 || records[0].Result != history.ResultDecline || records[1].Result != history.ResultSuccess {
+		t.Fatalf("expected a decline then a success in the history. got %+v", records)
+	}
+
+	success := records[1]
+
+	if success.TaskId != task.Id || success.Mode != "Safe" || success.OrderNumber != "#1001" || success.Price != 10000 ||
+		success.VariantId != 201 || success.Size != "M" || records[0].FailureReason == "" {
+		t.Fatalf("unexpected history records %+v", records)
+	}
+
+	if len(success.StepTimings) == 0 || success.StepTimings[0].Step != "Monitoring" {
+		t.Fatalf("expected step timings starting at monitoring. got %+v", success.StepTimings)
+	}
+}
+
+// Tests that a task stops once payment has been declined more times than it can retry
+func TestRunDeclineOutOfRetries(t *testing.T) {
+	for _, retries := range []int{0, 2} {
+		task, server := newTestStoreTask(t, shopifytest.Scenario{Declines: 10}, tasks.ModeShopifySafe, []string{"201"}, []string{})
+		task.PaymentRetries = retries
+		task.Run()
+
+		if status := task.GetStatus(); status.Value != "Payment Declined" {
+			t.Fatalf("expected the payment to be declined. got %q", status.Value)
+		}
+
+		if server.Declines() != retries+1 {
+			t.Fatalf("expected %v declines. got %v", retries+1, server.Declines())
+		}
+
+		if n := server.Requests(shopifytest.RouteSessions); n != retries+1 {
+			t.Fatalf("expected %v payment sessions. got %v", retries+1, n)
+		}
+
+		if len(server.Orders()) != 0 {
+			t.Fatal("expected no orders")
+		}
+	}
+}
+
+// Tests that a task fails once it runs out of attempts at a step
+func TestRunOutOfAttempts(t *testing.T) {
+	task, server := newTestStoreTask(t, shopifytest.Scenario{SoldOutAttempts: 100}, tasks.ModeShopifySafe, []string{"101"}, []string{})
+	task.RetryPolicies.Steps = map[tasks.RetryStep]tasks.RetryPolicy{tasks.RetryStepCart: {DelayMs: 1, MaxAttempts: 3}}
+	task.Run()
+
+	if status := task.GetStatus(); status.Value != "Out Of Attempts (cart)" {
+		t.Fatalf("expected the task to run out of attempts. got %q", status.Value)
+	}
+
+	if n := server.Requests(shopifytest.RouteCartAdd); n != 3 {
+		t.Fatalf("expected 3 add to cart attempts. got %v", n)
+	}
+
+	if len(server.Orders()) != 0 {
+		t.Fatal("expected no orders")
+	}
+}
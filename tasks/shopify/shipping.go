@@ -10,7 +10,7 @@ import (
 
 // FetchShippingRate Attempts to fetch the shipping rate
 func (t *Task) FetchShippingRate() (string, *resty.Response, error) {
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "*/*").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -64,7 +64,7 @@ func (t *Task) SubmitShippingRate(rate string) (Page, *resty.Response, error) {
 	body.Add("checkout[client_details][java_enabled]", "false")
 	body.Add("checkout[client_details][browser_tz]", "240")
 
-	resp, err := t.Client.R().
+	resp, err := t.Request().
 		SetHeader("Accept", "text/html,application/xhtml+xml,application/xml.q=0.9,image/avif,image/webp,image/apng,*/*.q=0.8,application/signed-exchange.v=b3.q=0.9").
 		SetHeader("Accept-Encoding", "gzip, deflate").
 		SetHeader("Accept-Language", "en-US,en.q=0.9").
@@ -0,0 +1,166 @@
+package shopifytest
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+type productJson struct {
+	Id          int64         `json:"id"`
+	Title       string        `json:"title"`
+	Handle      string        `json:"handle"`
+	Vendor      string        `json:"vendor"`
+	ProductType string        `json:"product_type"`
+	Tags        []string      `json:"tags"`
+	Available   bool          `json:"available"`
+	Variants    []variantJson `json:"variants"`
+}
+
+type variantJson struct {
+	Id        int64       `json:"id"`
+	Title     string      `json:"title"`
+	Option1   string      `json:"option1"`
+	Sku       string      `json:"sku"`
+	Available bool        `json:"available"`
+	Price     interface{} `json:"price"`
+	ProductId int64       `json:"product_id"`
+}
+
+// Converts a product to the format of /products.json, or the format of <product>.js which has prices in cents
+func toProductJson(p Product, cents bool) productJson {
+	j := productJson{
+		Id:          p.Id,
+		Title:       p.Title,
+		Handle:      p.Handle,
+		Vendor:      p.Vendor,
+		ProductType: p.Type,
+		Tags:        p.Tags,
+		Variants:    []variantJson{},
+	}
+
+	if j.Tags == nil {
+		j.Tags = []string{}
+	}
+
+	for _, v := range p.Variants {
+		var price interface{} = formatPrice(v.Price)
+
+		if cents {
+			price = v.Price
+		}
+
+		j.Available = j.Available || v.Available
+		j.Variants = append(j.Variants, variantJson{v.Id, v.Title, v.Title, v.Sku, v.Available, price, p.Id})
+	}
+
+	return j
+}
+
+const queuePage = `<!DOCTYPE html>
+<html>
+<head><title>Queue</title></head>
+<body>
+<h1>You're in line to check out</h1>
+<p>Please don't refresh the page.</p>
+</body>
+</html>`
+
+const shippingRatesPending = `<div class="content-box" data-poll-refresh="[data-step=shipping_method]">
+<p>Getting available shipping rates...</p>
+</div>`
+
+const shippingRatesReady = `<div class="content-box" data-shipping-methods>
+<div class="radio-wrapper" data-shipping-method="%v">
+<input class="input-radio" data-checkout-total-shipping-target="1000" type="radio" value="%v" name="checkout[shipping_rate][id]" />
+</div>
+</div>`
+
+func (s *Server) contactPage(c *checkout) string {
+	return s.checkoutPage(c, "contact_information", s.subtotal(c), `<input type="email" name="checkout[email]">
+<input type="text" name="checkout[shipping_address][first_name]">
+<input type="text" name="checkout[shipping_address][last_name]">
+<input type="text" name="checkout[shipping_address][address1]">
+<input type="text" name="checkout[shipping_address][city]">
+<input type="text" name="checkout[shipping_address][zip]">`)
+}
+
+func (s *Server) shippingPage(c *checkout) string {
+	return s.checkoutPage(c, "shipping_method", s.subtotal(c), `<div class="section--shipping-method">
+<p>Getting available shipping rates...</p>
+</div>`)
+}
+
+func (s *Server) taxesPage(c *checkout) string {
+	return s.checkoutPage(c, "payment_method", s.subtotal(c), `<div class="section--payment-method">
+<p>Calculating taxes...</p>
+</div>`)
+}
+
+func (s *Server) paymentPage(c *checkout) string {
+	var gateways strings.Builder
+
+	if s.scenario.OtherGateways {
+		gateways.WriteString(gatewayOption(GiftCardGatewayId, "", "Gift card"))
+		gateways.WriteString(gatewayOption(PaypalGatewayId, "offsite", "PayPal"))
+	}
+
+	gateways.WriteString(gatewayOption(s.GatewayId, "direct", "Credit card"))
+	return s.checkoutPage(c, "payment_method", s.total(c), gateways.String())
+}
+
+// Renders a payment gateway the way Shopify lists them on the payment page
+func gatewayOption(id int64, group string, label string) string {
+	attrs := fmt.Sprintf(`data-select-gateway="%v"`, id)
+
+	if group != "" {
+		attrs += fmt.Sprintf(` data-gateway-group="%v"`, group)
+	}
+
+	return fmt.Sprintf(`<div class="radio-wrapper" %v>
+<input type="radio" id="checkout_payment_gateway_%v" name="checkout[payment_gateway]" value="%v">
+<label for="checkout_payment_gateway_%v">%v</label>
+</div>
+`, attrs, id, id, id, label)
+}
+
+func (s *Server) processingPage(c *checkout) string {
+	return s.checkoutPage(c, "processing", s.total(c), `<h2>Your order's being processed.</h2>`)
+}
+
+func (s *Server) thankYouPage(c *checkout) string {
+	return s.checkoutPage(c, "thank_you", c.Order.Total, fmt.Sprintf(`<span class="os-order-number">Order #%v</span>
+<h2>Thank you!</h2>`, c.Order.Number))
+}
+
+// Renders a checkout page with the parts of Shopify's markup that tasks parse. Notices are only shown once.
+func (s *Server) checkoutPage(c *checkout, step string, due int64, content string) string {
+	var b strings.Builder
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head><title>Checkout</title></head>\n<body>\n")
+	b.WriteString(fmt.Sprintf("<script>\nShopify.Checkout.step = \"%v\";\n</script>\n", step))
+
+	if c.Notice != "" {
+		b.WriteString(fmt.Sprintf("<div class=\"notice notice--error\">\n<p class=\"notice__text\">%v</p>\n</div>\n", html.EscapeString(c.Notice)))
+		c.Notice = ""
+	}
+
+	b.WriteString(fmt.Sprintf("<form method=\"post\" action=\"%v\">\n", s.checkoutPath(c)))
+	b.WriteString(fmt.Sprintf("<input type=\"hidden\" name=\"authenticity_token\" value=\"%v\">\n", c.AuthenticityToken))
+	b.WriteString(content)
+	b.WriteString("\n</form>\n<div class=\"order-summary\">\n")
+
+	for _, item := range c.Items {
+		b.WriteString(fmt.Sprintf("<span class=\"product__description__name order-summary__emphasis\">%v</span>\n", html.EscapeString(item.Product.Title)))
+		b.WriteString(fmt.Sprintf("<span class=\"product__description__variant order-summary__small-text\">%v</span>\n", html.EscapeString(item.Variant.Title)))
+	}
+
+	b.WriteString(fmt.Sprintf("<span class=\"payment-due__price\" data-checkout-payment-due-target=\"%v\">$%v</span>\n", due, formatPrice(due)))
+	b.WriteString("</div>\n</body>\n</html>")
+	return b.String()
+}
+
+// Formats cents as a price, ex. 12000 -> 120.00
+func formatPrice(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,816 @@
+// Package shopifytest Provides a fake Shopify storefront so tasks can be run end to end without a real store
+package shopifytest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Routes that requests are counted under. See Server.Requests.
+const (
+	RouteProducts      = "/products.json"
+	RouteProduct       = "/products/{handle}.js"
+	RouteCartAdd       = "/cart/add.js"
+	RouteCartClear     = "/cart/clear.js"
+	RouteCheckout      = "/checkout"
+	RouteQueue         = "/throttle/queue"
+	RouteQueuePoll     = "/queue/poll"
+	RouteSessions      = "/sessions"
+	RouteCheckoutPage  = "/checkouts/{token}"
+	RouteShippingRates = "/checkouts/{token}/shipping_rates"
+	RouteProcessing    = "/checkouts/{token}/processing"
+	RouteThankYou      = "/checkouts/{token}/thank_you"
+)
+
+const cartCookie = "cart"
+const queueCookie = "_checkout_queue_token"
+
+// DefaultDeclineMessage The notice shown on the payment page when a payment is declined
+const DefaultDeclineMessage = "Your card was declined. Please use a different card or contact your bank."
+
+// DefaultShippingRate The shipping rate offered when the scenario doesn't set one
+const DefaultShippingRate = "shopify-Standard-10.00"
+
+type Product struct {
+	Id       int64
+	Title    string
+	Handle   string
+	Vendor   string
+	Type     string
+	Tags     []string
+	Variants []Variant
+}
+
+type Variant struct {
+	Id        int64
+	Title     string // The size or option of the variant. Used as option1.
+	Sku       string
+	Price     int64 // In cents.
+	Available bool
+}
+
+// Scenario Scripts how the store behaves. Every count is the amount of times something happens before the store moves on.
+type Scenario struct {
+	MonitorMisses     int    // /products.json returns no products, as if the drop isn't live yet.
+	SoldOutAttempts   int    // Adding to cart fails as sold out before the variant restocks.
+	QueuePolls        int    // Polling the queue returns PollContinue before it's passed. Leave at 0 to skip the queue.
+	ShippingRatePolls int    // Fetching shipping rates returns none while they're still being calculated.
+	TaxPolls          int    // The calculating taxes page is shown before the payment page.
+	ProcessingPolls   int    // The processing page is shown before the order is placed or declined.
+	Declines          int    // Payments are declined before one goes through.
+	DeclineMessage    string // Defaults to DefaultDeclineMessage.
+	ShippingRate      string // The shipping rate ID offered at checkout. Defaults to DefaultShippingRate.
+	OtherGateways     bool   // Gift card and PayPal gateways are listed before the credit card gateway.
+}
+
+// The IDs of the gateways listed before the credit card gateway by Scenario.OtherGateways. Paying with them fails.
+const (
+	GiftCardGatewayId = 2000001
+	PaypalGatewayId   = 2000002
+)
+
+type OrderItem struct {
+	VariantId int64
+	Quantity  int
+}
+
+type Order struct {
+	Number        int
+	CheckoutToken string
+	Email         string
+	Items         []OrderItem
+	ShippingRate  string
+	Total         int64 // In cents, including shipping.
+
+	// The shipping rate was submitted along with the payment, as fast mode does when it knows the shop's gateway.
+	ShippingWithPayment bool
+}
+
+type lineItem struct {
+	Product  Product
+	Variant  Variant
+	Quantity int
+}
+
+type checkout struct {
+	Token               string
+	AuthenticityToken   string
+	Items               []lineItem
+	Email               string
+	ContactSubmitted    bool
+	ShippingRate        string
+	ShippingWithPayment bool
+	ShippingRatePolls   int
+	TaxPolls            int
+	Processing          bool
+	ProcessingPolls     int
+	Declined            bool
+	Notice              string
+	Order               *Order
+}
+
+type Server struct {
+	*httptest.Server
+	ShopId    int64
+	GatewayId int64
+
+	mutex     sync.Mutex
+	scenario  Scenario
+	products  []Product
+	carts     map[string][]lineItem
+	queues    map[string]int
+	passed    map[string]bool
+	checkouts map[string]*checkout
+	sessions  map[string]bool
+	orders    []Order
+	declines  int
+	requests  map[string]int
+
+	notModified int
+}
+
+var checkoutPathRegex = regexp.MustCompile(`^/(\d+)/checkouts/([^/]+)(/[^/]+)?$`)
+
+// NewServer Starts a fake store with the given products. The server must be closed once the test is done.
+func NewServer(products []Product, scenario Scenario) *Server {
+	if scenario.DeclineMessage == "" {
+		scenario.DeclineMessage = DefaultDeclineMessage
+	}
+
+	if scenario.ShippingRate == "" {
+		scenario.ShippingRate = DefaultShippingRate
+	}
+
+	s := &Server{
+		ShopId:    1000000,
+		GatewayId: 2000000,
+		scenario:  scenario,
+		products:  products,
+		carts:     map[string][]lineItem{},
+		queues:    map[string]int{},
+		passed:    map[string]bool{},
+		checkouts: map[string]*checkout{},
+		sessions:  map[string]bool{},
+		requests:  map[string]int{},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// DepositUrl Returns the URL payment sessions are created at
+func (s *Server) DepositUrl() string {
+	return s.URL + RouteSessions
+}
+
+// ProductUrl Returns the URL of a product's page
+func (s *Server) ProductUrl(handle string) string {
+	return fmt.Sprintf("%v/products/%v", s.URL, handle)
+}
+
+// SetAvailable Marks a variant as in or out of stock
+func (s *Server) SetAvailable(variantId int64, available bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.products {
+		for j := range s.products[i].Variants {
+			if s.products[i].Variants[j].Id == variantId {
+				s.products[i].Variants[j].Available = available
+			}
+		}
+	}
+}
+
+// SetPrice Changes the price of a variant
+func (s *Server) SetPrice(variantId int64, cents int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.products {
+		for j := range s.products[i].Variants {
+			if s.products[i].Variants[j].Id == variantId {
+				s.products[i].Variants[j].Price = cents
+			}
+		}
+	}
+}
+
+// AddProduct Adds a product to the store, or a variant to it if a product with the same ID exists
+func (s *Server) AddProduct(p Product) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.products {
+		if s.products[i].Id == p.Id {
+			s.products[i].Variants = append(s.products[i].Variants, p.Variants...)
+			return
+		}
+	}
+
+	s.products = append(s.products, p)
+}
+
+// NotModified Returns the amount of product list requests answered with 304 Not Modified
+func (s *Server) NotModified() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.notModified
+}
+
+// Orders Returns every order that has been placed
+func (s *Server) Orders() []Order {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Order{}, s.orders...)
+}
+
+// Declines Returns the amount of payments that have been declined
+func (s *Server) Declines() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.declines
+}
+
+// Requests Returns the amount of requests made to a route
+func (s *Server) Requests(route string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests[route]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := r.URL.Path
+
+	switch {
+	case path == RouteProducts && r.Method == http.MethodGet:
+		s.count(RouteProducts)
+		s.handleProducts(w, r)
+	case strings.Contains(path, "/products/") && strings.HasSuffix(path, ".js") && r.Method == http.MethodGet:
+		s.count(RouteProduct)
+		s.handleProduct(w, strings.TrimSuffix(path[strings.LastIndex(path, "/products/")+len("/products/"):], ".js"))
+	case path == RouteCartAdd && r.Method == http.MethodPost:
+		s.count(RouteCartAdd)
+		s.handleCartAdd(w, r)
+	case path == RouteCartClear && r.Method == http.MethodPost:
+		s.count(RouteCartClear)
+		s.handleCartClear(w, r)
+	case path == "/cart":
+		writeHtml(w, http.StatusOK, "<html><body><h1>Your cart is empty</h1></body></html>")
+	case path == RouteCheckout && r.Method == http.MethodGet:
+		s.count(RouteCheckout)
+		s.handleCheckout(w, r)
+	case path == RouteQueue && r.Method == http.MethodGet:
+		s.count(RouteQueue)
+		writeHtml(w, http.StatusOK, queuePage)
+	case path == RouteQueuePoll && r.Method == http.MethodPost:
+		s.count(RouteQueuePoll)
+		s.handleQueuePoll(w, r)
+	case path == RouteSessions && r.Method == http.MethodPost:
+		s.count(RouteSessions)
+		s.handleSessions(w, r)
+	case checkoutPathRegex.MatchString(path):
+		s.handleCheckoutPath(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleCheckoutPath(w http.ResponseWriter, r *http.Request) {
+	rs := checkoutPathRegex.FindStringSubmatch(r.URL.Path)
+	c, ok := s.checkouts[rs[2]]
+
+	if rs[1] != strconv.FormatInt(s.ShopId, 10) || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case rs[3] == "" && r.Method == http.MethodGet:
+		s.count(RouteCheckoutPage)
+		s.handleCheckoutPage(w, r, c)
+	case rs[3] == "" && r.Method == http.MethodPost:
+		s.count(RouteCheckoutPage)
+		s.handleCheckoutSubmit(w, r, c)
+	case rs[3] == "/shipping_rates" && r.Method == http.MethodGet:
+		s.count(RouteShippingRates)
+		s.handleShippingRates(w, c)
+	case rs[3] == "/processing" && r.Method == http.MethodGet:
+		s.count(RouteProcessing)
+		s.handleProcessing(w, r, c)
+	case rs[3] == "/thank_you" && r.Method == http.MethodGet:
+		s.count(RouteThankYou)
+		s.handleThankYou(w, r, c)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Sends an ETag with the product list and answers 304 when the client already has it
+func (s *Server) handleProducts(w http.ResponseWriter, r *http.Request) {
+	products := []productJson{}
+
+	if s.scenario.MonitorMisses > 0 {
+		s.scenario.MonitorMisses--
+	} else {
+		for _, p := range s.products {
+			products = append(products, toProductJson(p, false))
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"products": products})
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf("\"%v\"", hex.EncodeToString(sum[:8]))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) handleProduct(w http.ResponseWriter, handle string) {
+	for _, p := range s.products {
+		if p.Handle == handle {
+			writeJson(w, http.StatusOK, toProductJson(p, true))
+			return
+		}
+	}
+
+	writeJson(w, http.StatusNotFound, map[string]interface{}{"status": 404, "message": "Not Found"})
+}
+
+func (s *Server) handleCartAdd(w http.ResponseWriter, r *http.Request) {
+	form, err := readForm(r)
+
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]interface{}{"status": 400, "message": err.Error()})
+		return
+	}
+
+	id, _ := strconv.ParseInt(form.Get("id"), 10, 64)
+	quantity, err := strconv.Atoi(form.Get("quantity"))
+
+	if err != nil || quantity < 1 {
+		quantity = 1
+	}
+
+	product, variant, ok := s.findVariant(id)
+
+	if !ok {
+		writeJson(w, http.StatusNotFound, map[string]interface{}{"status": 404, "message": "Cart Error", "description": "Cannot find variant"})
+		return
+	}
+
+	if s.scenario.SoldOutAttempts > 0 || !variant.Available {
+		if s.scenario.SoldOutAttempts > 0 {
+			s.scenario.SoldOutAttempts--
+		}
+
+		writeJson(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"status":      422,
+			"message":     "Cart Error",
+			"description": fmt.Sprintf("The product '%v' is already sold out.", product.Title),
+		})
+		return
+	}
+
+	token := s.cartToken(w, r)
+	s.carts[token] = append(s.carts[token], lineItem{product, variant, quantity})
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"id":            variant.Id,
+		"quantity":      quantity,
+		"variant_id":    variant.Id,
+		"key":           fmt.Sprintf("%v:%v", variant.Id, randomToken(16)),
+		"title":         fmt.Sprintf("%v - %v", product.Title, variant.Title),
+		"price":         variant.Price,
+		"line_price":    variant.Price * int64(quantity),
+		"sku":           variant.Sku,
+		"vendor":        product.Vendor,
+		"product_id":    product.Id,
+		"url":           fmt.Sprintf("/products/%v?variant=%v", product.Handle, variant.Id),
+		"handle":        product.Handle,
+		"product_title": product.Title,
+		"variant_title": variant.Title,
+	})
+}
+
+func (s *Server) handleCartClear(w http.ResponseWriter, r *http.Request) {
+	token := s.cartToken(w, r)
+	delete(s.carts, token)
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"token":       token,
+		"total_price": 0,
+		"item_count":  0,
+		"items":       []interface{}{},
+	})
+}
+
+func (s *Server) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	token := s.cartToken(w, r)
+	items := s.carts[token]
+
+	if len(items) == 0 {
+		http.Redirect(w, r, "/cart", http.StatusFound)
+		return
+	}
+
+	if s.scenario.QueuePolls > 0 {
+		cookie, err := r.Cookie(queueCookie)
+
+		if err != nil || !s.passed[cookie.Value] {
+			queueToken := randomToken(32)
+			s.queues[queueToken] = s.scenario.QueuePolls
+
+			http.SetCookie(w, &http.Cookie{Name: queueCookie, Value: queueToken, Path: "/"})
+			http.Redirect(w, r, RouteQueue, http.StatusFound)
+			return
+		}
+	}
+
+	c := &checkout{
+		Token:             randomToken(32),
+		AuthenticityToken: randomToken(24),
+		Items:             items,
+	}
+
+	s.checkouts[c.Token] = c
+	http.Redirect(w, r, s.checkoutPath(c), http.StatusFound)
+}
+
+func (s *Server) handleQueuePoll(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Variables struct {
+			Token string `json:"token"`
+		} `json:"variables"`
+	}
+
+	data, _ := ioutil.ReadAll(r.Body)
+
+	if err := json.Unmarshal(data, &body); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{err.Error()}})
+		return
+	}
+
+	remaining, ok := s.queues[body.Variables.Token]
+
+	if !ok {
+		writeJson(w, http.StatusNotFound, map[string]interface{}{"errors": []string{"unknown queue token"}})
+		return
+	}
+
+	if remaining == 0 {
+		delete(s.queues, body.Variables.Token)
+		s.passed[body.Variables.Token] = true
+
+		writeJson(w, http.StatusOK, pollResponse("PollComplete", body.Variables.Token, 0))
+		return
+	}
+
+	// Every poll hands out a new token, just like the real queue.
+	token := randomToken(32)
+	delete(s.queues, body.Variables.Token)
+	s.queues[token] = remaining - 1
+
+	writeJson(w, http.StatusOK, pollResponse("PollContinue", token, remaining))
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		CreditCard struct {
+			Number            string `json:"number"`
+			VerificationValue string `json:"verification_value"`
+		} `json:"credit_card"`
+	}
+
+	data, _ := ioutil.ReadAll(r.Body)
+
+	if err := json.Unmarshal(data, &body); err != nil || body.CreditCard.Number == "" || body.CreditCard.VerificationValue == "" {
+		writeJson(w, http.StatusUnprocessableEntity, map[string]interface{}{"errors": []string{"invalid credit card"}})
+		return
+	}
+
+	id := "east-" + randomToken(32)
+	s.sessions[id] = true
+
+	writeJson(w, http.StatusOK, map[string]interface{}{"id": id})
+}
+
+func (s *Server) handleCheckoutPage(w http.ResponseWriter, r *http.Request, c *checkout) {
+	if c.Order != nil {
+		http.Redirect(w, r, s.checkoutPath(c)+"/thank_you", http.StatusFound)
+		return
+	}
+
+	if !c.ContactSubmitted {
+		writeHtml(w, http.StatusOK, s.contactPage(c))
+		return
+	}
+
+	if c.ShippingRate == "" {
+		writeHtml(w, http.StatusOK, s.shippingPage(c))
+		return
+	}
+
+	if c.TaxPolls > 0 {
+		c.TaxPolls--
+		writeHtml(w, http.StatusOK, s.taxesPage(c))
+		return
+	}
+
+	writeHtml(w, http.StatusOK, s.paymentPage(c))
+}
+
+func (s *Server) handleCheckoutSubmit(w http.ResponseWriter, r *http.Request, c *checkout) {
+	form, err := readForm(r)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if c.Order != nil {
+		http.Redirect(w, r, s.checkoutPath(c)+"/thank_you", http.StatusFound)
+		return
+	}
+
+	switch form.Get("previous_step") {
+	case "contact_information":
+		for _, field := range []string{"checkout[email]", "checkout[shipping_address][address1]", "checkout[shipping_address][city]",
+			"checkout[shipping_address][zip]", "checkout[shipping_address][country]"} {
+			if form.Get(field) == "" {
+				c.Notice = fmt.Sprintf("%v is required", field)
+				writeHtml(w, http.StatusOK, s.contactPage(c))
+				return
+			}
+		}
+
+		c.Email = form.Get("checkout[email]")
+		c.ContactSubmitted = true
+		c.ShippingRatePolls = s.scenario.ShippingRatePolls
+		http.Redirect(w, r, s.checkoutPath(c)+"?previous_step=contact_information&step=shipping_method", http.StatusFound)
+	case "shipping_method":
+		if !s.submitShippingRate(c, form.Get("checkout[shipping_rate][id]")) {
+			writeHtml(w, http.StatusOK, s.shippingPage(c))
+			return
+		}
+
+		http.Redirect(w, r, s.checkoutPath(c)+"?previous_step=shipping_method&step=payment_method", http.StatusFound)
+	case "payment_method":
+		s.submitPayment(w, r, c, form)
+	default:
+		http.Redirect(w, r, s.checkoutPath(c), http.StatusFound)
+	}
+}
+
+// Sets the shipping rate of a checkout if it's the one on offer
+func (s *Server) submitShippingRate(c *checkout, rate string) bool {
+	if !c.ContactSubmitted || rate != s.scenario.ShippingRate {
+		c.Notice = "Your shipping rate is no longer available"
+		return false
+	}
+
+	c.ShippingRate = rate
+	c.TaxPolls = s.scenario.TaxPolls
+	return true
+}
+
+func (s *Server) submitPayment(w http.ResponseWriter, r *http.Request, c *checkout, form url.Values) {
+	// Fast mode submits the shipping rate along with the payment.
+	if c.ShippingRate == "" && form.Get("checkout[shipping_rate][id]") != "" {
+		c.ShippingWithPayment = s.submitShippingRate(c, form.Get("checkout[shipping_rate][id]"))
+	}
+
+	if !c.ContactSubmitted || c.ShippingRate == "" {
+		http.Redirect(w, r, s.checkoutPath(c), http.StatusFound)
+		return
+	}
+
+	session := form.Get("s")
+
+	switch {
+	case !s.sessions[session]:
+		c.Notice = "There was a problem with your payment session. Please try again."
+	case form.Get("checkout[payment_gateway]") != strconv.FormatInt(s.GatewayId, 10):
+		c.Notice = "The selected payment method is no longer available."
+	case form.Get("checkout[total_price]") != strconv.FormatInt(s.total(c), 10):
+		c.Notice = "The order total has changed. Please review your order."
+	}
+
+	delete(s.sessions, session)
+
+	if c.Notice != "" {
+		writeHtml(w, http.StatusOK, s.paymentPage(c))
+		return
+	}
+
+	c.Processing = true
+	c.ProcessingPolls = s.scenario.ProcessingPolls
+	c.Declined = s.scenario.Declines > 0
+
+	if c.Declined {
+		s.scenario.Declines--
+	}
+
+	http.Redirect(w, r, s.checkoutPath(c)+"/processing", http.StatusFound)
+}
+
+func (s *Server) handleShippingRates(w http.ResponseWriter, c *checkout) {
+	if !c.ContactSubmitted {
+		writeHtml(w, http.StatusUnprocessableEntity, "")
+		return
+	}
+
+	if c.ShippingRatePolls > 0 {
+		c.ShippingRatePolls--
+		writeHtml(w, http.StatusOK, shippingRatesPending)
+		return
+	}
+
+	writeHtml(w, http.StatusOK, fmt.Sprintf(shippingRatesReady, s.scenario.ShippingRate, s.scenario.ShippingRate))
+}
+
+func (s *Server) handleProcessing(w http.ResponseWriter, r *http.Request, c *checkout) {
+	if !c.Processing {
+		http.Redirect(w, r, s.checkoutPath(c), http.StatusFound)
+		return
+	}
+
+	if c.ProcessingPolls > 0 {
+		c.ProcessingPolls--
+		writeHtml(w, http.StatusOK, s.processingPage(c))
+		return
+	}
+
+	c.Processing = false
+
+	if c.Declined {
+		c.Declined = false
+		c.Notice = s.scenario.DeclineMessage
+		s.declines++
+
+		http.Redirect(w, r, s.checkoutPath(c)+"?from_processing_page=1&validate=true", http.StatusFound)
+		return
+	}
+
+	order := Order{
+		Number:        1001 + len(s.orders),
+		CheckoutToken: c.Token,
+		Email:         c.Email,
+		ShippingRate:  c.ShippingRate,
+		Total:         s.total(c),
+
+		ShippingWithPayment: c.ShippingWithPayment,
+	}
+
+	for _, item := range c.Items {
+		order.Items = append(order.Items, OrderItem{item.Variant.Id, item.Quantity})
+	}
+
+	c.Order = &order
+	s.orders = append(s.orders, order)
+
+	http.Redirect(w, r, s.checkoutPath(c)+"/thank_you", http.StatusFound)
+}
+
+func (s *Server) handleThankYou(w http.ResponseWriter, r *http.Request, c *checkout) {
+	if c.Order == nil {
+		http.Redirect(w, r, s.checkoutPath(c), http.StatusFound)
+		return
+	}
+
+	writeHtml(w, http.StatusOK, s.thankYouPage(c))
+}
+
+// Returns the token of the request's cart, creating one if it doesn't have one yet
+func (s *Server) cartToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(cartCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	token := randomToken(32)
+	http.SetCookie(w, &http.Cookie{Name: cartCookie, Value: token, Path: "/"})
+	return token
+}
+
+func (s *Server) findVariant(id int64) (Product, Variant, bool) {
+	for _, p := range s.products {
+		for _, v := range p.Variants {
+			if v.Id == id {
+				return p, v, true
+			}
+		}
+	}
+
+	return Product{}, Variant{}, false
+}
+
+func (s *Server) checkoutPath(c *checkout) string {
+	return fmt.Sprintf("/%v/checkouts/%v", s.ShopId, c.Token)
+}
+
+// Returns the subtotal of a checkout in cents
+func (s *Server) subtotal(c *checkout) int64 {
+	var total int64
+
+	for _, item := range c.Items {
+		total += item.Variant.Price * int64(item.Quantity)
+	}
+
+	return total
+}
+
+// Returns the total of a checkout in cents, including shipping once a rate has been chosen
+func (s *Server) total(c *checkout) int64 {
+	total := s.subtotal(c)
+
+	if c.ShippingRate != "" {
+		total += shippingRatePrice(c.ShippingRate)
+	}
+
+	return total
+}
+
+func (s *Server) count(route string) {
+	s.requests[route]++
+}
+
+// Returns the price of a shipping rate in cents. Rates end with their price, ex. shopify-Standard-10.00
+func shippingRatePrice(rate string) int64 {
+	price, err := strconv.ParseFloat(rate[strings.LastIndex(rate, "-")+1:], 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return int64(price*100 + 0.5)
+}
+
+func pollResponse(typename string, token string, eta int) map[string]interface{} {
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"poll": map[string]interface{}{
+				"__typename":                 typename,
+				"token":                      token,
+				"pollAfter":                  time.Now(),
+				"queueEtaSeconds":            eta,
+				"productVariantAvailability": []interface{}{},
+			},
+		},
+	}
+}
+
+// Reads a form body regardless of the content type it was sent with
+func readForm(r *http.Request) (url.Values, error) {
+	data, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return url.ParseQuery(string(data))
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeHtml(w http.ResponseWriter, status int, html string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(html))
+}
+
+func randomToken(length int) string {
+	b := make([]byte, (length+1)/2)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)[:length]
+}
@@ -0,0 +1,332 @@
+package shopify
+
+import (
+	"Mystery/proxies"
+	"Mystery/sizes"
+	"Mystery/tasks"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMonitorInterval How often a site monitor polls when it isn't given an interval
+const DefaultMonitorInterval = 3500 * time.Millisecond
+
+// SiteMonitor Polls a site once per interval for the products every subscribed task is waiting on and hands each task
+// its match, so the amount of requests doesn't grow with the amount of tasks. It keeps the last product list it saw and
+// reports what changed since to its watchers. It only polls while a task is waiting or something is watching.
+type SiteMonitor struct {
+	Site         tasks.Website
+	Interval     time.Duration // Defaults to DefaultMonitorInterval.
+	poller       *Task         // Sends the monitor's requests through the monitor's own proxy list.
+	mutex        *sync.Mutex
+	subscribers  map[*monitorSubscriber]struct{}
+	watchers     map[*monitorWatcher]struct{}
+	running      bool
+	snapshot     []Product // The last product list, only used by the polling goroutine.
+	hasSnapshot  bool
+	etag         string // Validators of the last product list, sent so the site can answer 304 if it hasn't changed.
+	lastModified string
+}
+
+// Something receiving the product events of a monitor
+type monitorWatcher struct {
+	handler func(e ProductEvent)
+}
+
+// A task waiting on the monitor
+type monitorSubscriber struct {
+	target   monitorTarget
+	sizes    []string
+	selector *sizes.Selector   // Chooses the task's variant.
+	match    chan monitorMatch // Buffered so the monitor never waits on the task.
+}
+
+type monitorMatch struct {
+	product Product
+	variant ProductVariant
+}
+
+// NewSiteMonitor Creates a monitor for the site that polls through the proxy list. A nil proxy list uses localhost.
+func NewSiteMonitor(site tasks.Website, proxyList *proxies.ProxyList, interval time.Duration) *SiteMonitor {
+	poller := NewTaskShopify(site, nil, proxyList, tasks.ModeShopifySafe, nil, nil)
+	poller.Id = fmt.Sprintf("monitor-%v", site.Name)
+
+	return &SiteMonitor{
+		Site:        site,
+		Interval:    interval,
+		poller:      poller,
+		mutex:       &sync.Mutex{},
+		subscribers: map[*monitorSubscriber]struct{}{},
+		watchers:    map[*monitorWatcher]struct{}{},
+	}
+}
+
+// Watch Polls the site's product list every interval, even while no task is waiting, and calls the handler with every
+// change from one poll to the next. The handler is called from the polling goroutine so it shouldn't block. Call the
+// returned function to stop watching.
+func (m *SiteMonitor) Watch(handler func(e ProductEvent)) func() {
+	w := &monitorWatcher{handler: handler}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.watchers[w] = struct{}{}
+	m.start()
+
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		delete(m.watchers, w)
+	}
+}
+
+// WaitForMatch Blocks until the monitor finds a product matching the monitor inputs with a variant in the size range,
+// chosen by the selector. Variant inputs match straight away without a request. Returns the context's error if it's
+// done first.
+func (m *SiteMonitor) WaitForMatch(ctx context.Context, inputs []string, sizeRange []string, selector *sizes.Selector) (Product, ProductVariant, error) {
+	target, err := parseMonitorInputs(inputs)
+
+	if err != nil {
+		return Product{}, ProductVariant{}, err
+	}
+
+	if target.variantId != 0 {
+		return Product{}, ProductVariant{Id: target.variantId}, nil
+	}
+
+	sub := &monitorSubscriber{
+		target:   target,
+		sizes:    sizeRange,
+		selector: selector,
+		match:    make(chan monitorMatch, 1),
+	}
+
+	m.subscribe(sub)
+	defer m.unsubscribe(sub)
+
+	select {
+	case match := <-sub.match:
+		return match.product, match.variant, nil
+	case <-ctx.Done():
+		return Product{}, ProductVariant{}, ctx.Err()
+	}
+}
+
+// Subscribers Returns the amount of tasks waiting on the monitor
+func (m *SiteMonitor) Subscribers() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.subscribers)
+}
+
+func (m *SiteMonitor) subscribe(sub *monitorSubscriber) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.subscribers[sub] = struct{}{}
+	m.start()
+}
+
+// Starts polling if the monitor isn't already. The mutex must be held.
+func (m *SiteMonitor) start() {
+	if !m.running {
+		m.running = true
+		go m.run()
+	}
+}
+
+func (m *SiteMonitor) unsubscribe(sub *monitorSubscriber) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.subscribers, sub)
+}
+
+// Polls until no task is waiting and nothing is watching. The poller is only marked as stopped once it has finished, so
+// a task that subscribes in the meantime is picked up by the same goroutine.
+func (m *SiteMonitor) run() {
+	for {
+		m.poller.Begin()
+		m.poller.SelectProxy()
+		m.poller.SetStep(tasks.StepMonitoring)
+
+		for m.poll() {
+			m.poller.Sleep(m.interval())
+		}
+
+		m.poller.Finish()
+
+		m.mutex.Lock()
+
+		if len(m.subscribers) == 0 && len(m.watchers) == 0 {
+			m.running = false
+			m.mutex.Unlock()
+			return
+		}
+
+		m.mutex.Unlock()
+	}
+}
+
+func (m *SiteMonitor) interval() time.Duration {
+	if m.Interval > 0 {
+		return m.Interval
+	}
+
+	return DefaultMonitorInterval
+}
+
+// Returns the tasks that are waiting on the monitor and if anything is watching it
+func (m *SiteMonitor) waiting() ([]*monitorSubscriber, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subs := make([]*monitorSubscriber, 0, len(m.subscribers))
+
+	for sub := range m.subscribers {
+		subs = append(subs, sub)
+	}
+
+	return subs, len(m.watchers) > 0
+}
+
+// Fetches /products.json once if anything is watching or any task monitors by keywords and every product URL a task
+// monitors once, then hands out the matches. Returns false if no task is waiting and nothing is watching.
+func (m *SiteMonitor) poll() bool {
+	subs, watched := m.waiting()
+
+	if len(subs) == 0 && !watched {
+		return false
+	}
+
+	var products []Product
+	productsFetched := false
+	urlProducts := map[string]Product{}
+
+	if watched {
+		products = m.fetchProducts()
+		productsFetched = true
+	}
+
+	for _, sub := range subs {
+		if url := sub.target.url; url != "" {
+			if _, ok := urlProducts[url]; !ok {
+				urlProducts[url] = m.fetchProduct(url)
+			}
+		} else if !productsFetched {
+			products = m.fetchProducts()
+			productsFetched = true
+		}
+	}
+
+	for _, sub := range subs {
+		var product Product
+		var variant ProductVariant
+		var err error
+
+		if url := sub.target.url; url != "" {
+			product = urlProducts[url]
+			variant, err = getCheckoutVariant(product, sub.sizes, sub.selector)
+		} else {
+			product, variant, err = findKeywordMatch(products, sub.target.keywords, sub.sizes, sub.selector)
+		}
+
+		if err == nil {
+			m.deliver(sub, monitorMatch{product, variant})
+		}
+	}
+
+	return true
+}
+
+// Returns the site's most recent products, or none if the request fails. The list is only downloaded again if it has
+// changed, and every change since the last list is sent to the watchers.
+func (m *SiteMonitor) fetchProducts() []Product {
+	resp, products, err := m.poller.GetProductsIfChanged(m.etag, m.lastModified)
+
+	if err != nil {
+		m.poller.Log(err)
+		return nil
+	}
+
+	if resp.StatusCode() == http.StatusNotModified {
+		return m.snapshot
+	}
+
+	if resp.IsError() {
+		m.poller.Log(fmt.Sprintf("Error Monitoring Products (%v)", resp.StatusCode()))
+		return nil
+	}
+
+	// Every product is new on the first poll, which isn't worth reporting
+	if m.hasSnapshot {
+		m.publish(DiffProducts(m.snapshot, products))
+	}
+
+	m.snapshot = products
+	m.hasSnapshot = true
+	m.etag = resp.Header().Get("ETag")
+	m.lastModified = resp.Header().Get("Last-Modified")
+	return products
+}
+
+// Sends product events to every watcher
+func (m *SiteMonitor) publish(events []ProductEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	handlers := make([]func(e ProductEvent), 0, len(m.watchers))
+
+	for w := range m.watchers {
+		handlers = append(handlers, w.handler)
+	}
+
+	m.mutex.Unlock()
+	now := time.Now()
+
+	for _, e := range events {
+		e.Time = now
+		e.Site = m.Site
+
+		for _, handler := range handlers {
+			handler(e)
+		}
+	}
+}
+
+// Returns the product at the URL, or an empty product if the request fails
+func (m *SiteMonitor) fetchProduct(url string) Product {
+	resp, product, err := m.poller.GetProductSpecific(url)
+
+	if err != nil {
+		m.poller.Log(err)
+		return Product{}
+	}
+
+	if resp.IsError() {
+		m.poller.Log(fmt.Sprintf("Error Monitoring Product %v (%v)", url, resp.StatusCode()))
+		return Product{}
+	}
+
+	return product
+}
+
+// Hands a match to a task that is still waiting and stops it from waiting on the monitor
+func (m *SiteMonitor) deliver(sub *monitorSubscriber, match monitorMatch) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(m.subscribers, sub)
+	sub.match <- match
+}
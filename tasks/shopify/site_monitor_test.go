@@ -0,0 +1,164 @@
+package shopify
+
+import (
+	"Mystery/tasks"
+	"Mystery/tasks/shopify/shopifytest"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests that tasks on a site with a shared monitor check out with one poll per interval instead of one per task
+func TestSiteMonitorSharesPolls(t *testing.T) {
+	server := newTestStore(t, shopifytest.Scenario{MonitorMisses: 2})
+	monitor := NewSiteMonitor(tasks.Website{Name: "Fake Store", Url: server.URL}, nil, 5*time.Millisecond)
+
+	var all []*Task
+
+	for _, size := range []string{"9", "10", "9", "10", "9"} {
+		task := newTestStoreTaskOn(server, tasks.ModeShopifySafe, []string{"+dunk"}, []string{size})
+		task.Monitor = monitor
+		all = append(all, task)
+	}
+
+	hoodie := newTestStoreTaskOn(server, tasks.ModeShopifySafe, []string{server.ProductUrl("essentials-hoodie")}, []string{"M"})
+	hoodie.Monitor = monitor
+	all = append(all, hoodie)
+
+	wg := sync.WaitGroup{}
+
+	for _, task := range all {
+		wg.Add(1)
+
+		go func(task *Task) {
+			defer wg.Done()
+			task.Run()
+		}(task)
+	}
+
+	wg.Wait()
+
+	for _, task := range all {
+		if status := task.GetStatus(); status.Value != "Checked Out!" {
+			t.Fatalf("expected every task to check out. got %q", status.Value)
+		}
+	}
+
+	if n := len(server.Orders()); n != len(all) {
+		t.Fatalf("expected %v orders. got %v", len(all), n)
+	}
+
+	// Each task polling on its own would take at least 3 requests
+	if n := server.Requests(shopifytest.RouteProducts); n >= len(all) {
+		t.Fatalf("expected the product list to be polled once per interval. got %v requests", n)
+	}
+
+	if n := server.Requests(shopifytest.RouteProduct); n >= len(all) {
+		t.Fatalf("expected the product url to be polled once per interval. got %v requests", n)
+	}
+}
+
+// Tests that a task stops waiting on the monitor once its context is done and that variant inputs never wait
+func TestSiteMonitorWaitForMatch(t *testing.T) {
+	server := newTestStore(t, shopifytest.Scenario{MonitorMisses: 1000})
+	monitor := NewSiteMonitor(tasks.Website{Name: "Fake Store", Url: server.URL}, nil, time.Millisecond)
+
+	_, variant, err := monitor.WaitForMatch(context.Background(), []string{"101"}, nil, nil)
+
+	if err != nil || variant.Id != 101 || server.Requests(shopifytest.RouteProducts) != 0 {
+		t.Fatalf("expected the variant input to match without a request. got %v %v", variant.Id, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := monitor.WaitForMatch(ctx, []string{"+dunk"}, nil, nil); err != context.DeadlineExceeded {
+		t.Fatalf("expected the wait to end with the context. got %v", err)
+	}
+
+	if n := monitor.Subscribers(); n != 0 {
+		t.Fatalf("expected no subscribers. got %v", n)
+	}
+
+	// The monitor stops polling once nobody is waiting
+	time.Sleep(20 * time.Millisecond)
+	n := server.Requests(shopifytest.RouteProducts)
+	time.Sleep(20 * time.Millisecond)
+
+	if n == 0 || server.Requests(shopifytest.RouteProducts) != n {
+		t.Fatalf("expected the monitor to poll while waited on and stop after. got %v then %v", n, server.Requests(shopifytest.RouteProducts))
+	}
+}
+
+// Waits for the next product event, failing the test if it's not the expected type
+func nextProductEvent(t *testing.T, events <-chan ProductEvent, expected ProductEventType) ProductEvent {
+	select {
+	case e := <-events:
+		if e.Type != expected {
+			t.Fatalf("expected a %v event. got %v for %v", expected, e.Type, e.Variant.Id)
+		}
+
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a %v event", expected)
+		return ProductEvent{}
+	}
+}
+
+// Tests that a watched monitor reports changes between polls and skips downloading product lists that haven't changed
+func TestSiteMonitorWatch(t *testing.T) {
+	server := shopifytest.NewServer([]shopifytest.Product{
+		{Id: 1, Title: "Dunk", Handle: "dunk", Variants: []shopifytest.Variant{
+			{Id: 101, Title: "9", Price: 11000, Available: true},
+			{Id: 102, Title: "10", Price: 11000, Available: true},
+		}},
+	}, shopifytest.Scenario{})
+
+	defer server.Close()
+
+	monitor := NewSiteMonitor(tasks.Website{Name: "Fake Store", Url: server.URL}, nil, time.Millisecond)
+	events := make(chan ProductEvent, 10)
+	stop := monitor.Watch(func(e ProductEvent) {
+		events <- e
+	})
+
+	defer stop()
+
+	// Wait until the first list has been seen and the site has said it hasn't changed since
+	for server.NotModified() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	server.SetAvailable(102, false)
+	e := nextProductEvent(t, events, ProductEventSoldOut)
+
+	if e.Variant.Id != 102 || !e.Before.Available || e.Site.Name != "Fake Store" || e.Time.IsZero() {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	server.SetPrice(101, 12000)
+	e = nextProductEvent(t, events, ProductEventPriceChange)
+
+	if e.Before.PriceCents() != 11000 || e.Variant.PriceCents() != 12000 {
+		t.Fatalf("expected the price to change from 11000 to 12000. got %v to %v", e.Before.PriceCents(), e.Variant.PriceCents())
+	}
+
+	server.AddProduct(shopifytest.Product{Id: 2, Title: "Hoodie", Handle: "hoodie", Variants: []shopifytest.Variant{{Id: 201, Title: "M", Price: 9000}}})
+	nextProductEvent(t, events, ProductEventNew)
+
+	server.AddProduct(shopifytest.Product{Id: 2, Variants: []shopifytest.Variant{{Id: 202, Title: "L", Price: 9000}}})
+	nextProductEvent(t, events, ProductEventNewVariant)
+
+	server.SetAvailable(202, true)
+	nextProductEvent(t, events, ProductEventRestock)
+
+	stop()
+	time.Sleep(20 * time.Millisecond)
+	n := server.Requests(shopifytest.RouteProducts)
+	time.Sleep(20 * time.Millisecond)
+
+	if server.Requests(shopifytest.RouteProducts) != n {
+		t.Fatal("expected the monitor to stop polling once nothing is watching")
+	}
+}
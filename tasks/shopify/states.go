@@ -0,0 +1,312 @@
+package shopify
+
+import (
+	"Mystery/tasks"
+	"errors"
+	"fmt"
+)
+
+// ErrIllegalTransition A state handler moved to a step that isn't declared as a transition of the current one
+var ErrIllegalTransition = errors.New("illegal checkout transition")
+
+// ErrNoProgress The task kept handling the same steps without getting any further in checkout
+var ErrNoProgress = errors.New("checkout is not making progress")
+
+// CheckoutState Handles a single checkout step and returns the step to move to next
+type CheckoutState struct {
+	Handle      func(t *Task) CheckoutStep
+	Transitions []CheckoutStep
+}
+
+type CheckoutMachine struct {
+	States     map[CheckoutStep]CheckoutState
+	MaxRepeats int // The amount of times a step can be handled in a row without the step or page changing.
+	MaxVisits  int // The amount of times a step can be moved to from another step before it's considered a loop.
+}
+
+// DefaultCheckoutMachine The state machine every Shopify task checks out with
+var DefaultCheckoutMachine = &CheckoutMachine{
+	States: map[CheckoutStep]CheckoutState{
+		CheckoutStepMonitoring: {
+			Handle:      (*Task).handleMonitoring,
+			Transitions: []CheckoutStep{CheckoutStepAddingToCart},
+		},
+		CheckoutStepAddingToCart: {
+			Handle:      (*Task).handleAddingToCart,
+			Transitions: []CheckoutStep{CheckoutStepCreatingCheckout},
+		},
+		CheckoutStepCreatingCheckout: {
+			Handle: (*Task).handleCreatingCheckout,
+			Transitions: []CheckoutStep{CheckoutStepQueue, CheckoutStepContact, CheckoutStepShippingMethod, CheckoutStepCalculatingTaxes,
+				CheckoutStepPaymentMethod, CheckoutStepCheckpoint, CheckoutStepLogin, CheckoutStepLoginChallenge, CheckoutStepNone},
+		},
+		CheckoutStepQueue: {
+			Handle: (*Task).handleQueue,
+			Transitions: []CheckoutStep{CheckoutStepCreatingCheckout, CheckoutStepContact, CheckoutStepShippingMethod, CheckoutStepCalculatingTaxes,
+				CheckoutStepPaymentMethod, CheckoutStepCheckpoint, CheckoutStepLogin, CheckoutStepLoginChallenge, CheckoutStepNone},
+		},
+		CheckoutStepContact: {
+			Handle: (*Task).handleContact,
+			Transitions: []CheckoutStep{CheckoutStepShippingMethod, CheckoutStepCalculatingTaxes, CheckoutStepPaymentMethod, CheckoutStepQueue,
+				CheckoutStepCheckpoint, CheckoutStepNone},
+		},
+		CheckoutStepShippingMethod: {
+			Handle: (*Task).handleShippingMethod,
+			Transitions: []CheckoutStep{CheckoutStepContact, CheckoutStepCalculatingTaxes, CheckoutStepPaymentMethod, CheckoutStepProcessing,
+				CheckoutStepOrderConfirmation, CheckoutStepQueue, CheckoutStepNone},
+		},
+		CheckoutStepCalculatingTaxes: {
+			Handle:      (*Task).handleCalculatingTaxes,
+			Transitions: []CheckoutStep{CheckoutStepShippingMethod, CheckoutStepPaymentMethod, CheckoutStepNone},
+		},
+		CheckoutStepPaymentMethod: {
+			Handle: (*Task).handlePaymentMethod,
+			Transitions: []CheckoutStep{CheckoutStepShippingMethod, CheckoutStepProcessing, CheckoutStepOrderConfirmation, CheckoutStepQueue,
+				CheckoutStepNone},
+		},
+		CheckoutStepProcessing: {
+			Handle:      (*Task).handleProcessing,
+			Transitions: []CheckoutStep{CheckoutStepPaymentMethod, CheckoutStepOrderConfirmation, CheckoutStepNone},
+		},
+		CheckoutStepOrderConfirmation: {
+			Handle: (*Task).handleOrderConfirmation,
+		},
+		CheckoutStepCheckpoint: {
+			Handle: (*Task).handleCheckpoint,
+		},
+		CheckoutStepLogin: {
+			Handle: (*Task).handleLogin,
+		},
+		CheckoutStepLoginChallenge: {
+			Handle: (*Task).handleLogin,
+		},
+		CheckoutStepNone: {
+			Handle: (*Task).handleUnknownPage,
+			Transitions: []CheckoutStep{CheckoutStepCreatingCheckout, CheckoutStepQueue, CheckoutStepContact, CheckoutStepShippingMethod,
+				CheckoutStepCalculatingTaxes, CheckoutStepPaymentMethod, CheckoutStepProcessing, CheckoutStepOrderConfirmation,
+				CheckoutStepCheckpoint, CheckoutStepLogin, CheckoutStepLoginChallenge},
+		},
+	},
+	MaxRepeats: 50,
+	MaxVisits:  20,
+}
+
+// Run Handles steps starting from the given one until the task is stopped or reaches CheckoutStepDone.
+// Every step may move to itself or to CheckoutStepDone without declaring it as a transition.
+func (m *CheckoutMachine) Run(t *Task, step CheckoutStep) error {
+	repeats := 0
+	visits := map[CheckoutStep]int{}
+	t.Step = step
+	t.SetStep(step.String())
+
+	for t.IsRunning() && t.Step != CheckoutStepDone {
+		state, ok := m.States[t.Step]
+
+		if !ok {
+			return errors.New(fmt.Sprintf("no handler for checkout step %v", t.Step))
+		}
+
+		url := t.CurrentPage.Url
+		next := state.Handle(t)
+
+		// The handler was interrupted because the task was stopped, so there's nothing left to check.
+		if !t.IsRunning() {
+			return nil
+		}
+
+		if next == t.Step {
+			if url != t.CurrentPage.Url {
+				repeats = 0
+				continue
+			}
+
+			repeats++
+
+			if m.MaxRepeats > 0 && repeats >= m.MaxRepeats {
+				return fmt.Errorf("%w (stuck on %v after %v attempts)", ErrNoProgress, t.Step, repeats)
+			}
+
+			continue
+		}
+
+		if next != CheckoutStepDone && !state.canMoveTo(next) {
+			return fmt.Errorf("%w (%v -> %v)", ErrIllegalTransition, t.Step, next)
+		}
+
+		visits[next]++
+
+		if m.MaxVisits > 0 && visits[next] > m.MaxVisits {
+			return fmt.Errorf("%w (moved to %v %v times)", ErrNoProgress, next, visits[next])
+		}
+
+		t.Log(fmt.Sprintf("Step: %v -> %v", t.Step, next))
+		t.Step = next
+		t.SetStep(next.String())
+		t.ResetAllAttempts()
+		repeats = 0
+	}
+
+	return nil
+}
+
+// Returns if the state declares a transition to the given step
+func (s *CheckoutState) canMoveTo(step CheckoutStep) bool {
+	for _, transition := range s.Transitions {
+		if transition == step {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the step of the page the task is currently on
+func (t *Task) pageStep() CheckoutStep {
+	if t.CurrentPage.Url != "" {
+		return t.CurrentPage.GetCheckoutStep()
+	}
+
+	if t.CheckoutUrl == "" {
+		return CheckoutStepCreatingCheckout
+	}
+
+	// On fast mode the checkout page is never loaded, so contact info is submitted straight away.
+	return CheckoutStepContact
+}
+
+func (t *Task) handleMonitoring() CheckoutStep {
+	t.FlowMonitorProducts()
+
+	if !t.IsProductFound() {
+		return CheckoutStepMonitoring
+	}
+
+	return CheckoutStepAddingToCart
+}
+
+func (t *Task) handleAddingToCart() CheckoutStep {
+	t.FlowAddToCart(t.Variant.Id)
+
+	if t.VariantInCart != t.Variant.Id {
+		return CheckoutStepAddingToCart
+	}
+
+	return CheckoutStepCreatingCheckout
+}
+
+func (t *Task) handleCreatingCheckout() CheckoutStep {
+	t.FlowCreateCheckout()
+
+	// Fully load the checkout page if we're on safe mode because anti-bot and other parameters need to be submitted if detected.
+	if t.Mode == tasks.ModeShopifySafe {
+		t.FlowLoadCheckoutPage()
+	}
+
+	return t.pageStep()
+}
+
+func (t *Task) handleQueue() CheckoutStep {
+	t.FlowPollQueue()
+	return t.pageStep()
+}
+
+func (t *Task) handleContact() CheckoutStep {
+	// On fast mode contact info is forced through before the page is loaded. This allows us to skip the page loading
+	// step which is a request - ultimately making fast mode faster.
+	t.FlowSubmitContactInfo(true)
+	return t.pageStep()
+}
+
+func (t *Task) handleShippingMethod() CheckoutStep {
+	// Shipping rates are required to be fetched now before they can be submitted. No way to avoid this for now that I can see.
+	t.FlowFetchShippingRate()
+
+	// Submits the payment and shipping rate in a single step if using fast mode and the gateway is already known.
+	if t.Mode == tasks.ModeShopifyFast && Gateways.Get(t.CurrentPage.GetShopId()) != 0 {
+		t.FlowSubmitPayment(true)
+	} else {
+		t.FlowSubmitShippingRate()
+	}
+
+	return t.pageStep()
+}
+
+func (t *Task) handleCalculatingTaxes() CheckoutStep {
+	t.FlowCalculateTaxes()
+	return t.pageStep()
+}
+
+func (t *Task) handlePaymentMethod() CheckoutStep {
+	// Landing back on the payment page after submitting payment means that the payment has failed.
+	if t.SubmittedPayment {
+		t.SubmittedPayment = false
+		t.HandleCheckoutFailure()
+
+		if !t.IsRunning() {
+			return CheckoutStepDone
+		}
+
+		return t.pageStep()
+	}
+
+	t.FlowSubmitPayment(false)
+	return t.pageStep()
+}
+
+func (t *Task) handleProcessing() CheckoutStep {
+	t.FlowProcessOrder()
+	return t.pageStep()
+}
+
+func (t *Task) handleOrderConfirmation() CheckoutStep {
+	t.HandleCheckoutSuccess()
+	return CheckoutStepDone
+}
+
+func (t *Task) handleCheckpoint() CheckoutStep {
+	t.UpdateStatus(&tasks.TaskStatus{
+		Value: "Waiting For Checkpoint Captcha (Not Supported)",
+		Level: tasks.StatusLevelError,
+	}, true)
+
+	return CheckoutStepDone
+}
+
+func (t *Task) handleLogin() CheckoutStep {
+	t.UpdateStatus(&tasks.TaskStatus{
+		Value: "Account Required (Not Supported)",
+		Level: tasks.StatusLevelError,
+	}, true)
+
+	return CheckoutStepDone
+}
+
+// Recovers from a page that isn't part of checkout by reloading the checkout or creating a new one
+func (t *Task) handleUnknownPage() CheckoutStep {
+	t.Log(fmt.Sprintf("Unknown checkout page: %v", t.CurrentPage.Url))
+
+	if t.CheckoutUrl == "" {
+		t.CurrentPage = Page{}
+		t.PreviousPage = Page{}
+		return CheckoutStepCreatingCheckout
+	}
+
+	t.UpdateStatus(&tasks.TaskStatus{
+		Value: "Fetching Checkout Page",
+		Level: tasks.StatusLevelInfo,
+	}, true)
+
+	_, resp, err := t.GetCheckoutPage(t.CheckoutUrl)
+
+	if err != nil {
+		t.Log(err)
+		t.UpdateStatus(&tasks.TaskStatus{
+			Value: fmt.Sprintf("Error Fetching Checkout Page (%v)", resp.StatusCode()),
+			Level: tasks.StatusLevelError,
+		}, false)
+
+		t.retry(tasks.RetryStepCheckout, resp)
+	}
+
+	return t.pageStep()
+}
@@ -0,0 +1,212 @@
+package shopify
+
+import (
+	"Mystery/tasks"
+	"errors"
+	"testing"
+)
+
+// Creates a running task for state machine tests
+func newStateTestTask(t *testing.T) *Task {
+	task := NewTaskShopify(tasks.Website{Name: "Test", Url: "https://example.com"}, &testProfile, nil, tasks.ModeShopifySafe, []string{"+a"}, []string{})
+
+	if !task.Begin() {
+		t.Fatal("expected the task to begin")
+	}
+
+	t.Cleanup(task.Finish)
+	return task
+}
+
+// Tests that the machine follows the handlers until it's done
+func TestCheckoutMachineRunsToDone(t *testing.T) {
+	task := newStateTestTask(t)
+	var visited []CheckoutStep
+
+	m := &CheckoutMachine{
+		States: map[CheckoutStep]CheckoutState{
+			CheckoutStepMonitoring: {
+				Handle: func(t *Task) CheckoutStep {
+					visited = append(visited, t.Step)
+					return CheckoutStepAddingToCart
+				},
+				Transitions: []CheckoutStep{CheckoutStepAddingToCart},
+			},
+			CheckoutStepAddingToCart: {
+				Handle: func(t *Task) CheckoutStep {
+					visited = append(visited, t.Step)
+					return CheckoutStepDone
+				},
+			},
+		},
+	}
+
+	if err := m.Run(task, CheckoutStepMonitoring); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(visited) != 2 || visited[0] != CheckoutStepMonitoring || visited[1] != CheckoutStepAddingToCart {
+		t.Fatalf("unexpected steps: %v", visited)
+	}
+
+	if task.Step != CheckoutStepDone {
+		t.Fatalf("expected to finish on %v. got %v", CheckoutStepDone, task.Step)
+	}
+}
+
+// Tests that moving to a step that isn't declared is reported
+func TestCheckoutMachineIllegalTransition(t *testing.T) {
+	task := newStateTestTask(t)
+
+	m := &CheckoutMachine{
+		States: map[CheckoutStep]CheckoutState{
+			CheckoutStepContact: {
+				Handle: func(t *Task) CheckoutStep {
+					return CheckoutStepOrderConfirmation
+				},
+				Transitions: []CheckoutStep{CheckoutStepShippingMethod},
+			},
+		},
+	}
+
+	if err := m.Run(task, CheckoutStepContact); !errors.Is(err, ErrIllegalTransition) {
+		t.Fatalf("expected an illegal transition error. got %v", err)
+	}
+}
+
+// Tests that handling the same step over and over without the page changing is reported
+func TestCheckoutMachineNoProgress(t *testing.T) {
+	task := newStateTestTask(t)
+	calls := 0
+
+	m := &CheckoutMachine{
+		States: map[CheckoutStep]CheckoutState{
+			CheckoutStepContact: {
+				Handle: func(t *Task) CheckoutStep {
+					calls++
+					return CheckoutStepContact
+				},
+			},
+		},
+		MaxRepeats: 5,
+	}
+
+	if err := m.Run(task, CheckoutStepContact); !errors.Is(err, ErrNoProgress) {
+		t.Fatalf("expected a no progress error. got %v", err)
+	}
+
+	if calls != 5 {
+		t.Fatalf("expected 5 calls. got %v", calls)
+	}
+}
+
+// Tests that a step repeating with a new page each time counts as progress
+func TestCheckoutMachinePageChangeIsProgress(t *testing.T) {
+	task := newStateTestTask(t)
+	calls := 0
+
+	m := &CheckoutMachine{
+		States: map[CheckoutStep]CheckoutState{
+			CheckoutStepQueue: {
+				Handle: func(t *Task) CheckoutStep {
+					calls++
+					t.CurrentPage = Page{Url: t.CurrentPage.Url + "/queue"}
+
+					if calls == 10 {
+						return CheckoutStepDone
+					}
+
+					return CheckoutStepQueue
+				},
+			},
+		},
+		MaxRepeats: 2,
+	}
+
+	if err := m.Run(task, CheckoutStepQueue); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that bouncing between two steps is reported
+func TestCheckoutMachineLoop(t *testing.T) {
+	task := newStateTestTask(t)
+
+	m := &CheckoutMachine{
+		States: map[CheckoutStep]CheckoutState{
+			CheckoutStepContact: {
+				Handle: func(t *Task) CheckoutStep {
+					return CheckoutStepShippingMethod
+				},
+				Transitions: []CheckoutStep{CheckoutStepShippingMethod},
+			},
+			CheckoutStepShippingMethod: {
+				Handle: func(t *Task) CheckoutStep {
+					return CheckoutStepContact
+				},
+				Transitions: []CheckoutStep{CheckoutStepContact},
+			},
+		},
+		MaxVisits: 3,
+	}
+
+	if err := m.Run(task, CheckoutStepContact); !errors.Is(err, ErrNoProgress) {
+		t.Fatalf("expected a no progress error. got %v", err)
+	}
+}
+
+// Tests that the machine exits without an error when the task is stopped
+func TestCheckoutMachineStopped(t *testing.T) {
+	task := newStateTestTask(t)
+
+	m := &CheckoutMachine{
+		States: map[CheckoutStep]CheckoutState{
+			CheckoutStepMonitoring: {
+				Handle: func(t *Task) CheckoutStep {
+					t.Cancel()
+					return CheckoutStepMonitoring
+				},
+			},
+		},
+	}
+
+	if err := m.Run(task, CheckoutStepMonitoring); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that every transition of the default machine leads to a step that has a handler
+func TestDefaultCheckoutMachineTransitions(t *testing.T) {
+	for step, state := range DefaultCheckoutMachine.States {
+		if state.Handle == nil {
+			t.Fatalf("%v has no handler", step)
+		}
+
+		for _, next := range state.Transitions {
+			if _, ok := DefaultCheckoutMachine.States[next]; !ok {
+				t.Fatalf("%v can move to %v which has no handler", step, next)
+			}
+		}
+	}
+}
+
+// Tests which step a task moves to based on its current page
+func TestPageStep(t *testing.T) {
+	task := NewTaskShopify(tasks.Website{}, &testProfile, nil, tasks.ModeShopifyFast, nil, nil)
+
+	if step := task.pageStep(); step != CheckoutStepCreatingCheckout {
+		t.Fatalf("expected %v. got %v", CheckoutStepCreatingCheckout, step)
+	}
+
+	task.CheckoutUrl = "https://example.com/1/checkouts/abc"
+
+	if step := task.pageStep(); step != CheckoutStepContact {
+		t.Fatalf("expected %v. got %v", CheckoutStepContact, step)
+	}
+
+	task.CurrentPage = Page{Url: "https://example.com/throttle/queue"}
+
+	if step := task.pageStep(); step != CheckoutStepQueue {
+		t.Fatalf("expected %v. got %v", CheckoutStepQueue, step)
+	}
+}
@@ -1,11 +1,13 @@
 package shopify
 
 import (
-	"Mystery/discord"
+	"Mystery/history"
+	"Mystery/notify"
 	"Mystery/profiles"
 	"Mystery/proxies"
 	"Mystery/tasks"
 	"fmt"
+	"github.com/go-resty/resty/v2"
 	"strconv"
 	"strings"
 	"time"
@@ -22,14 +24,17 @@ type Task struct {
 	ShippingRate          string
 	PreviousQueueResponse PollQueueResponse
 	SubmittedContactInfo  bool
+	SubmittedPayment      bool
+	Step                  CheckoutStep
+	Declines              int          // The amount of times payment has been declined since the task started.
+	PaymentPrice          string       // The total price in cents that payment was last submitted with.
+	Monitor               *SiteMonitor // Shared by every task on the site. Nil if the task monitors on its own.
 }
 
 // NewTaskShopify Returns a new Shopify task
-func NewTaskShopify(site tasks.Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode tasks.TaskMode, inputs []string, sizes []string) Task {
-	t := tasks.NewTask(site, profile, proxyList, mode, inputs, sizes)
-
-	return Task{
-		t,
+func NewTaskShopify(site tasks.Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode tasks.TaskMode, inputs []string, sizes []string) *Task {
+	t := &Task{
+		tasks.NewTask(site, profile, proxyList, mode, inputs, sizes),
 		Product{},
 		ProductVariant{},
 		0,
@@ -39,100 +44,50 @@ func NewTaskShopify(site tasks.Website, profile *profiles.Profile, proxyList *pr
 		"",
 		PollQueueResponse{},
 		false,
+		false,
+		CheckoutStepNone,
+		0,
+		"",
+		nil,
 	}
+
+	t.Runner = t
+	return t
 }
 
+// Start Runs the task in the background
 func (t *Task) Start() {
-	if t.TaskManager != nil {
-		t.TaskManager.TaskWaitGroup.Add(1)
+	if !t.Begin() {
+		return
 	}
 
 	go t.Run()
 }
 
+// Run Runs the task until it checks out or is stopped
 func (t *Task) Run() {
-	t.SelectProxy()
-	t.IsRunning = true
-
-	for t.IsRunning {
-		t.FlowMonitorProducts()
-		t.FlowAddToCart(t.Variant.Id)
-		t.FlowCreateCheckout()
-		t.FlowPollQueue()
-
-		// Checkpoint not support yet
-		if t.CurrentPage.GetCheckoutStep() == CheckoutStepCheckpoint {
-			t.UpdateStatus(&tasks.TaskStatus{
-				Value: "Waiting For Checkpoint Captcha (Not Supported)",
-				Level: tasks.StatusLevelError,
-			}, true)
-
-			t.Stop()
-			return
-		}
-
-		// Login not supported yet
-		if t.CurrentPage.GetCheckoutStep() == CheckoutStepLogin {
-			t.UpdateStatus(&tasks.TaskStatus{
-				Value: "Account Required (Not Supported)",
-				Level: tasks.StatusLevelError,
-			}, true)
+	t.Begin()
+	defer t.finish()
 
-			t.Stop()
-			return
-		}
-
-		// Fully load the checkout page if we're on safe mode because anti-bot and other parameters need to be submitted if detected.
-		if t.Mode == tasks.ModeShopifySafe {
-			t.FlowLoadCheckoutPage()
-		}
-
-		// Below this point requires a valid checkout session.
-		if t.CheckoutUrl == "" {
-			continue
-		}
-
-		// On fast mode, immediately force submit contact info if it hasn't been submitted already. This allows us to skip the page loading
-		// step which us a request - ultimately making fast mode faster.
-		if t.Mode == tasks.ModeShopifyFast && !t.SubmittedContactInfo {
-			t.FlowSubmitContactInfo(true)
-		} else {
-			t.FlowSubmitContactInfo(false)
-		}
-
-		// Shipping rates are required to be fetched now before they can be submitted. No way to avoid this for now that I can see.
-		t.FlowFetchShippingRate()
-
-		gateway := GetGateway(t.CurrentPage.GetShopId())
-
-		// Go through the normal flow on safe mode or if we don't already have the gateway cached.
-		if t.Mode == tasks.ModeShopifySafe || gateway == 0 {
-			t.FlowSubmitShippingRate()
-		}
-
-		t.FlowCalculateTaxes()
-
-		// Submits the payment and shipping rate in a single step if using fast mode, otherwise it
-		// goes through the normal flow.
-		if t.Mode == tasks.ModeShopifyFast && gateway != 0 {
-			t.FlowSubmitPayment(true)
-		} else {
-			t.FlowSubmitPayment(false)
-		}
+	t.SelectProxy()
 
-		t.FlowProcessOrder()
-		t.HandleCheckoutFailure()
-		t.HandleCheckoutSuccess()
+	if err := DefaultCheckoutMachine.Run(t, CheckoutStepMonitoring); err != nil {
+		t.Log(err)
+		t.UpdateStatus(&tasks.TaskStatus{
+			Value: fmt.Sprintf("Checkout Stopped On %v", t.Step),
+			Level: tasks.StatusLevelError,
+		}, true)
 	}
 }
 
-// Stop the task from running
+// Stop the task from running. Returns once the task has fully stopped.
 func (t *Task) Stop() {
-	if t.TaskManager != nil {
-		t.TaskManager.TaskWaitGroup.Done()
-	}
+	t.Cancel()
+	t.Wait()
+}
 
-	t.IsRunning = false
+// Resets the checkout state once the task has stopped running
+func (t *Task) finish() {
 	t.Product = Product{}
 	t.Variant = ProductVariant{}
 	t.VariantInCart = 0
@@ -142,16 +97,29 @@ func (t *Task) Stop() {
 	t.ShippingRate = ""
 	t.PreviousQueueResponse = PollQueueResponse{}
 	t.SubmittedContactInfo = false
+	t.SubmittedPayment = false
+	t.Step = CheckoutStepNone
+	t.SetStep("")
+	t.Declines = 0
+	t.ReleaseVariant()
+	t.Finish()
 	t.Log("Task Stopped")
 }
 
 // FlowMonitorProducts Monitors for products with error handling flow
 func (t *Task) FlowMonitorProducts() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
+		return
+	}
+
+	defer t.TimeFlow("monitor_products")()
+
+	if t.Monitor != nil {
+		t.waitForMonitor()
 		return
 	}
 
-	for !t.IsProductFound() {
+	for !t.IsProductFound() && t.IsRunning() {
 		t.UpdateStatus(&tasks.TaskStatus{
 			Value: "Monitoring",
 			Level: tasks.StatusLevelInfo,
@@ -161,16 +129,14 @@ func (t *Task) FlowMonitorProducts() {
 
 		switch err {
 		case nil:
-			t.Log(fmt.Sprintf("Product Found: %v | %v (%v)", t.Product.Title, t.Variant.Option1, t.Variant.Id))
-			t.ProductName = t.Product.Title
-			t.ProductSize = t.Variant.Option1
+			t.productFound()
 		case ErrProductNotFound:
 			t.UpdateStatus(&tasks.TaskStatus{
 				Value: "Product Not Found",
 				Level: tasks.StatusLevelInfo,
 			}, true)
 
-			time.Sleep(3500 * time.Millisecond)
+			t.retry(tasks.RetryStepMonitor, resp)
 		default:
 			t.Log(err)
 			t.UpdateStatus(&tasks.TaskStatus{
@@ -178,14 +144,51 @@ func (t *Task) FlowMonitorProducts() {
 				Level: tasks.StatusLevelError,
 			}, false)
 
-			time.Sleep(3500 * time.Millisecond)
+			t.retry(tasks.RetryStepMonitor, resp)
+		}
+	}
+}
+
+// Waits for the site's shared monitor to find the product
+func (t *Task) waitForMonitor() {
+	t.UpdateStatus(&tasks.TaskStatus{
+		Value: "Monitoring",
+		Level: tasks.StatusLevelInfo,
+	}, true)
+
+	product, variant, err := t.Monitor.WaitForMatch(t.Context(), t.MonitorInputs, t.Sizes, t.VariantSelector())
+
+	if err != nil {
+		if !t.IsRunning() {
+			return
 		}
+
+		t.Log(err)
+		t.UpdateStatus(&tasks.TaskStatus{
+			Value: "Error Monitoring Product",
+			Level: tasks.StatusLevelError,
+		}, false)
+
+		t.retry(tasks.RetryStepMonitor, nil)
+		return
 	}
+
+	t.Product = product
+	t.Variant = variant
+	t.productFound()
+}
+
+// Records the product that monitoring found
+func (t *Task) productFound() {
+	t.Log(fmt.Sprintf("Product Found: %v | %v (%v)", t.Product.Title, t.Variant.Option1, t.Variant.Id))
+	t.ProductName = t.Product.Title
+	t.ProductSize = t.Variant.Option1
+	t.Emit(tasks.TaskEvent{Type: tasks.EventProductFound, Product: t.ProductName, Size: t.ProductSize})
 }
 
 // FlowAddToCart Adds item to the cart depending on the mode
 func (t *Task) FlowAddToCart(variant int64) {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -194,6 +197,8 @@ func (t *Task) FlowAddToCart(variant int64) {
 		return
 	}
 
+	defer t.TimeFlow("add_to_cart")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: "Adding To Cart",
 		Level: tasks.StatusLevelInfo,
@@ -208,7 +213,7 @@ func (t *Task) FlowAddToCart(variant int64) {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepCart, resp)
 		return
 	}
 
@@ -222,11 +227,12 @@ func (t *Task) FlowAddToCart(variant int64) {
 	}
 
 	t.Log(fmt.Sprintf("Item added to cart: %v (%v)", t.Product.Title, variant))
+	t.Emit(tasks.TaskEvent{Type: tasks.EventCarted, Product: t.Product.Title, Size: t.ProductSize})
 }
 
 // FlowCreateCheckout Creates a checkout session for the flow
 func (t *Task) FlowCreateCheckout() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -234,6 +240,8 @@ func (t *Task) FlowCreateCheckout() {
 		return
 	}
 
+	defer t.TimeFlow("create_checkout")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: "Creating Checkout",
 		Level: tasks.StatusLevelInfo,
@@ -248,7 +256,7 @@ func (t *Task) FlowCreateCheckout() {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepCheckout, resp)
 		return
 	}
 
@@ -261,7 +269,7 @@ func (t *Task) FlowCreateCheckout() {
 
 // FlowCreateCheckoutFast Creates a checkout session + adds to cart in one request for the flow
 func (t *Task) FlowCreateCheckoutFast(variant int64) {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -273,6 +281,8 @@ func (t *Task) FlowCreateCheckoutFast(variant int64) {
 		return
 	}
 
+	defer t.TimeFlow("create_checkout_fast")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: fmt.Sprintf("Creating Checkout (Fast)"),
 		Level: tasks.StatusLevelInfo,
@@ -287,7 +297,11 @@ func (t *Task) FlowCreateCheckoutFast(variant int64) {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepCheckout, resp)
+	}
+
+	if t.VariantInCart == variant {
+		t.Emit(tasks.TaskEvent{Type: tasks.EventCarted, Product: t.ProductName, Size: t.ProductSize})
 	}
 
 	t.Log(fmt.Sprintf("Redirected to: %v", resp.RawResponse.Request.URL.String()))
@@ -295,7 +309,7 @@ func (t *Task) FlowCreateCheckoutFast(variant int64) {
 
 // FlowLoadCheckoutPage Loads the initial checkout page
 func (t *Task) FlowLoadCheckoutPage() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -303,6 +317,8 @@ func (t *Task) FlowLoadCheckoutPage() {
 		return
 	}
 
+	defer t.TimeFlow("load_checkout_page")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: fmt.Sprintf("Fetching Checkout Page"),
 		Level: tasks.StatusLevelInfo,
@@ -317,7 +333,7 @@ func (t *Task) FlowLoadCheckoutPage() {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepCheckout, resp)
 		return
 	}
 
@@ -326,7 +342,7 @@ func (t *Task) FlowLoadCheckoutPage() {
 
 // FlowSubmitContactInfo Submits the contact info for the flow
 func (t *Task) FlowSubmitContactInfo(force bool) {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -334,6 +350,8 @@ func (t *Task) FlowSubmitContactInfo(force bool) {
 		return
 	}
 
+	defer t.TimeFlow("submit_contact_info")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: fmt.Sprintf("Submitting Contact Info"),
 		Level: tasks.StatusLevelInfo,
@@ -348,7 +366,7 @@ func (t *Task) FlowSubmitContactInfo(force bool) {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepContact, resp)
 		return
 	}
 
@@ -358,7 +376,7 @@ func (t *Task) FlowSubmitContactInfo(force bool) {
 
 // FlowFetchShippingRate Fetches shipping rates for the flow
 func (t *Task) FlowFetchShippingRate() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -366,7 +384,9 @@ func (t *Task) FlowFetchShippingRate() {
 		return
 	}
 
-	for t.ShippingRate == "" {
+	defer t.TimeFlow("fetch_shipping_rate")()
+
+	for t.ShippingRate == "" && t.IsRunning() {
 		t.UpdateStatus(&tasks.TaskStatus{
 			Value: fmt.Sprintf("Fetching Shipping Rate"),
 			Level: tasks.StatusLevelInfo,
@@ -384,7 +404,7 @@ func (t *Task) FlowFetchShippingRate() {
 				Level: tasks.StatusLevelInfo,
 			}, true)
 
-			time.Sleep(1 * time.Second)
+			t.retry(tasks.RetryStepPollShipping, resp)
 		default:
 			t.Log(err)
 			t.UpdateStatus(&tasks.TaskStatus{
@@ -392,14 +412,14 @@ func (t *Task) FlowFetchShippingRate() {
 				Level: tasks.StatusLevelError,
 			}, false)
 
-			time.Sleep(3500 * time.Millisecond)
+			t.retry(tasks.RetryStepShipping, resp)
 		}
 	}
 }
 
 // FlowSubmitShippingRate Submits the shipping rate for the flow
 func (t *Task) FlowSubmitShippingRate() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
@@ -411,6 +431,8 @@ func (t *Task) FlowSubmitShippingRate() {
 		return
 	}
 
+	defer t.TimeFlow("submit_shipping_rate")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: fmt.Sprintf("Submitting Shipping Rate"),
 		Level: tasks.StatusLevelInfo,
@@ -425,7 +447,7 @@ func (t *Task) FlowSubmitShippingRate() {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepShipping, resp)
 		return
 	}
 
@@ -434,11 +456,13 @@ func (t *Task) FlowSubmitShippingRate() {
 
 // FlowCalculateTaxes Handles calculating taxes for the flow
 func (t *Task) FlowCalculateTaxes() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
-	for t.CurrentPage.GetCheckoutStep() == CheckoutStepCalculatingTaxes {
+	defer t.TimeFlow("calculate_taxes")()
+
+	for t.CurrentPage.GetCheckoutStep() == CheckoutStepCalculatingTaxes && t.IsRunning() {
 		t.UpdateStatus(&tasks.TaskStatus{
 			Value: fmt.Sprintf("Calculating Taxes"),
 			Level: tasks.StatusLevelInfo,
@@ -453,12 +477,13 @@ func (t *Task) FlowCalculateTaxes() {
 				Level: tasks.StatusLevelError,
 			}, false)
 
-			time.Sleep(3500 * time.Millisecond)
+			t.retry(tasks.RetryStepTaxes, resp)
+			continue
 		}
 
 		if !done {
 			t.Log("Still calculating taxes")
-			time.Sleep(1 * time.Millisecond)
+			t.retry(tasks.RetryStepPollTaxes, resp)
 			continue
 		}
 
@@ -469,12 +494,12 @@ func (t *Task) FlowCalculateTaxes() {
 
 // FlowSubmitPayment Submits payment for the flow
 func (t *Task) FlowSubmitPayment(fast bool) {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
 	if fast {
-		gateway := GetGateway(t.CurrentPage.GetShopId())
+		gateway := Gateways.Get(t.CurrentPage.GetShopId())
 
 		if t.ShippingRate == "" || gateway == 0 {
 			return
@@ -485,6 +510,8 @@ func (t *Task) FlowSubmitPayment(fast bool) {
 		}
 	}
 
+	defer t.TimeFlow("submit_payment")()
+
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: fmt.Sprintf("Fetching Payment Token"),
 		Level: tasks.StatusLevelInfo,
@@ -499,12 +526,18 @@ func (t *Task) FlowSubmitPayment(fast bool) {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepPayment, resp)
 		return
 	}
 
+	status := "Submitting Payment"
+
+	if t.Declines > 0 {
+		status = fmt.Sprintf("Submitting Payment (Retry %v/%v)", t.Declines, t.PaymentRetries)
+	}
+
 	t.UpdateStatus(&tasks.TaskStatus{
-		Value: fmt.Sprintf("Submitting Payment"),
+		Value: status,
 		Level: tasks.StatusLevelInfo,
 	}, true)
 
@@ -517,20 +550,23 @@ func (t *Task) FlowSubmitPayment(fast bool) {
 			Level: tasks.StatusLevelError,
 		}, false)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepPayment, resp)
 		return
 	}
 
+	t.SubmittedPayment = true
 	t.Log(fmt.Sprintf("Redirected to : %v", page.Url))
 }
 
 // FlowProcessOrder Polls the order processing page for the flow
 func (t *Task) FlowProcessOrder() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
-	for t.CurrentPage.GetCheckoutStep() == CheckoutStepProcessing {
+	defer t.TimeFlow("process_order")()
+
+	for t.CurrentPage.GetCheckoutStep() == CheckoutStepProcessing && t.IsRunning() {
 		t.UpdateStatus(&tasks.TaskStatus{
 			Value: fmt.Sprintf("Processing Order"),
 			Level: tasks.StatusLevelImportant,
@@ -545,7 +581,7 @@ func (t *Task) FlowProcessOrder() {
 				Level: tasks.StatusLevelError,
 			}, false)
 
-			time.Sleep(3500 * time.Millisecond)
+			t.retry(tasks.RetryStepProcessing, resp)
 			continue
 		}
 
@@ -553,7 +589,7 @@ func (t *Task) FlowProcessOrder() {
 
 		// Add a delay before polling the processing step again
 		if t.CurrentPage.GetCheckoutStep() == CheckoutStepProcessing {
-			time.Sleep(1 * time.Second)
+			t.retry(tasks.RetryStepPollProcessing, resp)
 			continue
 		}
 	}
@@ -561,14 +597,19 @@ func (t *Task) FlowProcessOrder() {
 
 // FlowPollQueue Handles polling the checkout queue
 func (t *Task) FlowPollQueue() {
-	if !t.IsRunning {
+	if !t.IsRunning() {
 		return
 	}
 
-	for t.CurrentPage.GetCheckoutStep() == CheckoutStepQueue {
+	defer t.TimeFlow("poll_queue")()
+
+	for t.CurrentPage.GetCheckoutStep() == CheckoutStepQueue && t.IsRunning() {
 		// Wait until we're allowed to poll the queue again or if it has never been polled.
-		if t.PreviousQueueResponse != (PollQueueResponse{}) && time.Now().UTC().Before(t.PreviousQueueResponse.Data.Poll.PollAfter) {
-			continue
+		if t.PreviousQueueResponse != (PollQueueResponse{}) {
+			if wait := time.Until(t.PreviousQueueResponse.Data.Poll.PollAfter); wait > 0 {
+				t.Sleep(wait)
+				continue
+			}
 		}
 
 		t.UpdateStatus(&tasks.TaskStatus{
@@ -576,11 +617,11 @@ func (t *Task) FlowPollQueue() {
 			Level: tasks.StatusLevelInfo,
 		}, true)
 
-		data, _, err := t.PollQueue()
+		data, resp, err := t.PollQueue()
 
 		if err != nil {
 			t.Log(err)
-			time.Sleep(3500 * time.Millisecond)
+			t.retry(tasks.RetryStepQueue, resp)
 			continue
 		}
 
@@ -589,7 +630,7 @@ func (t *Task) FlowPollQueue() {
 		switch t.PreviousQueueResponse.Data.Poll.GetTypename() {
 		case QueuePollContinue:
 			t.Log(fmt.Sprintf("Still In Queue | ETA: %v | Poll After: %v", data.Data.Poll.QueueEtaSeconds, data.Data.Poll.PollAfter))
-			time.Sleep(3000 * time.Millisecond)
+			t.retry(tasks.RetryStepPollQueue, resp)
 		case QueuePollComplete:
 			t.Log("Queue completed!")
 
@@ -607,7 +648,7 @@ func (t *Task) FlowPollQueue() {
 				return
 			}
 
-			if !t.IsRunning {
+			if !t.IsRunning() {
 				return
 			}
 
@@ -626,7 +667,7 @@ func (t *Task) FlowPollQueue() {
 					Level: tasks.StatusLevelError,
 				}, false)
 
-				time.Sleep(3500 * time.Millisecond)
+				t.retry(tasks.RetryStepCheckout, resp)
 				continue
 			}
 
@@ -655,17 +696,19 @@ func (t *Task) HandleCheckoutFailure() {
 
 	// Out of stock. Task doesn't stop here, as we'll still be running for restocks
 	if strings.Contains(t.CurrentPage.Url, "stock_problems") {
+		t.recordCheckout(history.ResultFailure, "Out of stock")
 		t.UpdateStatus(&tasks.TaskStatus{
 			Value: fmt.Sprintf("Payment Failed - Out of Stock"),
 			Level: tasks.StatusLevelInfo,
 		}, true)
 
-		time.Sleep(3500 * time.Millisecond)
+		t.retry(tasks.RetryStepMonitor, nil)
 		return
 	}
 
 	// With fast mode, this can happen when the total price is incorrect
 	if strings.Contains(strings.ToLower(notice), "order total has changed") {
+		t.recordCheckout(history.ResultFailure, notice)
 		t.UpdateStatus(&tasks.TaskStatus{
 			Value: fmt.Sprintf("Payment Failed - Incorrect Order Total"),
 			Level: tasks.StatusLevelError,
@@ -676,26 +719,47 @@ func (t *Task) HandleCheckoutFailure() {
 	// Handle common payment decline reasons
 	for _, reason := range []string{"issue processing", "problem processing", "declined", "insufficient"} {
 		if strings.Contains(strings.ToLower(notice), reason) {
-			t.Log(fmt.Sprintf("Payment Declined - %v", notice))
-			t.UpdateStatus(&tasks.TaskStatus{
-				Value: "Payment Declined",
-				Level: tasks.StatusLevelError,
-			}, false)
-
-			t.SendWebhook(false)
-			t.Stop()
+			t.handleDecline(notice)
 			return
 		}
 	}
 
 	t.Log(fmt.Sprintf("Payment Declined - %v", notice))
+	t.recordCheckout(history.ResultFailure, notice)
 	t.UpdateStatus(&tasks.TaskStatus{
 		Value: "Payment Error",
 		Level: tasks.StatusLevelError,
 	}, false)
 
-	t.SendWebhook(false)
-	t.Stop()
+	t.SendWebhook(notify.EventCheckoutFailure)
+	t.Cancel()
+}
+
+// Waits to submit payment again after a decline. The next submission fetches a new payment session from
+// GetPaymentToken. Once the task is out of payment retries, the decline is sent to notifiers and the task is stopped.
+func (t *Task) handleDecline(notice string) {
+	t.Declines++
+	t.Log(fmt.Sprintf("Payment Declined (%v/%v) - %v", t.Declines, t.PaymentRetries+1, notice))
+	t.Emit(tasks.TaskEvent{Type: tasks.EventCheckoutDecline, Product: t.ProductName, Size: t.ProductSize, Message: notice})
+	t.recordCheckout(history.ResultDecline, notice)
+
+	if t.Declines > t.PaymentRetries {
+		t.UpdateStatus(&tasks.TaskStatus{
+			Value: "Payment Declined",
+			Level: tasks.StatusLevelError,
+		}, false)
+
+		t.sendCheckoutWebhook(notify.EventCheckoutDecline, notice)
+		t.Cancel()
+		return
+	}
+
+	t.UpdateStatus(&tasks.TaskStatus{
+		Value: fmt.Sprintf("Payment Declined - Retrying (%v/%v)", t.Declines, t.PaymentRetries),
+		Level: tasks.StatusLevelError,
+	}, true)
+
+	t.Sleep(t.GetRetryPolicy(tasks.RetryStepDecline).GetDelay(t.Declines))
 }
 
 // HandleCheckoutSuccess Handles when the checkout has succeeded
@@ -709,8 +773,44 @@ func (t *Task) HandleCheckoutSuccess() {
 		Level: tasks.StatusLevelSuccess,
 	}, true)
 
-	t.SendWebhook(true)
-	t.Stop()
+	t.Emit(tasks.TaskEvent{Type: tasks.EventCheckoutSuccess, Product: t.ProductName, Size: t.ProductSize})
+	t.recordCheckout(history.ResultSuccess, "")
+	t.SendWebhook(notify.EventCheckoutSuccess)
+	t.Cancel()
+}
+
+// Waits before retrying a step that failed with the given response, which can be nil. If the step has run out
+// of attempts, the task fails and is stopped. Returns false if the task shouldn't retry.
+func (t *Task) retry(step tasks.RetryStep, resp *resty.Response) bool {
+	status := 0
+
+	if resp != nil {
+		status = resp.StatusCode()
+	}
+
+	if t.Retry(step, status) {
+		return true
+	}
+
+	if t.IsRunning() {
+		t.fail(step)
+	}
+
+	return false
+}
+
+// Fails the task after a step has run out of attempts
+func (t *Task) fail(step tasks.RetryStep) {
+	reason := fmt.Sprintf("Ran out of attempts at the %v step (%v attempts)", step, t.GetAttempts(step))
+
+	t.Log(reason)
+	t.UpdateStatus(&tasks.TaskStatus{
+		Value: fmt.Sprintf("Out Of Attempts (%v)", step),
+		Level: tasks.StatusLevelError,
+	}, false)
+
+	t.sendCheckoutWebhook(notify.EventCheckoutFailure, reason)
+	t.Cancel()
 }
 
 // IsProductFound Returns if the product is found
@@ -724,9 +824,21 @@ func (t *Task) UpdateCurrentPage(p Page) {
 	t.CurrentPage = p
 }
 
-// SendWebhook Sends a webhook to Discord
-func (t *Task) SendWebhook(success bool) {
-	failureReason := "None"
+// SendWebhook Sends a checkout event to the notifiers of the task's group. Failures use the notice on the current page
+// as the reason.
+func (t *Task) SendWebhook(eventType notify.EventType) {
+	failureReason := ""
+
+	if eventType != notify.EventCheckoutSuccess {
+		failureReason, _ = t.CurrentPage.GetNoticeText()
+	}
+
+	t.sendCheckoutWebhook(eventType, failureReason)
+}
+
+// Sends a checkout event to the notifiers of the task's group with the given failure reason
+func (t *Task) sendCheckoutWebhook(eventType notify.EventType, failureReason string) {
+	success := eventType == notify.EventCheckoutSuccess
 	proxyList := "None"
 	orderNumber := "None"
 	orderLink := ""
@@ -738,23 +850,78 @@ func (t *Task) SendWebhook(success bool) {
 	if success {
 		orderNumber = t.CurrentPage.GetOrderNumber()
 		orderLink = t.CurrentPage.Url
-	} else {
-		failureReason, _ = t.CurrentPage.GetNoticeText()
 	}
 
-	discord.SendCheckoutWebhook(discord.CheckoutWebhookData{
+	productTitle, productSize := t.checkoutProduct()
+
+	t.Notify(notify.NewCheckoutEvent(eventType, notify.CheckoutEvent{
 		Success:       success,
 		FailureReason: failureReason,
 		Site:          t.Site.Name,
 		Mode:          tasks.ModeToString(t.Mode),
-		ProductTitle:  t.CurrentPage.GetProductTitle(),
-		ProductSize:   t.CurrentPage.GetProductSize(),
+		ProductTitle:  productTitle,
+		ProductSize:   productSize,
 		ProductImage:  t.CurrentPage.GetProductImage(),
 		Profile:       t.Profile.Name,
 		ProxyList:     proxyList,
 		Email:         t.Profile.ShippingAddress.Email,
 		OrderNumber:   orderNumber,
 		OrderLink:     orderLink,
-		Quantity:      strconv.Itoa(t.Quantity),
+		Quantity:      t.Quantity,
+		TaskId:        t.Id,
+		LogFile:       t.LogFile(),
+	}))
+}
+
+// Returns the title and size of the product being checked out. Tasks can fail before they reach a checkout page, so
+// what was found while monitoring is used when the page doesn't have them.
+func (t *Task) checkoutProduct() (string, string) {
+	productTitle := t.CurrentPage.GetProductTitle()
+	productSize := t.CurrentPage.GetProductSize()
+
+	if productTitle == "None" && t.ProductName != "" {
+		productTitle = t.ProductName
+	}
+
+	if productSize == "None" && t.ProductSize != "" {
+		productSize = t.ProductSize
+	}
+
+	return productTitle, productSize
+}
+
+// Saves a checkout attempt with the product, variant and price to the manager's history
+func (t *Task) recordCheckout(result history.Result, failureReason string) {
+	productTitle, productSize := t.checkoutProduct()
+	orderNumber := ""
+
+	if result == history.ResultSuccess {
+		orderNumber = t.CurrentPage.GetOrderNumber()
+	}
+
+	t.RecordCheckout(history.Record{
+		Product:       productTitle,
+		VariantId:     t.Variant.Id,
+		Size:          productSize,
+		Price:         t.checkoutPrice(),
+		Result:        result,
+		OrderNumber:   orderNumber,
+		FailureReason: failureReason,
 	})
 }
+
+// Returns the total price in cents of the checkout. Falls back to the variant's price if payment was never submitted
+// and the page doesn't show a total. Returns 0 if neither is known.
+func (t *Task) checkoutPrice() int64 {
+	if price, err := strconv.ParseInt(t.PaymentPrice, 10, 64); err == nil && price > 0 {
+		return price
+	}
+
+	if total, err := t.CurrentPage.GetTotalPrice(); err == nil {
+		if price, err := strconv.ParseInt(total, 10, 64); err == nil {
+			return price
+		}
+	}
+
+	return t.Variant.PriceCents()
+}
@@ -3,7 +3,10 @@ package shopify
 import (
 	"Mystery/profiles"
 	"Mystery/tasks"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 var testProfile = profiles.Profile{
@@ -111,3 +114,62 @@ func TestRunFastFlowHatClub(t *testing.T) {
 	task := NewTaskShopify(site, &testProfile, nil, tasks.ModeShopifySafe, []string{"+a"}, []string{})
 	task.Run()
 }
+
+// Tests that stopping a task cancels its in-flight request and waits for it to exit
+func TestStopCancelsRunningTask(t *testing.T) {
+	requested := make(chan struct{}, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+
+		// Never respond, so the task can only get past this by being cancelled.
+		<-r.Context().Done()
+	}))
+
+	defer server.Close()
+
+	m := tasks.NewManager()
+	site := tasks.Website{Name: "Test", Url: server.URL}
+	task := NewTaskShopify(site, &testProfile, nil, tasks.ModeShopifySafe, []string{"+dunk"}, []string{})
+	m.AddTask(&task.Task)
+
+	m.StartTask(task)
+
+	select {
+	case <-requested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task never made a request")
+	}
+
+	if !task.IsRunning() {
+		t.Fatal("expected the task to be running")
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		m.StopTask(task)
+		m.WaitForAllTasks()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not stop")
+	}
+
+	if task.IsRunning() {
+		t.Fatal("expected the task to be stopped")
+	}
+
+	if task.IsProductFound() || task.CheckoutUrl != "" {
+		t.Fatal("expected the checkout state to be reset")
+	}
+
+	// Stopping a task that isn't running shouldn't block
+	m.StopTask(task)
+}
@@ -12,7 +12,7 @@ func (t *Task) CalculateTaxes() (bool, *resty.Response, error) {
 	page, resp, err := t.GetCheckoutPage(fmt.Sprintf("%v?previous_step=shipping_method&step=payment_method", t.CheckoutUrl))
 
 	if err != nil {
-		return false, nil, err
+		return false, resp, err
 	}
 
 	if resp.IsError() {
@@ -1,37 +1,64 @@
 package tasks
 
 import (
+	"Mystery/history"
+	"Mystery/logs"
+	"Mystery/notify"
 	"Mystery/profiles"
 	"Mystery/proxies"
+	"Mystery/sizes"
 	"Mystery/utils"
+	"context"
 	"fmt"
 	"github.com/go-resty/resty/v2"
 	"net/http"
-	"net/url"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
 type Task struct {
-	TaskManager   *Manager
-	Group         *TaskGroup
-	Id            string
-	Status        *TaskStatus
-	IsRunning     bool
-	Client        *resty.Client
-	Site          Website
-	Profile       *profiles.Profile
-	ProxyList     *proxies.ProxyList
-	Mode          TaskMode
-	MonitorInputs []string
-	Sizes         []string
-	Quantity      int
-	ProductName   string
-	ProductSize   string
+	TaskManager      *Manager
+	Group            *TaskGroup
+	Runner           TaskRunner
+	Id               string
+	Status           *TaskStatus
+	Client           *resty.Client
+	Site             Website
+	Profile          *profiles.Profile
+	ProxyList        *proxies.ProxyList
+	Mode             TaskMode
+	MonitorInputs    []string
+	Sizes            []string
+	Quantity         int
+	PaymentRetries   int            // The amount of times payment is submitted again after being declined.
+	RotateProxyAfter int            // The amount of proxy errors in a row after which the task switches proxy. 0 never switches.
+	VariantStrategy  sizes.Strategy // How the task chooses between the variants in its size range. Defaults to random.
+	VariantSeed      int64          // Makes random choices repeat in the same order. 0 seeds from the time.
+	ProductName      string
+	ProductSize      string
+	RetryPolicies    RetryPolicies
+	step             string               // The checkout step shown in log lines.
+	stepStarted      time.Time            // When the current step was set.
+	stepTimings      []history.StepTiming // The time spent on each step before the current one since the task started.
+	logFile          *logs.RotatingFile   // The task's own log file, opened on the first line logged.
+	logFileFailed    bool
+	instrumented     bool           // If the client has been set up to record request metrics and proxy health.
+	proxy            *proxies.Proxy // The proxy the client is using, nil for localhost.
+	proxyErrors      int            // Proxy errors in a row on the current proxy.
+	rotateProxy      bool           // If the next request should switch proxy.
+	selector         *sizes.Selector
+	attempts         map[RetryStep]int
+	mutex            *sync.Mutex
+	ctx              context.Context
+	cancel           context.CancelFunc
+	done             chan struct{}
+	waitGroup        *sync.WaitGroup
 }
 
 type TaskStatus struct {
-	Value string
-	Level TaskStatusLevel
+	Value string          `json:"value"`
+	Level TaskStatusLevel `json:"level"`
 }
 
 type TaskStatusLevel int
@@ -48,6 +75,9 @@ type TaskRunner interface {
 	Stop()
 }
 
+// The size a task's own log file is rotated at
+const taskLogFileMaxBytes = 5 * 1024 * 1024
+
 // NewTask Creates and returns a new task
 func NewTask(site Website, profile *profiles.Profile, proxyList *proxies.ProxyList, mode TaskMode, inputs []string, sizes []string) Task {
 	t := Task{
@@ -57,13 +87,15 @@ func NewTask(site Website, profile *profiles.Profile, proxyList *proxies.ProxyLi
 			Value: "Idle",
 			Level: StatusLevelInfo,
 		},
-		Site:          site,
-		Profile:       profile,
-		ProxyList:     proxyList,
-		Mode:          mode,
-		MonitorInputs: inputs,
-		Sizes:         sizes,
-		Quantity:      1,
+		Site:             site,
+		Profile:          profile,
+		ProxyList:        proxyList,
+		Mode:             mode,
+		MonitorInputs:    inputs,
+		Sizes:            sizes,
+		Quantity:         1,
+		RotateProxyAfter: DefaultRotateProxyAfter,
+		mutex:            &sync.Mutex{},
 	}
 
 	t.Client.SetTimeout(1 * time.Minute)
@@ -79,37 +111,375 @@ func (t *Task) SelectProxy() {
 
 	t.Client.RemoveProxy()
 
-	proxy := t.ProxyList.SelectNextProxy()
+	proxy := t.ProxyList.SelectProxy(t.Id)
+
+	if proxy == nil {
+		// A SOCKS proxy is dialed through by the transport, so removing the client's proxy isn't enough
+		t.Client.SetTransport(&http.Transport{})
+		t.setProxy(nil)
+		t.Log(fmt.Sprintf("Proxy list %v has no proxies, using Localhost", t.ProxyList.Name))
+		return
+	}
+
+	t.Client.SetTransport(proxy.Transport())
+	t.setProxy(proxy)
+	t.Log(fmt.Sprintf("Using Proxy: %v", proxy.Address()))
+}
+
+// Notify Sends an event to the notifiers the task's group routes it to
+func (t *Task) Notify(e notify.Event) {
+	if t.TaskManager == nil {
+		return
+	}
+
+	var routes notify.Routes
+
+	if t.Group != nil {
+		e.Group = t.Group.Name
+		routes = t.Group.Notify
+	}
+
+	t.TaskManager.Notifications.SendAndLog(routes, e)
+}
+
+// Begin Creates the context that the task runs under. Returns false if the task is already running.
+// Every call that returns true must be followed by a call to Finish once the task has stopped running.
+func (t *Task) Begin() bool {
+	t.mutex.Lock()
+
+	if t.ctx != nil {
+		t.mutex.Unlock()
+		return false
+	}
+
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.done = make(chan struct{})
+	t.stepTimings = nil
+
+	// Done here rather than in NewTask because the client's hooks need the task's final address
+	if !t.instrumented {
+		t.instrumentClient()
+		t.trackProxyHealth()
+		t.instrumented = true
+	}
+
+	if t.TaskManager != nil {
+		t.waitGroup = t.TaskManager.TaskWaitGroup
+		t.waitGroup.Add(1)
+	}
+
+	t.mutex.Unlock()
+	t.Emit(TaskEvent{Type: EventTaskStarted})
+	return true
+}
+
+// Finish Marks the task as no longer running and releases anything waiting on it
+func (t *Task) Finish() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.ctx == nil {
+		return
+	}
+
+	// Sent before the task is marked as stopped, so anything waiting on the task sees the event first
+	t.Emit(TaskEvent{Type: EventTaskStopped})
+
+	t.cancel()
+	close(t.done)
+
+	if t.waitGroup != nil {
+		t.waitGroup.Done()
+	}
+
+	t.ctx = nil
+	t.cancel = nil
+	t.done = nil
+	t.waitGroup = nil
+}
+
+// Cancel Signals the task to stop running. In-flight requests and sleeps return immediately.
+func (t *Task) Cancel() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// Wait Blocks until the task has finished running
+func (t *Task) Wait() {
+	t.mutex.Lock()
+	done := t.done
+	t.mutex.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// IsRunning Returns if the task is running and hasn't been told to stop
+func (t *Task) IsRunning() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.ctx != nil && t.ctx.Err() == nil
+}
+
+// Edit Changes the task's settings with edit while the task's mutex is held, so the task can't start halfway through the
+// change. Returns false without calling edit if the task is running or still stopping. edit must not call methods of the
+// task that lock its mutex.
+func (t *Task) Edit(edit func()) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.ctx != nil {
+		return false
+	}
+
+	edit()
+	return true
+}
+
+// Context Returns the context the task is running under. Tasks that aren't running use a background context.
+func (t *Task) Context() context.Context {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 
-	urlStr := ""
+	if t.ctx == nil {
+		return context.Background()
+	}
+
+	return t.ctx
+}
 
-	if proxy.Username != "" && proxy.Password != "" {
-		urlStr = fmt.Sprintf("http://%v:%v@%v:%v", proxy.Username, proxy.Password, proxy.Host, proxy.Port)
-	} else {
-		urlStr = fmt.Sprintf("http://%v:%v", proxy.Host, proxy.Port)
+// Sleep Pauses the task for a given duration. Returns false if the task was stopped while sleeping.
+func (t *Task) Sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-t.Context().Done():
+		return false
 	}
+}
 
-	uri, _ := url.Parse(urlStr)
+// Request Creates a new request that is cancelled when the task stops
+func (t *Task) Request() *resty.Request {
+	if t.takeProxyRotation() {
+		t.Log(fmt.Sprintf("Switching proxy after %v proxy errors in a row", t.RotateProxyAfter))
+		t.SelectProxy()
+	}
+
+	return t.Client.R().SetContext(t.Context())
+}
 
-	t.Client.SetTransport(&http.Transport{
-		Proxy: http.ProxyURL(uri),
-	})
+// GetStatus Returns the current status of the task
+func (t *Task) GetStatus() *TaskStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 
-	t.Client.SetProxy(urlStr)
-	t.Log(fmt.Sprintf("Using Proxy: %v:%v", proxy.Host, proxy.Port))
+	return t.Status
 }
 
 // UpdateStatus Updates the status of the task (ex. Idle / Adding To Cart / Processing)
 func (t *Task) UpdateStatus(s *TaskStatus, log bool) {
+	t.mutex.Lock()
 	t.Status = s
+	t.mutex.Unlock()
+
+	t.Emit(TaskEvent{Type: EventStatusChanged, Status: s})
 
-	if log {
-		t.Log(s.Value)
+	if log && s.Level == StatusLevelError {
+		t.Logger().Warn(s.Value)
+	} else if log {
+		t.Logger().Info(s.Value)
 	}
 }
 
-// Log a string to stdout / file
+// Log Logs a message with the task's details. Errors are logged at the error level, everything else at info.
 func (t *Task) Log(l interface{}) {
-	now := time.Now()
-	fmt.Printf("[%d:%d:%d] [TASK: %v] %v\n", now.Hour(), now.Minute(), now.Second(), t.Id, l)
+	if err, ok := l.(error); ok {
+		t.Logger().Error(err.Error())
+		return
+	}
+
+	t.Logger().Info(fmt.Sprint(l))
+}
+
+// Logger Returns a logger that adds the task's ID, group, site, profile, mode and checkout step to every line. Lines
+// also go to the task's own log file if the manager has a task log directory.
+func (t *Task) Logger() *logs.Logger {
+	base := logs.Default()
+
+	if t.TaskManager != nil && t.TaskManager.Logger != nil {
+		base = t.TaskManager.Logger
+	}
+
+	args := []interface{}{"task", t.Id}
+
+	if t.Group != nil {
+		args = append(args, "group", t.Group.Name)
+	}
+
+	args = append(args, "site", t.Site.Name)
+
+	if t.Profile != nil {
+		args = append(args, "profile", t.Profile.Name)
+	}
+
+	args = append(args, "mode", ModeToString(t.Mode))
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.step != "" {
+		args = append(args, "step", t.step)
+	}
+
+	logger := base.With(args...)
+
+	if file := t.openLogFile(); file != nil {
+		logger = logger.WithHandler(logs.NewJsonHandler(file, logs.LevelDebug))
+	}
+
+	return logger
+}
+
+// SetStep Sets the checkout step shown in the task's log lines and tells subscribers if it changed. The time spent on
+// the previous step is added to the task's step timings.
+func (t *Task) SetStep(step string) {
+	t.mutex.Lock()
+	previous := t.step
+	t.step = step
+
+	if step != previous {
+		now := time.Now()
+
+		if previous != "" {
+			t.stepTimings = addStepTiming(t.stepTimings, previous, now.Sub(t.stepStarted))
+			stepDuration.Observe(now.Sub(t.stepStarted).Seconds(), previous, t.Site.Name, ModeToString(t.Mode))
+		}
+
+		t.stepStarted = now
+	}
+
+	t.mutex.Unlock()
+
+	if step != "" && step != previous {
+		t.Emit(TaskEvent{Type: EventStepChanged, Step: step, PreviousStep: previous})
+	}
+}
+
+// GetStep Returns the checkout step the task is on, or "" if it isn't on one
+func (t *Task) GetStep() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.step
+}
+
+// StepTimings Returns the time spent on each step since the task started, including the current one so far
+func (t *Task) StepTimings() []history.StepTiming {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	timings := append([]history.StepTiming{}, t.stepTimings...)
+
+	if t.step != "" {
+		timings = addStepTiming(timings, t.step, time.Since(t.stepStarted))
+	}
+
+	return timings
+}
+
+// Adds time to a step, keeping steps that are reached more than once (ex. payment after a decline) as one timing
+func addStepTiming(timings []history.StepTiming, step string, d time.Duration) []history.StepTiming {
+	for i := range timings {
+		if timings[i].Step == step {
+			timings[i].Duration += d
+			return timings
+		}
+	}
+
+	return append(timings, history.StepTiming{Step: step, Duration: d})
+}
+
+// RecordCheckout Saves a checkout attempt to the manager's history with the task's ID, group, site, mode, profile,
+// proxy list and step timings filled in. Nothing is saved if the manager doesn't keep a history.
+func (t *Task) RecordCheckout(r history.Record) {
+	if t.TaskManager == nil || t.TaskManager.History == nil {
+		return
+	}
+
+	r.TaskId = t.Id
+	r.Site = t.Site.Name
+	r.Mode = ModeToString(t.Mode)
+	r.StepTimings = t.StepTimings()
+
+	if t.Group != nil {
+		r.Group = t.Group.Name
+	}
+
+	if t.Profile != nil {
+		r.Profile = t.Profile.Name
+	}
+
+	if t.ProxyList != nil {
+		r.ProxyList = t.ProxyList.Name
+	}
+
+	if err := t.TaskManager.History.Add(&r); err != nil {
+		t.Logger().Error("Failed to save checkout to history", "error", err)
+	}
+}
+
+// LogFile Returns the path of the task's own log file, or "" if the manager doesn't keep one per task
+func (t *Task) LogFile() string {
+	if t.TaskManager == nil || t.TaskManager.TaskLogDir == "" {
+		return ""
+	}
+
+	return filepath.Join(t.TaskManager.TaskLogDir, t.Id+".log")
+}
+
+// Opens the task's log file the first time it's needed. Must be called with the task's mutex held.
+func (t *Task) openLogFile() *logs.RotatingFile {
+	if t.logFile != nil || t.logFileFailed {
+		return t.logFile
+	}
+
+	path := t.LogFile()
+
+	if path == "" {
+		return nil
+	}
+
+	file, err := logs.OpenRotatingFile(path, taskLogFileMaxBytes, 1)
+
+	if err != nil {
+		t.logFileFailed = true
+		logs.Error("Failed to open task log file", "task", t.Id, "error", err)
+		return nil
+	}
+
+	t.logFile = file
+	return file
+}
+
+// CloseLogFile Closes the task's log file. It's opened again if the task logs anything else.
+func (t *Task) CloseLogFile() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.logFile == nil {
+		return nil
+	}
+
+	err := t.logFile.Close()
+	t.logFile = nil
+	return err
 }
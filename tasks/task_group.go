@@ -1,11 +1,18 @@
 package tasks
 
-import "sync"
+import (
+	"Mystery/notify"
+	"Mystery/sizes"
+	"sync"
+)
 
 type TaskGroup struct {
-	Name  string
-	Tasks []*Task
-	Mutex *sync.Mutex
+	Name          string
+	Tasks         []*Task
+	Mutex         *sync.Mutex
+	RetryPolicies RetryPolicies
+	Notify        notify.Routes // The notifiers each checkout event is sent to. Events that aren't listed use the default routes.
+	spread        *sizes.Spread // The variants the group's tasks are going for, created by the first task to spread.
 }
 
 // NewTaskGroup Create and returns a new task group
@@ -35,9 +42,33 @@ func (g *TaskGroup) RemoveTask(t *Task) {
 
 	t.Group = nil
 
+	if g.spread != nil {
+		g.spread.Release(t.Id)
+	}
+
 	for i, task := range g.Tasks {
 		if task == t {
 			g.Tasks = append(g.Tasks[:i], g.Tasks[i+1:]...)
 		}
 	}
 }
+
+// GetTasks Returns a copy of the group's tasks, so they can be started or stopped without holding the group's mutex
+func (g *TaskGroup) GetTasks() []*Task {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	return append([]*Task{}, g.Tasks...)
+}
+
+// SizeSpread Returns what the group's tasks that spread across sizes are going for
+func (g *TaskGroup) SizeSpread() *sizes.Spread {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	if g.spread == nil {
+		g.spread = sizes.NewSpread()
+	}
+
+	return g.spread
+}
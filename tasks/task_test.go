@@ -1,9 +1,17 @@
 package tasks
 
 import (
+	"Mystery/logs"
+	"Mystery/profiles"
 	"Mystery/proxies"
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestTaskWithProxy(t *testing.T) {
@@ -19,3 +27,117 @@ func TestTaskWithProxy(t *testing.T) {
 
 	fmt.Println(string(resp.Body()))
 }
+
+// Tests that cancelling a task interrupts its sleep and that it can be started again after finishing
+func TestTaskSleepCancel(t *testing.T) {
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+
+	if !task.Begin() {
+		t.Fatal("expected the task to begin")
+	}
+
+	if task.Begin() {
+		t.Fatal("expected the task to already be running")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		task.Cancel()
+	}()
+
+	start := time.Now()
+
+	if task.Sleep(time.Minute) {
+		t.Fatal("expected the sleep to be interrupted")
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("sleep was not interrupted in time")
+	}
+
+	if task.IsRunning() {
+		t.Fatal("expected the task to no longer be running")
+	}
+
+	task.Finish()
+	task.Wait()
+
+	if !task.Begin() {
+		t.Fatal("expected the task to begin again after finishing")
+	}
+
+	task.Finish()
+}
+
+// Tests that a task can only be edited while it isn't running
+func TestTaskEdit(t *testing.T) {
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, []string{"10"})
+	task.Begin()
+
+	if task.Edit(func() { task.Sizes = []string{"11"} }) || task.Sizes[0] != "10" {
+		t.Fatal("expected a running task not to be edited")
+	}
+
+	task.Cancel()
+
+	if task.Edit(func() { task.Sizes = []string{"11"} }) {
+		t.Fatal("expected a stopping task not to be edited")
+	}
+
+	task.Finish()
+
+	if !task.Edit(func() { task.Sizes = []string{"11"} }) || task.Sizes[0] != "11" {
+		t.Fatal("expected a stopped task to be edited")
+	}
+}
+
+// Tests that task log lines carry the task's details and go to its own log file
+func TestTaskLogger(t *testing.T) {
+	var out bytes.Buffer
+	m := NewManager()
+	m.Logger = logs.New(logs.NewConsoleHandler(&out, logs.LevelInfo))
+	m.TaskLogDir = t.TempDir()
+
+	group := NewTaskGroup("Dunks")
+	task := NewTask(Website{Name: "Kith"}, &profiles.Profile{Name: "Test US"}, nil, ModeShopifyFast, nil, nil)
+	group.AddTask(&task)
+	m.AddTask(&task)
+
+	task.SetStep("Payment")
+	task.Log(errors.New("card declined"))
+
+	expected := fmt.Sprintf("ERROR card declined task=%v group=Dunks site=Kith profile=\"Test US\" mode=Fast step=Payment\n", task.Id)
+
+	if !strings.HasSuffix(out.String(), expected) {
+		t.Fatalf("expected %q. got %q", expected, out.String())
+	}
+
+	m.RemoveTask(&task)
+	data, err := ioutil.ReadFile(filepath.Join(m.TaskLogDir, task.Id+".log"))
+
+	if err != nil || !strings.Contains(string(data), `"msg":"card declined"`) {
+		t.Fatalf("expected the line in the task's log file. got %q (%v)", data, err)
+	}
+}
+
+// Tests that time spent on a step that is reached more than once is added to the same timing
+func TestTaskStepTimings(t *testing.T) {
+	task := NewTask(Website{}, nil, nil, ModeShopifySafe, nil, nil)
+	task.Begin()
+	defer task.Finish()
+
+	for _, step := range []string{"Monitoring", "Payment Method", "Processing", "Payment Method"} {
+		task.SetStep(step)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	timings := task.StepTimings()
+
+	if len(timings) != 3 || timings[0].Step != "Monitoring" || timings[1].Step != "Payment Method" || timings[2].Step != "Processing" {
+		t.Fatalf("expected a timing for each step in the order they were reached. got %+v", timings)
+	}
+
+	if timings[1].Duration < 20*time.Millisecond {
+		t.Fatalf("expected both payment visits to be added together. got %+v", timings)
+	}
+}
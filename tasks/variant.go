@@ -0,0 +1,33 @@
+package tasks
+
+import "Mystery/sizes"
+
+// VariantSelector Returns what the task chooses its variant with, created from VariantStrategy and VariantSeed the
+// first time it's needed. Tasks that spread across sizes spread across the other tasks in their group.
+func (t *Task) VariantSelector() *sizes.Selector {
+	var spread *sizes.Spread
+
+	// Fetched before the task's mutex is held, since the group's mutex is held while the group's tasks are locked
+	if t.VariantStrategy == sizes.StrategySpread && t.Group != nil {
+		spread = t.Group.SizeSpread()
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.selector == nil {
+		t.selector = sizes.NewSelector(t.VariantStrategy, t.VariantSeed)
+		t.selector.Owner = t.Id
+		t.selector.Spread = spread
+	}
+
+	return t.selector
+}
+
+// ReleaseVariant Forgets the variant the task was going for, so the other tasks in its group stop steering away from
+// it. Called once the task stops.
+func (t *Task) ReleaseVariant() {
+	if group := t.Group; group != nil {
+		group.SizeSpread().Release(t.Id)
+	}
+}
@@ -3,8 +3,8 @@ package tasks
 import "strings"
 
 type Website struct {
-	Name string
-	Url  string
+	Name string `json:"name"`
+	Url  string `json:"url"`
 }
 
 // GetHostName Returns the "host" name of the website's URL
@@ -0,0 +1,573 @@
+package tui
+
+import (
+	"Mystery/tasks"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorBold    = "\x1b[1m"
+	colorReverse = "\x1b[7m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorDim     = "\x1b[2m"
+)
+
+// The keys the dashboard handles. Printable keys are their own character.
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyLeft  = "left"
+	keyRight = "right"
+	keyQuit  = "ctrl+c"
+)
+
+// How many lines of the selected task's log are shown
+const logPaneLines = 8
+
+// How often the dashboard is redrawn when nothing else happens
+var refreshInterval = 250 * time.Millisecond
+
+// A table column and how many characters wide it is
+type column struct {
+	name  string
+	width int
+}
+
+var columns = []column{
+	{"ID", 8},
+	{"Site", 16},
+	{"Profile", 14},
+	{"Proxy", 12},
+	{"Status", 34},
+	{"Product", 30},
+	{"Size", 6},
+}
+
+// Dashboard Shows every task group as a table of its tasks, with the selected task's recent log underneath. Tasks and
+// groups can be started and stopped from the keyboard.
+type Dashboard struct {
+	Manager    *tasks.Manager
+	Logs       *LogBuffer
+	mutex      *sync.Mutex
+	products   map[string][2]string // The product title and size each task found, by task ID.
+	carts      int
+	checkouts  int
+	declines   int
+	selectedId string
+	quit       chan struct{}
+	quitOnce   *sync.Once
+}
+
+// NewDashboard Creates a dashboard for the manager's tasks. The log buffer should be receiving the tasks' log lines.
+func NewDashboard(m *tasks.Manager, logBuffer *LogBuffer) *Dashboard {
+	return &Dashboard{
+		Manager:  m,
+		Logs:     logBuffer,
+		mutex:    &sync.Mutex{},
+		products: map[string][2]string{},
+		quit:     make(chan struct{}),
+		quitOnce: &sync.Once{},
+	}
+}
+
+// Run Takes over the terminal and shows the dashboard until q or Ctrl+C is pressed or Stop is called
+func (d *Dashboard) Run(in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+
+	if !term.IsTerminal(fd) {
+		return errors.New("the dashboard needs to run in a terminal")
+	}
+
+	state, err := term.MakeRaw(fd)
+
+	if err != nil {
+		return err
+	}
+
+	defer term.Restore(fd, state)
+
+	// Switch to the alternate screen and hide the cursor, then undo both when finished
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	subscription := d.Manager.Subscribe()
+	defer subscription.Unsubscribe()
+
+	// Also lets the key reader below return after its next read
+	defer d.Stop()
+
+	keys := make(chan []byte)
+
+	go func() {
+		buf := make([]byte, 64)
+
+		for {
+			n, err := in.Read(buf)
+
+			if err != nil {
+				close(keys)
+				return
+			}
+
+			select {
+			case keys <- append([]byte{}, buf[:n]...):
+			case <-d.quit:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	draw := func() {
+		width, height, err := term.GetSize(fd)
+
+		if err != nil {
+			width, height = 120, 40
+		}
+
+		fmt.Fprint(out, "\x1b[H"+strings.Join(d.Render(width, height), "\x1b[K\r\n")+"\x1b[K\x1b[J")
+	}
+
+	draw()
+
+	// Events only update state. Drawing on every one would flood the terminal during a drop, so the screen is only
+	// drawn on the ticker and after key presses.
+	for {
+		select {
+		case <-d.quit:
+			return nil
+		case e, ok := <-subscription.Events:
+			if ok {
+				d.HandleEvent(e)
+			}
+
+			continue
+		case b, ok := <-keys:
+			if !ok {
+				return nil
+			}
+
+			for _, key := range parseKeys(b) {
+				if !d.HandleKey(key) {
+					return nil
+				}
+			}
+		case <-ticker.C:
+		}
+
+		draw()
+	}
+}
+
+// Stop Makes Run return
+func (d *Dashboard) Stop() {
+	d.quitOnce.Do(func() {
+		close(d.quit)
+	})
+}
+
+// HandleEvent Updates the counters and products from a task event
+func (d *Dashboard) HandleEvent(e tasks.TaskEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch e.Type {
+	case tasks.EventProductFound:
+		d.products[e.TaskId] = [2]string{e.Product, e.Size}
+	case tasks.EventCarted:
+		d.carts++
+	case tasks.EventCheckoutSuccess:
+		d.checkouts++
+	case tasks.EventCheckoutDecline:
+		d.declines++
+	}
+}
+
+// HandleKey Acts on a key press. Returns false if the dashboard should close.
+//
+//	up/down or k/j     Select the previous/next task
+//	left/right or h/l  Select the first task of the previous/next group
+//	s/x                Start/stop the selected task
+//	S/X                Start/stop the selected task's group
+//	q or Ctrl+C        Close the dashboard
+func (d *Dashboard) HandleKey(key string) bool {
+	rows := d.rows()
+	selected := d.selectedIndex(rows)
+
+	switch key {
+	case keyQuit, "q":
+		return false
+	case keyUp, "k":
+		selected--
+	case keyDown, "j":
+		selected++
+	case keyLeft, "h":
+		selected = groupStart(rows, selected, -1)
+	case keyRight, "l":
+		selected = groupStart(rows, selected, 1)
+	case "s", "x", "S", "X":
+		if selected < len(rows) {
+			d.control(key, rows[selected])
+		}
+	}
+
+	if len(rows) > 0 {
+		if selected < 0 {
+			selected = 0
+		} else if selected >= len(rows) {
+			selected = len(rows) - 1
+		}
+
+		d.mutex.Lock()
+		d.selectedId = rows[selected].task.Id
+		d.mutex.Unlock()
+	}
+
+	return true
+}
+
+// Starts or stops the row's task or group. Stopping waits for tasks to finish, so it's done in the background.
+func (d *Dashboard) control(key string, r row) {
+	switch key {
+	case "s":
+		if r.task.Runner != nil {
+			d.Manager.StartTask(r.task.Runner)
+		}
+	case "x":
+		if r.task.Runner != nil {
+			go d.Manager.StopTask(r.task.Runner)
+		}
+	case "S":
+		d.Manager.StartTaskGroup(r.group)
+	case "X":
+		go d.Manager.StopTaskGroup(r.group)
+	}
+}
+
+// A task and the group it's shown under
+type row struct {
+	group *tasks.TaskGroup
+	task  *tasks.Task
+}
+
+// Returns every task in every group, with groups sorted by name and tasks in the order they were added
+func (d *Dashboard) rows() []row {
+	d.Manager.TaskGroupMutex.Lock()
+	groups := make([]*tasks.TaskGroup, 0, len(d.Manager.TaskGroups))
+
+	for g := range d.Manager.TaskGroups {
+		groups = append(groups, g)
+	}
+
+	d.Manager.TaskGroupMutex.Unlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	var rows []row
+
+	for _, g := range groups {
+		g.Mutex.Lock()
+
+		for _, t := range g.Tasks {
+			rows = append(rows, row{g, t})
+		}
+
+		g.Mutex.Unlock()
+	}
+
+	return rows
+}
+
+// Returns the index of the selected task, or 0 if it's gone
+func (d *Dashboard) selectedIndex(rows []row) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, r := range rows {
+		if r.task.Id == d.selectedId {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// Returns the index of the first task in the group before or after the selected one
+func groupStart(rows []row, selected int, direction int) int {
+	if selected >= len(rows) {
+		return selected
+	}
+
+	current := rows[selected].group
+	i := selected
+
+	// Move to the first row of the current group
+	for i > 0 && rows[i-1].group == current {
+		i--
+	}
+
+	if direction < 0 {
+		if i == 0 {
+			return 0
+		}
+
+		previous := rows[i-1].group
+
+		for i > 0 && rows[i-1].group == previous {
+			i--
+		}
+
+		return i
+	}
+
+	for i < len(rows) && rows[i].group == current {
+		i++
+	}
+
+	if i == len(rows) {
+		return selected
+	}
+
+	return i
+}
+
+// Render Returns the lines of the dashboard for a terminal of the given size
+func (d *Dashboard) Render(width int, height int) []string {
+	rows := d.rows()
+	selected := d.selectedIndex(rows)
+
+	d.mutex.Lock()
+	carts, checkouts, declines := d.carts, d.checkouts, d.declines
+	products := make(map[string][2]string, len(d.products))
+
+	for id, p := range d.products {
+		products[id] = p
+	}
+
+	d.mutex.Unlock()
+
+	running := 0
+	groupSizes := map[*tasks.TaskGroup]int{}
+
+	for _, r := range rows {
+		groupSizes[r.group]++
+
+		if r.task.IsRunning() {
+			running++
+		}
+	}
+
+	header := fmt.Sprintf(" NOTHING   Carts: %v   Checkouts: %v   Declines: %v   Running: %v/%v", carts, checkouts, declines, running, len(rows))
+	lines := []string{colorBold + fit(header, width) + colorReset, ""}
+
+	// The task tables, scrolled so the selected task is visible
+	var table []string
+	selectedLine := 0
+	var group *tasks.TaskGroup
+
+	for i, r := range rows {
+		if r.group != group {
+			group = r.group
+
+			if i > 0 {
+				table = append(table, "")
+			}
+
+			table = append(table, colorBold+fit(fmt.Sprintf(" %v (%v tasks)", group.Name, groupSizes[group]), width)+colorReset)
+			table = append(table, colorDim+fit(columnHeader(), width)+colorReset)
+		}
+
+		if i == selected {
+			selectedLine = len(table)
+		}
+
+		table = append(table, renderRow(r.task, products[r.task.Id], i == selected, width))
+	}
+
+	if len(rows) == 0 {
+		table = append(table, " No tasks.")
+	}
+
+	tableHeight := height - len(lines) - logPaneLines - 2
+
+	if tableHeight < 1 {
+		tableHeight = 1
+	}
+
+	if len(table) > tableHeight {
+		start := selectedLine - tableHeight/2
+
+		if start < 0 {
+			start = 0
+		} else if start > len(table)-tableHeight {
+			start = len(table) - tableHeight
+		}
+
+		table = table[start : start+tableHeight]
+	}
+
+	lines = append(lines, table...)
+
+	for len(lines) < height-logPaneLines-1 {
+		lines = append(lines, "")
+	}
+
+	// The log pane shows the selected task's log, or the general log without tasks
+	title := " Log "
+	logLines := d.Logs.Lines("")
+
+	if len(rows) > 0 {
+		title = fmt.Sprintf(" Log: %v ", rows[selected].task.Id)
+		logLines = d.Logs.Lines(rows[selected].task.Id)
+	}
+
+	lines = append(lines, colorDim+fit("──"+title+strings.Repeat("─", width), width)+colorReset)
+
+	if len(logLines) > logPaneLines-1 {
+		logLines = logLines[len(logLines)-(logPaneLines-1):]
+	}
+
+	for _, line := range logLines {
+		lines = append(lines, fit(" "+line, width))
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, colorDim+fit(" ↑/↓ select  ←/→ group  s/x start/stop task  S/X start/stop group  q quit", width)+colorReset)
+	return lines
+}
+
+func columnHeader() string {
+	var b strings.Builder
+
+	for _, c := range columns {
+		b.WriteString(" " + pad(c.name, c.width))
+	}
+
+	return b.String()
+}
+
+// Renders a task as a table row with its status colored by level
+func renderRow(t *tasks.Task, product [2]string, selected bool, width int) string {
+	status := t.GetStatus()
+	profile, proxy := "", "Localhost"
+
+	if t.Profile != nil {
+		profile = t.Profile.Name
+	}
+
+	if t.ProxyList != nil {
+		proxy = t.ProxyList.Name
+	}
+
+	values := []string{t.Id, t.Site.Name, profile, proxy, status.Value, product[0], product[1]}
+	var b strings.Builder
+	used := 0
+
+	for i, c := range columns {
+		cell := " " + pad(values[i], c.width)
+
+		if used+utf8.RuneCountInString(cell) > width {
+			cell = fit(cell, width-used)
+		}
+
+		used += utf8.RuneCountInString(cell)
+
+		if i == 4 && !selected {
+			cell = statusColor(status.Level) + cell + colorReset
+		}
+
+		b.WriteString(cell)
+	}
+
+	if selected {
+		return colorReverse + b.String() + colorReset
+	}
+
+	return b.String()
+}
+
+func statusColor(level tasks.TaskStatusLevel) string {
+	switch level {
+	case tasks.StatusLevelImportant:
+		return colorYellow
+	case tasks.StatusLevelError:
+		return colorRed
+	case tasks.StatusLevelSuccess:
+		return colorGreen
+	default:
+		return ""
+	}
+}
+
+// Pads or cuts the text to exactly the width
+func pad(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+
+	if n > width {
+		return fit(s, width)
+	}
+
+	return s + strings.Repeat(" ", width-n)
+}
+
+// Cuts the text to at most the width, ending with "…" if anything was cut
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// Splits terminal input into keys. Arrow keys arrive as escape sequences, ex. "\x1b[A".
+func parseKeys(b []byte) []string {
+	var keys []string
+
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == 3:
+			keys = append(keys, keyQuit)
+		case b[i] == 0x1b && i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O'):
+			switch b[i+2] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			case 'C':
+				keys = append(keys, keyRight)
+			case 'D':
+				keys = append(keys, keyLeft)
+			}
+
+			i += 2
+		case b[i] >= 0x20 && b[i] < 0x7f:
+			keys = append(keys, string(b[i]))
+		}
+	}
+
+	return keys
+}
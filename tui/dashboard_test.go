@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"Mystery/logs"
+	"Mystery/tasks"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Runs a task by marking it as running until it's stopped
+type testRunner struct {
+	task *tasks.Task
+}
+
+func (r *testRunner) Start() {
+	r.task.Begin()
+}
+
+func (r *testRunner) Stop() {
+	r.task.Cancel()
+	r.task.Finish()
+}
+
+// Creates a dashboard for two groups, "Dunks" with two tasks and "Jordans" with one
+func newTestDashboard() (*Dashboard, [][]*tasks.Task) {
+	m := tasks.NewManager()
+	var groups [][]*tasks.Task
+
+	for _, g := range []struct {
+		name  string
+		tasks int
+	}{{"Jordans", 1}, {"Dunks", 2}} {
+		group := tasks.NewTaskGroup(g.name)
+		var groupTasks []*tasks.Task
+
+		for i := 0; i < g.tasks; i++ {
+			task := tasks.NewTask(tasks.Website{Name: "Kith"}, nil, nil, tasks.ModeShopifySafe, nil, nil)
+			task.Runner = &testRunner{&task}
+			group.AddTask(&task)
+			m.AddTask(&task)
+			groupTasks = append(groupTasks, &task)
+		}
+
+		m.AddTaskGroup(&group)
+		groups = append([][]*tasks.Task{groupTasks}, groups...)
+	}
+
+	return NewDashboard(&m, NewLogBuffer(logs.LevelInfo, 5)), groups
+}
+
+// Tests that arrow keys, Ctrl+C and printable characters are split into keys
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("j\x1b[A\x1b[B\x1bOC\x1b[Dq\x03"))
+	expected := []string{"j", keyUp, keyDown, keyRight, keyLeft, "q", keyQuit}
+
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v. got %v", expected, keys)
+	}
+}
+
+// Tests moving the selection between tasks and groups, stopping at the ends
+func TestHandleKeyNavigation(t *testing.T) {
+	d, groups := newTestDashboard()
+	dunks, jordans := groups[0], groups[1]
+
+	steps := []struct {
+		key      string
+		expected *tasks.Task
+	}{
+		{"k", dunks[0]},
+		{"j", dunks[1]},
+		{keyDown, jordans[0]},
+		{"j", jordans[0]},
+		{keyLeft, dunks[0]},
+		{"h", dunks[0]},
+		{"l", jordans[0]},
+		{keyRight, jordans[0]},
+		{keyUp, dunks[1]},
+	}
+
+	for i, step := range steps {
+		if !d.HandleKey(step.key) {
+			t.Fatalf("step %v: expected %v not to close the dashboard", i, step.key)
+		}
+
+		if d.selectedId != step.expected.Id {
+			t.Fatalf("step %v: expected %v to select %v. got %v", i, step.key, step.expected.Id, d.selectedId)
+		}
+	}
+
+	if d.HandleKey("q") || d.HandleKey(keyQuit) {
+		t.Fatalf("expected q and Ctrl+C to close the dashboard")
+	}
+}
+
+// Tests starting and stopping the selected task and its group
+func TestHandleKeyControl(t *testing.T) {
+	d, groups := newTestDashboard()
+	dunks, jordans := groups[0], groups[1]
+
+	d.HandleKey("s")
+
+	if !dunks[0].IsRunning() || dunks[1].IsRunning() {
+		t.Fatalf("expected only the selected task to start")
+	}
+
+	d.HandleKey("S")
+
+	if !dunks[1].IsRunning() || jordans[0].IsRunning() {
+		t.Fatalf("expected only the selected task's group to start")
+	}
+
+	d.HandleKey("x")
+	waitForStop(t, dunks[0])
+
+	if !dunks[1].IsRunning() {
+		t.Fatalf("expected only the selected task to stop")
+	}
+
+	d.HandleKey("X")
+	waitForStop(t, dunks[1])
+}
+
+func waitForStop(t *testing.T, task *tasks.Task) {
+	deadline := time.Now().Add(time.Second)
+
+	for task.IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected task %v to stop", task.Id)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Tests that the dashboard shows the counters, groups, colored statuses and the selected task's log
+func TestRender(t *testing.T) {
+	d, groups := newTestDashboard()
+	dunks := groups[0]
+
+	dunks[0].UpdateStatus(&tasks.TaskStatus{Value: "Card declined", Level: tasks.StatusLevelError}, false)
+	d.HandleEvent(tasks.TaskEvent{Type: tasks.EventProductFound, TaskId: dunks[0].Id, Product: "Dunk Low Panda", Size: "10"})
+	d.HandleEvent(tasks.TaskEvent{Type: tasks.EventCarted, TaskId: dunks[0].Id})
+	d.HandleEvent(tasks.TaskEvent{Type: tasks.EventCheckoutDecline, TaskId: dunks[0].Id})
+
+	d.Logs.Handle(logs.Record{Level: logs.LevelError, Message: "Card declined", Fields: []logs.Field{{Key: "task", Value: dunks[0].Id}}})
+	d.Logs.Handle(logs.Record{Level: logs.LevelInfo, Message: "Monitoring", Fields: []logs.Field{{Key: "task", Value: dunks[1].Id}}})
+
+	d.HandleKey("j")
+	lines := d.Render(160, 30)
+
+	if len(lines) != 30 {
+		t.Fatalf("expected 30 lines. got %v", len(lines))
+	}
+
+	screen := strings.Join(lines, "\n")
+
+	for _, expected := range []string{
+		"Carts: 1   Checkouts: 0   Declines: 1   Running: 0/3",
+		"Dunks (2 tasks)",
+		"Jordans (1 tasks)",
+		colorRed + " Card declined",
+		"Dunk Low Panda",
+		"Log: " + dunks[1].Id,
+		"INFO  Monitoring",
+	} {
+		if !strings.Contains(screen, expected) {
+			t.Fatalf("expected the dashboard to contain %q. got\n%v", expected, screen)
+		}
+	}
+
+	if strings.Index(screen, "Dunks") > strings.Index(screen, "Jordans") {
+		t.Fatalf("expected groups to be sorted by name")
+	}
+
+	if strings.Contains(screen, "ERROR Card declined") {
+		t.Fatalf("expected only the selected task's log")
+	}
+}
+
+// Tests that the buffer keeps the last lines of each task and lines without a task separately
+func TestLogBuffer(t *testing.T) {
+	b := NewLogBuffer(logs.LevelInfo, 2)
+
+	if b.Enabled(logs.LevelDebug) || !b.Enabled(logs.LevelWarn) {
+		t.Fatalf("expected only info and above to be enabled")
+	}
+
+	for _, message := range []string{"one", "two", "three"} {
+		b.Handle(logs.Record{Level: logs.LevelInfo, Message: message, Fields: []logs.Field{{Key: "task", Value: "a"}}})
+	}
+
+	b.Handle(logs.Record{Level: logs.LevelWarn, Message: "general"})
+	lines := b.Lines("a")
+
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "INFO  two") || !strings.HasSuffix(lines[1], "INFO  three") {
+		t.Fatalf("expected the last 2 lines of the task. got %q", lines)
+	}
+
+	if general := b.Lines(""); len(general) != 1 || !strings.HasSuffix(general[0], "WARN  general") {
+		t.Fatalf("expected the general line. got %q", general)
+	}
+}
@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"Mystery/logs"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LogBuffer Keeps the most recent log lines of every task so the dashboard can show them. Lines without a task field
+// are kept as general lines.
+type LogBuffer struct {
+	Level   logs.Level
+	Size    int // The amount of lines kept per task.
+	mutex   *sync.Mutex
+	tasks   map[string][]string
+	general []string
+}
+
+// NewLogBuffer Creates a buffer that keeps the last size lines at or above the level for each task
+func NewLogBuffer(level logs.Level, size int) *LogBuffer {
+	return &LogBuffer{
+		Level: level,
+		Size:  size,
+		mutex: &sync.Mutex{},
+		tasks: map[string][]string{},
+	}
+}
+
+// Enabled Returns if lines at the level are kept
+func (b *LogBuffer) Enabled(level logs.Level) bool {
+	return level >= b.Level
+}
+
+// Handle Keeps the record as a line, ex. "15:04:05 ERROR Card declined"
+func (b *LogBuffer) Handle(r logs.Record) error {
+	line := fmt.Sprintf("%v %-5v %v", r.Time.Format("15:04:05"), strings.ToUpper(r.Level.String()), r.Message)
+	task := ""
+
+	for _, f := range r.Fields {
+		if f.Key == "task" {
+			task = fmt.Sprint(f.Value)
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if task == "" {
+		b.general = appendLine(b.general, line, b.Size)
+	} else {
+		b.tasks[task] = appendLine(b.tasks[task], line, b.Size)
+	}
+
+	return nil
+}
+
+// Lines Returns the kept lines of a task, oldest first. An empty task ID returns the general lines.
+func (b *LogBuffer) Lines(task string) []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if task == "" {
+		return append([]string{}, b.general...)
+	}
+
+	return append([]string{}, b.tasks[task]...)
+}
+
+// Appends a line, dropping the oldest ones past the size
+func appendLine(lines []string, line string, size int) []string {
+	lines = append(lines, line)
+
+	if len(lines) > size {
+		lines = append(lines[:0:0], lines[len(lines)-size:]...)
+	}
+
+	return lines
+}
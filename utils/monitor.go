@@ -2,6 +2,7 @@ package utils
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -13,17 +14,23 @@ const (
 	MonitorInputTypeKeywords
 )
 
-// GetMonitorInputTypeFromString Returns the type of monitor input from the given string
+// GetMonitorInputTypeFromString Returns the type of monitor input from the given string. Inputs starting with + or -
+// are keywords, numbers are variants and absolute URLs are product links. Anything else is a keyword set too, ex. a
+// quoted phrase or vendor:nike.
 func GetMonitorInputTypeFromString(s string) MonitorInputType {
 	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
 		return MonitorInputTypeKeywords
 	}
 
-	_, err := url.ParseRequestURI(s)
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return MonitorInputTypeVariant
+	}
+
+	u, err := url.ParseRequestURI(s)
 
-	if err == nil {
+	if err == nil && u.Host != "" {
 		return MonitorInputTypeUrl
 	}
 
-	return MonitorInputTypeVariant
+	return MonitorInputTypeKeywords
 }
@@ -1,9 +1,9 @@
 package utils
 
 import (
+	"Mystery/keywords"
 	"fmt"
 	"math/rand"
-	"strings"
 	"time"
 )
 
@@ -21,26 +21,13 @@ func GenerateRandomString(length int) string {
 	return fmt.Sprintf("%x", b)[:length]
 }
 
-// IsKeywordMatch Returns if a string matches a given keyword set
-func IsKeywordMatch(str string, keywords string) bool {
-	if str == "" || keywords == "" {
-		return false
-	}
+// IsKeywordMatch Returns if a string matches a given keyword set. Keyword sets that can't be parsed match nothing.
+func IsKeywordMatch(str string, keywordSet string) bool {
+	query, err := keywords.Parse(keywordSet)
 
-	str = strings.ToLower(str)
-	keywords = strings.ToLower(strings.ReplaceAll(keywords, " ", ""))
-
-	for _, keyword := range strings.Split(keywords, ",") {
-		if keyword[0] == '+' {
-			if !strings.Contains(str, strings.Replace(keyword, "+", "", 1)) {
-				return false
-			}
-		} else if keyword[0] == '-' {
-			if strings.Contains(str, strings.Replace(keyword, "-", "", 1)) {
-				return false
-			}
-		}
+	if err != nil {
+		return false
 	}
 
-	return true
+	return query.Match(str, nil)
 }